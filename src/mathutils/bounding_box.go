@@ -0,0 +1,107 @@
+// Package mathutils provides some mathematical utilities used in the raytracer.
+package mathutils
+
+import "math"
+
+// Defines an axis-aligned bounding box.
+type BoundingBox struct {
+	Min, Max Vector
+}
+
+// Create a new bounding box with the given minimal and maximal corners and return it.
+func NewBoundingBox(min, max Vector) BoundingBox {
+	return BoundingBox{min, max}
+}
+
+// Create an empty bounding box which can be grown with AddPoint and return it.
+func EmptyBoundingBox() BoundingBox {
+	inf := math.Inf(1)
+	return BoundingBox{Vector{inf, inf, inf}, Vector{-inf, -inf, -inf}}
+}
+
+// Create a bounding box that contains the whole space and return it.
+func InfiniteBoundingBox() BoundingBox {
+	inf := math.Inf(1)
+	return BoundingBox{Vector{-inf, -inf, -inf}, Vector{inf, inf, inf}}
+}
+
+// Return true if b stretches infinitely in some direction.
+func (b *BoundingBox) IsInfinite() bool {
+	return math.IsInf(b.Min.X, 0) || math.IsInf(b.Min.Y, 0) || math.IsInf(b.Min.Z, 0) ||
+		math.IsInf(b.Max.X, 0) || math.IsInf(b.Max.Y, 0) || math.IsInf(b.Max.Z, 0)
+}
+
+// Return true if b contains no points.
+func (b *BoundingBox) IsEmpty() bool {
+	return b.Min.X > b.Max.X || b.Min.Y > b.Max.Y || b.Min.Z > b.Max.Z
+}
+
+// Grow b so it contains the point p.
+func (b *BoundingBox) AddPoint(p Vector) {
+	b.Min = Vector{math.Min(b.Min.X, p.X), math.Min(b.Min.Y, p.Y), math.Min(b.Min.Z, p.Z)}
+	b.Max = Vector{math.Max(b.Max.X, p.X), math.Max(b.Max.Y, p.Y), math.Max(b.Max.Z, p.Z)}
+}
+
+// Return the center of b.
+func (b *BoundingBox) Center() Vector {
+	return VectorMultiply(VectorAddition(b.Min, b.Max), 0.5)
+}
+
+// Return the smallest bounding box containing both lhs and rhs.
+func BoundingBoxUnion(lhs, rhs BoundingBox) BoundingBox {
+	result := lhs
+	result.AddPoint(rhs.Min)
+	result.AddPoint(rhs.Max)
+	return result
+}
+
+// Return the bounding box of b after it is transformed by t.
+func TransformBoundingBox(b BoundingBox, t Transform) BoundingBox {
+	if b.IsInfinite() {
+		return InfiniteBoundingBox()
+	}
+
+	result := EmptyBoundingBox()
+	for i := 0; i < 8; i++ {
+		corner := b.Min
+		if i&1 != 0 {
+			corner.X = b.Max.X
+		}
+		if i&2 != 0 {
+			corner.Y = b.Max.Y
+		}
+		if i&4 != 0 {
+			corner.Z = b.Max.Z
+		}
+		result.AddPoint(MultiplyPointTransform(corner, t))
+	}
+
+	return result
+}
+
+// Return the distances at which a ray with the given start and inverted direction enters and leaves b.
+// The last result is false if the ray misses b.
+func (b *BoundingBox) IntersectRay(start, inverseDirection Vector) (float64, float64, bool) {
+	near := math.Inf(-1)
+	far := math.Inf(1)
+
+	slab := func(min, max, start, inverseDirection float64) {
+		t1 := (min - start) * inverseDirection
+		t2 := (max - start) * inverseDirection
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		if t1 > near {
+			near = t1
+		}
+		if t2 < far {
+			far = t2
+		}
+	}
+
+	slab(b.Min.X, b.Max.X, start.X, inverseDirection.X)
+	slab(b.Min.Y, b.Max.Y, start.Y, inverseDirection.Y)
+	slab(b.Min.Z, b.Max.Z, start.Z, inverseDirection.Z)
+
+	return near, far, near <= far && far >= 0
+}
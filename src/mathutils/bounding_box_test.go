@@ -0,0 +1,70 @@
+package mathutils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAddPoint(t *testing.T) {
+	box := EmptyBoundingBox()
+	if !box.IsEmpty() {
+		t.Errorf("EmptyBoundingBox() failed!")
+	}
+
+	box.AddPoint(NewVector(1, 2, 3))
+	box.AddPoint(NewVector(-1, 5, 0))
+	if box.IsEmpty() || box.Min != NewVector(-1, 2, 0) || box.Max != NewVector(1, 5, 3) {
+		t.Errorf("BoundingBox.AddPoint() failed!")
+	}
+}
+
+func TestBoundingBoxUnion(t *testing.T) {
+	box := BoundingBoxUnion(NewBoundingBox(NewVector(0, 0, 0), NewVector(1, 1, 1)), NewBoundingBox(NewVector(2, -1, 0), NewVector(3, 0, 1)))
+	if box.Min != NewVector(0, -1, 0) || box.Max != NewVector(3, 1, 1) {
+		t.Errorf("BoundingBoxUnion() failed!")
+	}
+
+	if box.Center() != NewVector(1.5, 0, 0.5) {
+		t.Errorf("BoundingBox.Center() failed!")
+	}
+}
+
+func TestTransformBoundingBox(t *testing.T) {
+	box := NewBoundingBox(NewVector(-1, -1, -1), NewVector(1, 1, 1))
+	transform := TransformMultiplication(RotationTransform(NewVector(0, math.Pi/4, 0)), TranslationTransform(NewVector(10, 0, 0)))
+	result := TransformBoundingBox(box, transform)
+	if math.Abs(result.Min.X-(10-math.Sqrt2)) > 1e-10 || math.Abs(result.Max.X-(10+math.Sqrt2)) > 1e-10 || math.Abs(result.Max.Y-1) > 1e-10 {
+		t.Errorf("TransformBoundingBox() failed!")
+	}
+
+	infinite := InfiniteBoundingBox()
+	result = TransformBoundingBox(infinite, transform)
+	if !result.IsInfinite() {
+		t.Errorf("TransformBoundingBox() failed!")
+	}
+}
+
+func TestIntersectRay(t *testing.T) {
+	box := NewBoundingBox(NewVector(-1, -1, -1), NewVector(1, 1, 1))
+	inf := math.Inf(1)
+
+	near, far, ok := box.IntersectRay(NewVector(-5, 0, 0), NewVector(1, inf, inf))
+	if !ok || near != 4 || far != 6 {
+		t.Errorf("BoundingBox.IntersectRay() failed!")
+	}
+
+	near, far, ok = box.IntersectRay(NewVector(0, 0, 0), NewVector(inf, inf, 1))
+	if !ok || near != -1 || far != 1 {
+		t.Errorf("BoundingBox.IntersectRay() failed!")
+	}
+
+	_, _, ok = box.IntersectRay(NewVector(-5, 2, 0), NewVector(1, inf, inf))
+	if ok {
+		t.Errorf("BoundingBox.IntersectRay() failed!")
+	}
+
+	_, _, ok = box.IntersectRay(NewVector(5, 0, 0), NewVector(1, inf, inf))
+	if ok {
+		t.Errorf("BoundingBox.IntersectRay() failed!")
+	}
+}
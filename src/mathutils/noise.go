@@ -0,0 +1,151 @@
+// Package mathutils provides some mathematical utilities used in the raytracer.
+package mathutils
+
+import "math"
+
+// permutation holds a fixed shuffle of 0 to 255, repeated twice to avoid wrapping the indices.
+var permutation [512]int
+
+func init() {
+	var values [256]int
+	for i := range values {
+		values[i] = i
+	}
+
+	// Shuffle with a fixed xorshift generator so the noise is the same on every run.
+	state := uint32(2463534242)
+	for i := len(values) - 1; i > 0; i-- {
+		state ^= state << 13
+		state ^= state >> 17
+		state ^= state << 5
+		j := int(state % uint32(i+1))
+		values[i], values[j] = values[j], values[i]
+	}
+
+	for i := range permutation {
+		permutation[i] = values[i%256]
+	}
+}
+
+// hashLattice returns a pseudo random value in [0, 255] for the lattice point.
+func hashLattice(x, y, z int) int {
+	return permutation[permutation[permutation[x&255]+y&255]+z&255]
+}
+
+// fade is the quintic curve 6t^5 - 15t^4 + 10t^3 which smooths the interpolation between lattice points.
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+// lerp interpolates linearly between a and b.
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// gradient returns the dot product of the offset with one of the 12 gradient directions selected by the hash.
+func gradient(hash int, x, y, z float64) float64 {
+	switch hash & 15 {
+	case 0, 12:
+		return x + y
+	case 1, 14:
+		return -x + y
+	case 2:
+		return x - y
+	case 3:
+		return -x - y
+	case 4:
+		return x + z
+	case 5:
+		return -x + z
+	case 6:
+		return x - z
+	case 7:
+		return -x - z
+	case 8:
+		return y + z
+	case 9, 13:
+		return -y + z
+	case 10:
+		return y - z
+	default:
+		return -y - z
+	}
+}
+
+// Noise returns the Perlin gradient noise at the point. The result is roughly in [-1, 1],
+// zero at integer coordinates and varies with a period of about one unit.
+func Noise(p Vector) float64 {
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	x, y, z := int(fx), int(fy), int(fz)
+	dx, dy, dz := p.X-fx, p.Y-fy, p.Z-fz
+	u, v, w := fade(dx), fade(dy), fade(dz)
+
+	corner := func(i, j, k int) float64 {
+		return gradient(hashLattice(x+i, y+j, z+k), dx-float64(i), dy-float64(j), dz-float64(k))
+	}
+
+	return lerp(w,
+		lerp(v, lerp(u, corner(0, 0, 0), corner(1, 0, 0)), lerp(u, corner(0, 1, 0), corner(1, 1, 0))),
+		lerp(v, lerp(u, corner(0, 0, 1), corner(1, 0, 1)), lerp(u, corner(0, 1, 1), corner(1, 1, 1))))
+}
+
+// FBm returns the fractional Brownian motion at the point: the sum of octaves of noise,
+// each with twice the frequency and half the amplitude of the previous one.
+// A fractional number of octaves fades the last octave in, so the octaves can be changed smoothly.
+func FBm(p Vector, octaves float64) float64 {
+	return sumOctaves(p, octaves, Noise)
+}
+
+// Turbulence returns the sum of octaves of the absolute value of noise, like FBm.
+// The result is positive with creases where the noise crosses zero.
+func Turbulence(p Vector, octaves float64) float64 {
+	return sumOctaves(p, octaves, func(p Vector) float64 {
+		return math.Abs(Noise(p))
+	})
+}
+
+// sumOctaves sums the octaves of the noise function for FBm and Turbulence.
+func sumOctaves(p Vector, octaves float64, noise func(Vector) float64) float64 {
+	result := 0.0
+	amplitude := 1.0
+	whole := int(math.Max(0, octaves))
+	for i := 0; i < whole; i++ {
+		result += amplitude * noise(p)
+		amplitude *= 0.5
+		p = VectorMultiply(p, 2)
+	}
+
+	if part := octaves - float64(whole); part > 0 {
+		result += part * amplitude * noise(p)
+	}
+
+	return result
+}
+
+// Worley returns the distances from the point to the closest and the second closest of the feature points
+// scattered one per unit cell, which give the cellular pattern.
+func Worley(p Vector) (closest, second float64) {
+	closest, second = math.Inf(1), math.Inf(1)
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	x, y, z := int(fx), int(fy), int(fz)
+	for i := -1; i <= 1; i++ {
+		for j := -1; j <= 1; j++ {
+			for k := -1; k <= 1; k++ {
+				cx, cy, cz := x+i, y+j, z+k
+				feature := NewVector(
+					float64(cx)+float64(hashLattice(cx, cy, cz))/256,
+					float64(cy)+float64(hashLattice(cx+101, cy, cz))/256,
+					float64(cz)+float64(hashLattice(cx, cy+211, cz))/256)
+				distance := VectorSubstraction(feature, p)
+				length := distance.Length()
+				if length < closest {
+					closest, second = length, closest
+				} else if length < second {
+					second = length
+				}
+			}
+		}
+	}
+
+	return
+}
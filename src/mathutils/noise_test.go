@@ -0,0 +1,97 @@
+package mathutils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNoise(t *testing.T) {
+	if Noise(NewVector(0, 0, 0)) != 0 || Noise(NewVector(3, -7, 12)) != 0 {
+		t.Errorf("Noise() failed!")
+	}
+
+	p := NewVector(1.3, -2.7, 0.45)
+	if Noise(p) != Noise(p) {
+		t.Errorf("Noise() failed!")
+	}
+
+	varies := false
+	for i := 0; i < 1000; i++ {
+		value := Noise(NewVector(float64(i)*0.173, float64(i)*-0.091, float64(i)*0.057))
+		if value < -1.5 || value > 1.5 {
+			t.Errorf("Noise() failed!")
+		}
+
+		if math.Abs(value) > 0.1 {
+			varies = true
+		}
+	}
+
+	if !varies {
+		t.Errorf("Noise() failed!")
+	}
+
+	// The noise is continuous.
+	if math.Abs(Noise(p)-Noise(VectorAddition(p, NewVector(1e-6, 1e-6, 1e-6)))) > 1e-4 {
+		t.Errorf("Noise() failed!")
+	}
+}
+
+func TestFBm(t *testing.T) {
+	p := NewVector(0.31, 1.7, -4.2)
+	if FBm(p, 1) != Noise(p) {
+		t.Errorf("FBm() failed!")
+	}
+
+	if math.Abs(FBm(p, 2)-(Noise(p)+0.5*Noise(VectorMultiply(p, 2)))) > 1e-12 {
+		t.Errorf("FBm() failed!")
+	}
+
+	// Fractional octaves blend smoothly between the whole numbers.
+	if math.Abs(FBm(p, 1.5)-(FBm(p, 1)+FBm(p, 2))/2) > 1e-12 {
+		t.Errorf("FBm() failed!")
+	}
+
+	if FBm(p, 0) != 0 {
+		t.Errorf("FBm() failed!")
+	}
+}
+
+func TestTurbulence(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		p := NewVector(float64(i)*0.37, float64(i)*0.11, float64(i)*-0.23)
+		if Turbulence(p, 4) < 0 {
+			t.Errorf("Turbulence() failed!")
+		}
+	}
+
+	p := NewVector(0.31, 1.7, -4.2)
+	if Turbulence(p, 1) != math.Abs(Noise(p)) {
+		t.Errorf("Turbulence() failed!")
+	}
+}
+
+func TestWorley(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		p := NewVector(float64(i)*0.37, float64(i)*0.11, float64(i)*-0.23)
+		closest, second := Worley(p)
+		if closest < 0 || closest > second || closest > math.Sqrt(3) {
+			t.Errorf("Worley() failed!")
+		}
+	}
+
+	// Moving towards the closest feature point decreases the distance.
+	p := NewVector(0.5, 0.5, 0.5)
+	closest, _ := Worley(p)
+	found := false
+	for _, offset := range []Vector{NewVector(0.01, 0, 0), NewVector(-0.01, 0, 0), NewVector(0, 0.01, 0),
+		NewVector(0, -0.01, 0), NewVector(0, 0, 0.01), NewVector(0, 0, -0.01)} {
+		if moved, _ := Worley(VectorAddition(p, offset)); moved < closest {
+			found = true
+		}
+	}
+
+	if !found && closest > 0 {
+		t.Errorf("Worley() failed!")
+	}
+}
@@ -0,0 +1,138 @@
+// Package mathutils provides some mathematical utilities used in the raytracer.
+package mathutils
+
+import (
+	"math"
+	"sort"
+)
+
+// Values closer than this to zero are treated as zero by the polynomial solvers.
+const polynomialEpsilon = 1e-9
+
+func isZero(value float64) bool {
+	return math.Abs(value) < polynomialEpsilon
+}
+
+// Return the real roots of a*x^2 + b*x + c = 0 in ascending order.
+func SolveQuadratic(a, b, c float64) []float64 {
+	if isZero(a) {
+		if isZero(b) {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+
+	p := b / (2 * a)
+	q := c / a
+	discriminant := p*p - q
+
+	if isZero(discriminant) {
+		return []float64{-p}
+	}
+	if discriminant < 0 {
+		return nil
+	}
+
+	sqrtDiscriminant := math.Sqrt(discriminant)
+	return []float64{-sqrtDiscriminant - p, sqrtDiscriminant - p}
+}
+
+// Return the real roots of a*x^3 + b*x^2 + c*x + d = 0 in ascending order.
+func SolveCubic(a, b, c, d float64) []float64 {
+	if isZero(a) {
+		return SolveQuadratic(b, c, d)
+	}
+
+	A := b / a
+	B := c / a
+	C := d / a
+
+	// Substitute x = y - A/3 to eliminate the quadric term: y^3 + p*y + q = 0.
+	sqA := A * A
+	p := 1.0 / 3 * (-1.0/3*sqA + B)
+	q := 1.0 / 2 * (2.0/27*A*sqA - 1.0/3*A*B + C)
+
+	cbP := p * p * p
+	discriminant := q*q + cbP
+
+	var roots []float64
+	if isZero(discriminant) {
+		if isZero(q) {
+			roots = []float64{0}
+		} else {
+			u := math.Cbrt(-q)
+			roots = []float64{2 * u, -u}
+		}
+	} else if discriminant < 0 {
+		phi := 1.0 / 3 * math.Acos(-q/math.Sqrt(-cbP))
+		t := 2 * math.Sqrt(-p)
+		roots = []float64{t * math.Cos(phi), -t * math.Cos(phi+math.Pi/3), -t * math.Cos(phi-math.Pi/3)}
+	} else {
+		sqrtDiscriminant := math.Sqrt(discriminant)
+		u := math.Cbrt(sqrtDiscriminant - q)
+		v := -math.Cbrt(sqrtDiscriminant + q)
+		roots = []float64{u + v}
+	}
+
+	for i := range roots {
+		roots[i] -= A / 3
+	}
+	sort.Float64s(roots)
+	return roots
+}
+
+// Return the real roots of a*x^4 + b*x^3 + c*x^2 + d*x + e = 0 in ascending order.
+func SolveQuartic(a, b, c, d, e float64) []float64 {
+	if isZero(a) {
+		return SolveCubic(b, c, d, e)
+	}
+
+	A := b / a
+	B := c / a
+	C := d / a
+	D := e / a
+
+	// Substitute x = y - A/4 to eliminate the cubic term: y^4 + p*y^2 + q*y + r = 0.
+	sqA := A * A
+	p := -3.0/8*sqA + B
+	q := 1.0/8*sqA*A - 1.0/2*A*B + C
+	r := -3.0/256*sqA*sqA + 1.0/16*sqA*B - 1.0/4*A*C + D
+
+	var roots []float64
+	if isZero(r) {
+		roots = append(SolveCubic(1, 0, p, q), 0)
+	} else {
+		// Take one real root of the resolvent cubic and split into two quadratics.
+		z := SolveCubic(1, -1.0/2*p, -r, 1.0/2*r*p-1.0/8*q*q)
+		zMax := z[len(z)-1]
+
+		u := zMax*zMax - r
+		v := 2*zMax - p
+		if isZero(u) {
+			u = 0
+		} else if u > 0 {
+			u = math.Sqrt(u)
+		} else {
+			return nil
+		}
+
+		if isZero(v) {
+			v = 0
+		} else if v > 0 {
+			v = math.Sqrt(v)
+		} else {
+			return nil
+		}
+
+		if q < 0 {
+			v = -v
+		}
+		roots = append(SolveQuadratic(1, v, zMax-u), SolveQuadratic(1, -v, zMax+u)...)
+	}
+
+	for i := range roots {
+		roots[i] -= A / 4
+	}
+	sort.Float64s(roots)
+	return roots
+}
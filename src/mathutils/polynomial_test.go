@@ -0,0 +1,77 @@
+package mathutils
+
+import (
+	"math"
+	"testing"
+)
+
+func compareRoots(roots, expected []float64) bool {
+	if len(roots) != len(expected) {
+		return false
+	}
+
+	for i := range roots {
+		if math.Abs(roots[i]-expected[i]) > 1e-6 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestSolveQuadratic(t *testing.T) {
+	if !compareRoots(SolveQuadratic(1, -3, 2), []float64{1, 2}) {
+		t.Errorf("SolveQuadratic() failed!")
+	}
+
+	if !compareRoots(SolveQuadratic(1, 2, 1), []float64{-1}) {
+		t.Errorf("SolveQuadratic() failed!")
+	}
+
+	if !compareRoots(SolveQuadratic(1, 0, 1), []float64{}) {
+		t.Errorf("SolveQuadratic() failed!")
+	}
+
+	if !compareRoots(SolveQuadratic(0, 2, -4), []float64{2}) {
+		t.Errorf("SolveQuadratic() failed!")
+	}
+}
+
+func TestSolveCubic(t *testing.T) {
+	// (x - 1)(x - 2)(x - 3)
+	if !compareRoots(SolveCubic(1, -6, 11, -6), []float64{1, 2, 3}) {
+		t.Errorf("SolveCubic() failed!")
+	}
+
+	// (x - 1)(x^2 + 1)
+	if !compareRoots(SolveCubic(2, -2, 2, -2), []float64{1}) {
+		t.Errorf("SolveCubic() failed!")
+	}
+
+	// (x + 2)^2 (x - 1)
+	if !compareRoots(SolveCubic(1, 3, 0, -4), []float64{-2, 1}) {
+		t.Errorf("SolveCubic() failed!")
+	}
+}
+
+func TestSolveQuartic(t *testing.T) {
+	// (x - 1)(x - 2)(x - 3)(x - 4)
+	if !compareRoots(SolveQuartic(1, -10, 35, -50, 24), []float64{1, 2, 3, 4}) {
+		t.Errorf("SolveQuartic() failed!")
+	}
+
+	// (x^2 - 4)(x^2 + 1)
+	if !compareRoots(SolveQuartic(3, 0, -9, 0, -12), []float64{-2, 2}) {
+		t.Errorf("SolveQuartic() failed!")
+	}
+
+	// (x^2 + 1)(x^2 + 4)
+	if !compareRoots(SolveQuartic(1, 0, 5, 0, 4), []float64{}) {
+		t.Errorf("SolveQuartic() failed!")
+	}
+
+	// x (x - 1)(x + 1)(x - 5)
+	if !compareRoots(SolveQuartic(1, -5, -1, 5, 0), []float64{-1, 0, 1, 5}) {
+		t.Errorf("SolveQuartic() failed!")
+	}
+}
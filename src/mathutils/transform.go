@@ -0,0 +1,158 @@
+// Package mathutils provides some mathematical utilities used in the raytracer.
+package mathutils
+
+import "math"
+
+// Defines an affine transformation as a 4 x 4 homogeneous matrix.
+// Like Matrix it transforms row vectors, so the translation is in the last row.
+type Transform [4][4]float64
+
+// Create a new identity transformation and return it.
+func NewTransform() Transform {
+	var result Transform
+	for i := 0; i < 4; i++ {
+		result[i][i] = 1
+	}
+
+	return result
+}
+
+// Create a transformation that moves points by offset.
+func TranslationTransform(offset Vector) Transform {
+	result := NewTransform()
+	result[3][0] = offset.X
+	result[3][1] = offset.Y
+	result[3][2] = offset.Z
+
+	return result
+}
+
+// Create a transformation that scales points by factors along the X, Y and Z axis.
+func ScalingTransform(factors Vector) Transform {
+	result := NewTransform()
+	result[0][0] = factors.X
+	result[1][1] = factors.Y
+	result[2][2] = factors.Z
+
+	return result
+}
+
+// Create a transformation that rotates points around the X, Y and Z axis by angles(in radians), in this order.
+func RotationTransform(angles Vector) Transform {
+	rotation := MatrixMultiplication(MatrixMultiplication(RotationAroundX(angles.X), RotationAroundY(angles.Y)), RotationAroundZ(angles.Z))
+	return MatrixTransform(rotation)
+}
+
+// Create a transformation that rotates points by the unit quaternion with vector part x, y, z and scalar part w.
+// The rotation is counterclockwise around the axis in a right-handed coordinate system.
+func QuaternionTransform(x, y, z, w float64) Transform {
+	result := NewTransform()
+	result[0][0] = 1 - 2*(y*y+z*z)
+	result[0][1] = 2 * (x*y + z*w)
+	result[0][2] = 2 * (x*z - y*w)
+	result[1][0] = 2 * (x*y - z*w)
+	result[1][1] = 1 - 2*(x*x+z*z)
+	result[1][2] = 2 * (y*z + x*w)
+	result[2][0] = 2 * (x*z + y*w)
+	result[2][1] = 2 * (y*z - x*w)
+	result[2][2] = 1 - 2*(x*x+y*y)
+
+	return result
+}
+
+// Create a transformation with the linear part m and no translation.
+func MatrixTransform(m Matrix) Transform {
+	result := NewTransform()
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			result[i][j] = m[i][j]
+		}
+	}
+
+	return result
+}
+
+// Mutiply lhs by rhs and return the result. The result applies lhs first and rhs second.
+func TransformMultiplication(lhs, rhs Transform) Transform {
+	var result Transform
+
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				result[i][j] += lhs[i][k] * rhs[k][j]
+			}
+		}
+	}
+
+	return result
+}
+
+// Return the transposed t.
+func (t *Transform) Transpose() Transform {
+	var result Transform
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			result[i][j] = t[j][i]
+		}
+	}
+
+	return result
+}
+
+// Return the inverse of t and whether t is invertible.
+func (t *Transform) Inverse() (Transform, bool) {
+	matrix := *t
+	result := NewTransform()
+
+	for column := 0; column < 4; column++ {
+		pivot := column
+		for row := column + 1; row < 4; row++ {
+			if math.Abs(matrix[row][column]) > math.Abs(matrix[pivot][column]) {
+				pivot = row
+			}
+		}
+
+		if math.Abs(matrix[pivot][column]) < 1e-12 {
+			return NewTransform(), false
+		}
+
+		matrix[column], matrix[pivot] = matrix[pivot], matrix[column]
+		result[column], result[pivot] = result[pivot], result[column]
+
+		divider := matrix[column][column]
+		for j := 0; j < 4; j++ {
+			matrix[column][j] /= divider
+			result[column][j] /= divider
+		}
+
+		for row := 0; row < 4; row++ {
+			if row == column {
+				continue
+			}
+
+			factor := matrix[row][column]
+			for j := 0; j < 4; j++ {
+				matrix[row][j] -= factor * matrix[column][j]
+				result[row][j] -= factor * result[column][j]
+			}
+		}
+	}
+
+	return result, true
+}
+
+// Return the transposed inverse of t, which is used to transform normals.
+func (t *Transform) InverseTranspose() (Transform, bool) {
+	inverse, ok := t.Inverse()
+	return inverse.Transpose(), ok
+}
+
+// Transform the point p by t and return the result.
+func MultiplyPointTransform(p Vector, t Transform) Vector {
+	return Vector{p.X*t[0][0] + p.Y*t[1][0] + p.Z*t[2][0] + t[3][0], p.X*t[0][1] + p.Y*t[1][1] + p.Z*t[2][1] + t[3][1], p.X*t[0][2] + p.Y*t[1][2] + p.Z*t[2][2] + t[3][2]}
+}
+
+// Transform the direction v by t, ignoring the translation, and return the result.
+func MultiplyDirectionTransform(v Vector, t Transform) Vector {
+	return Vector{v.X*t[0][0] + v.Y*t[1][0] + v.Z*t[2][0], v.X*t[0][1] + v.Y*t[1][1] + v.Z*t[2][1], v.X*t[0][2] + v.Y*t[1][2] + v.Z*t[2][2]}
+}
@@ -0,0 +1,111 @@
+package mathutils
+
+import (
+	"math"
+	"testing"
+)
+
+func compareTransforms(lhs, rhs Transform) bool {
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			if math.Abs(lhs[i][j]-rhs[i][j]) > 1e-10 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func TestNewTransform(t *testing.T) {
+	transform := NewTransform()
+	expected := Transform{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}}
+	if transform != expected {
+		t.Errorf("NewTransform() failed!")
+	}
+}
+
+func TestTranslationTransform(t *testing.T) {
+	transform := TranslationTransform(NewVector(1, 2, 3))
+	if MultiplyPointTransform(NewVector(1, 1, 1), transform) != NewVector(2, 3, 4) {
+		t.Errorf("TranslationTransform() failed!")
+	}
+
+	if MultiplyDirectionTransform(NewVector(1, 1, 1), transform) != NewVector(1, 1, 1) {
+		t.Errorf("TranslationTransform() failed!")
+	}
+}
+
+func TestScalingTransform(t *testing.T) {
+	transform := ScalingTransform(NewVector(2, 3, 4))
+	if MultiplyPointTransform(NewVector(1, 1, 1), transform) != NewVector(2, 3, 4) {
+		t.Errorf("ScalingTransform() failed!")
+	}
+}
+
+func TestRotationTransform(t *testing.T) {
+	transform := RotationTransform(NewVector(0, math.Pi/2, 0))
+	res := MultiplyPointTransform(NewVector(1, 0, 0), transform)
+	if math.Abs(res.X) > 1e-10 || math.Abs(res.Y) > 1e-10 || math.Abs(res.Z-1) > 1e-10 {
+		t.Errorf("RotationTransform() failed!")
+	}
+}
+
+func TestQuaternionTransform(t *testing.T) {
+	halfAngle := math.Pi / 4
+	transform := QuaternionTransform(0, 0, math.Sin(halfAngle), math.Cos(halfAngle))
+	result := MultiplyDirectionTransform(NewVector(1, 0, 0), transform)
+	if math.Abs(result.X) > 1e-10 || math.Abs(result.Y-1) > 1e-10 || math.Abs(result.Z) > 1e-10 {
+		t.Errorf("QuaternionTransform() failed!")
+	}
+
+	transform = QuaternionTransform(math.Sin(halfAngle), 0, 0, math.Cos(halfAngle))
+	result = MultiplyDirectionTransform(NewVector(0, 1, 0), transform)
+	if math.Abs(result.X) > 1e-10 || math.Abs(result.Y) > 1e-10 || math.Abs(result.Z-1) > 1e-10 {
+		t.Errorf("QuaternionTransform() failed!")
+	}
+
+	if !compareTransforms(QuaternionTransform(0, 0, 0, 1), NewTransform()) {
+		t.Errorf("QuaternionTransform() failed!")
+	}
+}
+
+func TestTransformMultiplication(t *testing.T) {
+	transform := TransformMultiplication(ScalingTransform(NewVector(2, 2, 2)), TranslationTransform(NewVector(1, 0, 0)))
+	if MultiplyPointTransform(NewVector(1, 1, 1), transform) != NewVector(3, 2, 2) {
+		t.Errorf("TransformMultiplication() failed!")
+	}
+
+	transform = TransformMultiplication(TranslationTransform(NewVector(1, 0, 0)), ScalingTransform(NewVector(2, 2, 2)))
+	if MultiplyPointTransform(NewVector(1, 1, 1), transform) != NewVector(4, 2, 2) {
+		t.Errorf("TransformMultiplication() failed!")
+	}
+}
+
+func TestTransformInverse(t *testing.T) {
+	transform := TransformMultiplication(TransformMultiplication(ScalingTransform(NewVector(2, 3, 4)), RotationTransform(NewVector(0.3, 0.5, 0.7))), TranslationTransform(NewVector(5, -6, 7)))
+	inverse, ok := transform.Inverse()
+	if !ok || !compareTransforms(TransformMultiplication(transform, inverse), NewTransform()) {
+		t.Errorf("Transform.Inverse() failed!")
+	}
+
+	singular := ScalingTransform(NewVector(1, 0, 1))
+	if _, ok = singular.Inverse(); ok {
+		t.Errorf("Transform.Inverse() failed!")
+	}
+}
+
+func TestTransformInverseTranspose(t *testing.T) {
+	transform := ScalingTransform(NewVector(1, 2, 1))
+	normalTransform, ok := transform.InverseTranspose()
+	if !ok {
+		t.Errorf("Transform.InverseTranspose() failed!")
+	}
+
+	// The normal of the plane x + y = 0 must stay perpendicular to it after scaling.
+	normal := MultiplyDirectionTransform(NewVector(1, 1, 0), normalTransform)
+	tangent := MultiplyDirectionTransform(NewVector(1, -1, 0), transform)
+	if math.Abs(DotProduct(normal, tangent)) > 1e-10 {
+		t.Errorf("Transform.InverseTranspose() failed!")
+	}
+}
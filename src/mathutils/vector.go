@@ -94,3 +94,16 @@ func Faceforward(ray, normal Vector) Vector {
 	normal.UnaryMinus()
 	return normal
 }
+
+// Return two unit vectors that together with the unit vector normal form an orthonormal basis.
+func OrthonormalBasis(normal Vector) (Vector, Vector) {
+	var tangent Vector
+	if math.Abs(normal.Y) < 0.999 {
+		tangent = CrossProduct(NewVector(0, 1, 0), normal)
+	} else {
+		tangent = CrossProduct(normal, NewVector(0, 0, 1))
+	}
+	tangent.Normalize()
+
+	return tangent, CrossProduct(normal, tangent)
+}
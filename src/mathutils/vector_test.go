@@ -239,3 +239,23 @@ func TestVectorMultiply(t *testing.T) {
 		t.Errorf("VectorMultiply() failed!")
 	}
 }
+
+func TestOrthonormalBasis(t *testing.T) {
+	normals := []Vector{NewVector(0, 0, 1), NewVector(0, 1, 0), NewVector(0, -1, 0), NewVector(1, 2, 3)}
+	for _, normal := range normals {
+		normal.Normalize()
+		tangent, bitangent := OrthonormalBasis(normal)
+		if math.Abs(tangent.Length()-1) > 1e-10 || math.Abs(bitangent.Length()-1) > 1e-10 {
+			t.Errorf("OrthonormalBasis() failed!")
+		}
+
+		if math.Abs(DotProduct(tangent, normal)) > 1e-10 || math.Abs(DotProduct(bitangent, normal)) > 1e-10 || math.Abs(DotProduct(tangent, bitangent)) > 1e-10 {
+			t.Errorf("OrthonormalBasis() failed!")
+		}
+	}
+
+	tangent, bitangent := OrthonormalBasis(NewVector(0, 0, 1))
+	if tangent != NewVector(1, 0, 0) || bitangent != NewVector(0, 1, 0) {
+		t.Errorf("OrthonormalBasis() failed!")
+	}
+}
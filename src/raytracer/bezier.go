@@ -0,0 +1,156 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// BezierPatch defines a bicubic Bezier patch by its 4 x 4 control points, indexed by row along V and column along U.
+type BezierPatch [4][4]mathutils.Vector
+
+// bernstein returns the cubic Bernstein polynomials and their derivatives at t.
+func bernstein(t float64) (values, derivatives [4]float64) {
+	s := 1 - t
+	values = [4]float64{s * s * s, 3 * t * s * s, 3 * t * t * s, t * t * t}
+	derivatives = [4]float64{-3 * s * s, 3 * s * (s - 2*t), 3 * t * (2*s - t), 3 * t * t}
+	return
+}
+
+// Evaluate returns the point and the partial derivatives along U and V of the patch at u, v.
+func (p *BezierPatch) Evaluate(u, v float64) (point, dPdu, dPdv mathutils.Vector) {
+	bu, du := bernstein(u)
+	bv, dv := bernstein(v)
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			control := p[row][column]
+			point.Add(mathutils.VectorMultiply(control, bu[column]*bv[row]))
+			dPdu.Add(mathutils.VectorMultiply(control, du[column]*bv[row]))
+			dPdv.Add(mathutils.VectorMultiply(control, bu[column]*dv[row]))
+		}
+	}
+
+	return
+}
+
+// normal returns the unit normal of the patch at u, v. Where the patch is degenerate,
+// like at the tip of a cone shaped patch, the normal is taken slightly inside the patch.
+func (p *BezierPatch) normal(u, v float64) mathutils.Vector {
+	for _, offset := range []float64{0, 1e-4, 1e-3, 1e-2} {
+		_, dPdu, dPdv := p.Evaluate(u+offset*(0.5-u)*2, v+offset*(0.5-v)*2)
+		normal := mathutils.CrossProduct(dPdu, dPdv)
+		if normal.LengthSqr() > 1e-20 {
+			normal.Normalize()
+			return normal
+		}
+	}
+
+	return mathutils.NewVector(0, 1, 0)
+}
+
+// curvature returns a bound of the second derivatives of the patch, from the second differences of the control points.
+func (p *BezierPatch) curvature() float64 {
+	result := 0.0
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 2; j++ {
+			alongU := mathutils.VectorAddition(p[i][j], p[i][j+2])
+			alongU = mathutils.VectorSubstraction(alongU, mathutils.VectorMultiply(p[i][j+1], 2))
+			alongV := mathutils.VectorAddition(p[j][i], p[j+2][i])
+			alongV = mathutils.VectorSubstraction(alongV, mathutils.VectorMultiply(p[j+1][i], 2))
+			result = math.Max(result, math.Max(alongU.Length(), alongV.Length()))
+		}
+	}
+
+	return 6 * result
+}
+
+// TessellateBezierPatches turns the patches into mesh data with exact normals and per patch U and V coordinates.
+// The number of segments along each side of the patches is chosen so that the triangles stay within
+// tolerance of the surface. All patches use the same number so the edges of neighbouring patches match.
+func TessellateBezierPatches(patches []BezierPatch, tolerance float64) MeshData {
+	// The linear interpolation error over a segment of length h is at most h^2 / 8 times the second derivative.
+	curvature := 0.0
+	for i := range patches {
+		curvature = math.Max(curvature, patches[i].curvature())
+	}
+	segments := int(math.Ceil(math.Sqrt(curvature / (8 * tolerance))))
+	segments = int(math.Max(1, math.Min(float64(segments), 64)))
+
+	var data MeshData
+	for i := range patches {
+		first := len(data.Vertices)
+		for row := 0; row <= segments; row++ {
+			for column := 0; column <= segments; column++ {
+				u, v := float64(column)/float64(segments), float64(row)/float64(segments)
+				point, _, _ := patches[i].Evaluate(u, v)
+				data.Vertices = append(data.Vertices, point)
+				data.Normals = append(data.Normals, patches[i].normal(u, v))
+				data.UVs = append(data.UVs, [2]float64{u, v})
+			}
+		}
+
+		for row := 0; row < segments; row++ {
+			for column := 0; column < segments; column++ {
+				corner := first + row*(segments+1) + column
+				data.Polygons = append(data.Polygons, []int{corner, corner + 1, corner + segments + 2, corner + segments + 1})
+			}
+		}
+	}
+
+	return data
+}
+
+// ReadBezierPatches reads patches in the text format of the Utah teapot data: the number of patches
+// followed by, for each patch, the degrees along U and V, which must be 3 3, and its 16 control points.
+func ReadBezierPatches(reader io.Reader) ([]BezierPatch, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(bufio.ScanWords)
+	next := func() (float64, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.ErrUnexpectedEOF
+		}
+		return strconv.ParseFloat(scanner.Text(), 64)
+	}
+
+	count, err := next()
+	if err != nil || count < 1 || count != math.Floor(count) {
+		return nil, fmt.Errorf("Bezier patches: incorrect patch count")
+	}
+
+	patches := make([]BezierPatch, int(count))
+	for i := range patches {
+		degreeU, err := next()
+		if err != nil {
+			return nil, fmt.Errorf("Bezier patch %d: %v", i, err)
+		}
+		degreeV, err := next()
+		if err != nil {
+			return nil, fmt.Errorf("Bezier patch %d: %v", i, err)
+		}
+		if degreeU != 3 || degreeV != 3 {
+			return nil, fmt.Errorf("Bezier patch %d: only bicubic patches are supported", i)
+		}
+
+		for row := 0; row < 4; row++ {
+			for column := 0; column < 4; column++ {
+				var coordinates [3]float64
+				for k := range coordinates {
+					coordinates[k], err = next()
+					if err != nil {
+						return nil, fmt.Errorf("Bezier patch %d: %v", i, err)
+					}
+				}
+				patches[i][row][column] = mathutils.NewVector(coordinates[0], coordinates[1], coordinates[2])
+			}
+		}
+	}
+
+	return patches, nil
+}
@@ -0,0 +1,50 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTessellateBezierPatches(t *testing.T) {
+	var patch BezierPatch
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			height := 0.0
+			if row > 0 && row < 3 && column > 0 && column < 3 {
+				height = 1
+			}
+			patch[row][column] = mathutils.NewVector(float64(column), height, float64(row))
+		}
+	}
+
+	point, dPdu, dPdv := patch.Evaluate(0.5, 0.5)
+	if !compareVectors(point, mathutils.NewVector(1.5, 0.5625, 1.5)) || !compareVectors(dPdu, mathutils.NewVector(3, 0, 0)) || !compareVectors(dPdv, mathutils.NewVector(0, 0, 3)) {
+		t.Errorf("BezierPatch.Evaluate() failed!")
+	}
+
+	coarse := TessellateBezierPatches([]BezierPatch{patch}, 0.1)
+	fine := TessellateBezierPatches([]BezierPatch{patch}, 0.001)
+	if len(coarse.Polygons) >= len(fine.Polygons) || fine.Validate() != nil {
+		t.Errorf("TessellateBezierPatches() failed!")
+	}
+
+	mesh := NewMesh(fine)
+	var info IntersectionInfo
+	ray := NewRay(mathutils.NewVector(1.5, 10, 1.5), mathutils.NewVector(0, -1, 0))
+	if !mesh.Intersect(&ray, &info) || math.Abs(info.Distance-(10-0.5625)) > 1e-3 || math.Abs(info.U-0.5) > 1e-3 || math.Abs(info.V-0.5) > 1e-3 {
+		t.Errorf("TessellateBezierPatches() failed!")
+	}
+	if mathutils.DotProduct(info.Normal, mathutils.NewVector(0, -1, 0)) < 0.999 {
+		t.Errorf("TessellateBezierPatches() failed!")
+	}
+
+	patches, err := ReadBezierPatches(strings.NewReader("1\n3 3\n" + strings.Repeat("0 0 0\n", 16)))
+	if err != nil || len(patches) != 1 {
+		t.Errorf("ReadBezierPatches() failed!")
+	}
+	if _, err := ReadBezierPatches(strings.NewReader("1\n2 2\n")); err == nil {
+		t.Errorf("ReadBezierPatches() failed!")
+	}
+}
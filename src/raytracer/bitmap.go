@@ -0,0 +1,459 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"bufio"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Registers the JPEG decoder for the bitmap textures.
+	_ "image/png"  // Registers the PNG decoder for the bitmap textures.
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Bitmap wrap modes
+const (
+	WrapRepeat = iota
+	WrapClamp
+	WrapMirror
+)
+
+// Bitmap filters
+const (
+	FilterNearest   = iota // The closest pixel of the full image.
+	FilterBilinear         // The four closest pixels of the full image.
+	FilterTrilinear        // Bilinear lookups in the two mipmap levels matching the size of the pixel footprint.
+	FilterEWA              // Gaussian weighted pixels in the elliptical footprint, for surfaces seen at grazing angles.
+)
+
+// Longest ratio between the axes of the elliptical footprint of FilterEWA.
+const maxAnisotropy = 8
+
+// bitmapLevel holds the linear colors of an image, row by row from the top.
+type bitmapLevel struct {
+	width, height int
+	pixels        []utils.Color
+}
+
+// at returns the color of the pixel in column x and row y.
+func (b *bitmapLevel) at(x, y int) utils.Color {
+	return b.pixels[y*b.width+x]
+}
+
+// halved returns the level with half the width and height, averaging blocks of 2 x 2 pixels.
+func (b *bitmapLevel) halved() bitmapLevel {
+	result := bitmapLevel{width: int(math.Max(1, float64(b.width/2))), height: int(math.Max(1, float64(b.height/2)))}
+	result.pixels = make([]utils.Color, 0, result.width*result.height)
+	for y := 0; y < result.height; y++ {
+		for x := 0; x < result.width; x++ {
+			var color utils.Color
+			for _, offset := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				sourceX := int(math.Min(float64(2*x+offset[0]), float64(b.width-1)))
+				sourceY := int(math.Min(float64(2*y+offset[1]), float64(b.height-1)))
+				color = utils.ColorAddition(color, utils.MultiplyColorFloat(b.at(sourceX, sourceY), 0.25))
+			}
+			result.pixels = append(result.pixels, color)
+		}
+	}
+
+	return result
+}
+
+// bitmapImage holds an image and its mipmap, the levels of halved size down to a single pixel.
+type bitmapImage struct {
+	levels []bitmapLevel // The levels, starting with the full image.
+}
+
+// newBitmapImage creates and returns the image with the mipmap of the given full size level.
+func newBitmapImage(full bitmapLevel) *bitmapImage {
+	result := &bitmapImage{[]bitmapLevel{full}}
+	for last := &result.levels[0]; last.width > 1 || last.height > 1; last = &result.levels[len(result.levels)-1] {
+		result.levels = append(result.levels, last.halved())
+	}
+
+	return result
+}
+
+// bitmapCache holds the images already loaded, by path and color space, so that textures sharing a file share the image.
+var bitmapCache = struct {
+	sync.Mutex
+	images map[string]*bitmapImage
+}{images: make(map[string]*bitmapImage)}
+
+// loadBitmapImage loads an image file, or returns the cached image if it was already loaded.
+// PNG and JPEG files are converted from sRGB to linear colors unless linear is set.
+// Radiance HDR files always hold linear colors.
+func loadBitmapImage(path string, linear bool) (*bitmapImage, error) {
+	key := fmt.Sprintf("%s|%v", path, linear)
+	bitmapCache.Lock()
+	defer bitmapCache.Unlock()
+	if cached, ok := bitmapCache.images[key]; ok {
+		return cached, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var full *bitmapLevel
+	if strings.ToLower(filepath.Ext(path)) == ".hdr" {
+		full, err = readHDR(file)
+	} else {
+		full, err = decodeBitmapImage(file, linear)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	result := newBitmapImage(*full)
+	bitmapCache.images[key] = result
+	return result, nil
+}
+
+// decodeBitmapImage decodes a PNG or JPEG image.
+func decodeBitmapImage(reader io.Reader, linear bool) (*bitmapLevel, error) {
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	result := &bitmapLevel{width: bounds.Dx(), height: bounds.Dy()}
+	if result.width == 0 || result.height == 0 {
+		return nil, fmt.Errorf("Image is empty")
+	}
+
+	result.pixels = make([]utils.Color, 0, result.width*result.height)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			color := utils.Color{float64(r) / 0xffff, float64(g) / 0xffff, float64(b) / 0xffff}
+			if !linear {
+				for i := range color {
+					color[i] = srgbToLinear(color[i])
+				}
+			}
+			result.pixels = append(result.pixels, color)
+		}
+	}
+
+	return result, nil
+}
+
+// srgbToLinear converts an sRGB encoded value in [0, 1] to a linear one.
+func srgbToLinear(value float64) float64 {
+	if value <= 0.04045 {
+		return value / 12.92
+	}
+
+	return math.Pow((value+0.055)/1.055, 2.4)
+}
+
+// readHDR reads an image in the Radiance HDR format, with flat or run length encoded scanlines.
+// Only the usual orientation, -Y height +X width, is supported.
+func readHDR(reader io.Reader) (*bitmapLevel, error) {
+	buffered := bufio.NewReader(reader)
+	line, err := buffered.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "#?") {
+		return nil, fmt.Errorf("Not a Radiance HDR file")
+	}
+
+	// The header ends with an empty line and is followed by the resolution.
+	for {
+		line, err = buffered.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("HDR header ends without resolution")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("Unsupported HDR format %s", strings.TrimPrefix(line, "FORMAT="))
+		}
+	}
+
+	line, err = buffered.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("HDR file has no resolution")
+	}
+	result := &bitmapLevel{}
+	if _, err := fmt.Sscanf(line, "-Y %d +X %d", &result.height, &result.width); err != nil {
+		return nil, fmt.Errorf("Unsupported HDR resolution %s", strings.TrimSpace(line))
+	}
+	if result.width <= 0 || result.height <= 0 || result.width*result.height > 1<<28 {
+		return nil, fmt.Errorf("Incorrect HDR resolution %dx%d", result.width, result.height)
+	}
+
+	result.pixels = make([]utils.Color, 0, result.width*result.height)
+	scanline := make([]byte, 4*result.width)
+	for y := 0; y < result.height; y++ {
+		if err := readHDRScanline(buffered, scanline, result.width); err != nil {
+			return nil, fmt.Errorf("HDR scanline %d: %v", y, err)
+		}
+		for x := 0; x < result.width; x++ {
+			rgbe := scanline[4*x : 4*x+4]
+			var color utils.Color
+			if rgbe[3] != 0 {
+				scale := math.Ldexp(1, int(rgbe[3])-136)
+				color = utils.Color{float64(rgbe[0]) * scale, float64(rgbe[1]) * scale, float64(rgbe[2]) * scale}
+			}
+			result.pixels = append(result.pixels, color)
+		}
+	}
+
+	return result, nil
+}
+
+// readHDRScanline reads a scanline into RGBE quadruplets. Run length encoded scanlines
+// start with 2 2 and the width, and store the four components one after the other.
+func readHDRScanline(reader *bufio.Reader, scanline []byte, width int) error {
+	header, err := reader.Peek(4)
+	if err != nil {
+		return err
+	}
+	if width < 8 || width > 0x7fff || header[0] != 2 || header[1] != 2 || int(header[2])<<8|int(header[3]) != width {
+		_, err = io.ReadFull(reader, scanline)
+		return err
+	}
+	reader.Discard(4)
+
+	for component := 0; component < 4; component++ {
+		for x := 0; x < width; {
+			count, err := reader.ReadByte()
+			if err != nil {
+				return err
+			}
+
+			run := count > 128
+			if run {
+				count -= 128
+			}
+			if count == 0 || x+int(count) > width {
+				return fmt.Errorf("incorrect run length")
+			}
+
+			value, err := reader.ReadByte()
+			for i := 0; i < int(count) && err == nil; i++ {
+				scanline[4*(x+i)+component] = value
+				if !run && i+1 < int(count) {
+					value, err = reader.ReadByte()
+				}
+			}
+			if err != nil {
+				return err
+			}
+			x += int(count)
+		}
+	}
+
+	return nil
+}
+
+// Bitmap defines a texture sampling an image file. The image covers U and V from 0 to 1,
+// with V going up from the bottom of the image.
+type Bitmap struct {
+	image    *bitmapImage
+	wrap     int        // How U and V outside of [0, 1] are mapped into the image.
+	filter   int        // How the pixels around the sampled position are combined.
+	scale    [2]float64 // The scale applied to U and V.
+	offset   [2]float64 // The offset added to U and V after scaling and rotation.
+	rotation float64    // The rotation of U and V around the origin, in radians.
+}
+
+// NewBitmap creates and returns a new bitmap texture from an image file. The U and V coordinates are
+// scaled, rotated by the angle in degrees and offset before sampling the image.
+func NewBitmap(path string, linear bool, wrap, filter int, scale, offset [2]float64, rotation float64) (Bitmap, error) {
+	img, err := loadBitmapImage(path, linear)
+	if err != nil {
+		return Bitmap{}, err
+	}
+
+	return Bitmap{img, wrap, filter, scale, offset, mathutils.ToRadians(rotation)}, nil
+}
+
+// Sample implements sampling for Bitmap.
+func (b *Bitmap) Sample(info *IntersectionInfo) utils.Color {
+	u, v := b.transform(info.U, info.V)
+	switch b.filter {
+	case FilterNearest:
+		return b.nearest(u, v)
+	case FilterBilinear:
+		return b.bilinear(0, u, v)
+	}
+
+	// The footprint axes in pixels of the full image, rows go down while V goes up.
+	full := &b.image.levels[0]
+	du, dv := b.transformDirection(info.DUdx, info.DVdx)
+	axisX := [2]float64{du * float64(full.width), -dv * float64(full.height)}
+	du, dv = b.transformDirection(info.DUdy, info.DVdy)
+	axisY := [2]float64{du * float64(full.width), -dv * float64(full.height)}
+
+	if b.filter == FilterEWA {
+		return b.ewa(u, v, axisX, axisY)
+	}
+
+	width := math.Max(math.Hypot(axisX[0], axisX[1]), math.Hypot(axisY[0], axisY[1]))
+	return b.trilinear(u, v, math.Log2(width))
+}
+
+// nearest returns the pixel of the full image containing u, v.
+func (b *Bitmap) nearest(u, v float64) utils.Color {
+	full := &b.image.levels[0]
+	x := int(math.Floor(u * float64(full.width)))
+	y := int(math.Floor((1 - v) * float64(full.height)))
+	return b.texel(0, x, y)
+}
+
+// bilinear interpolates the four pixels of the level around u, v.
+func (b *Bitmap) bilinear(level int, u, v float64) utils.Color {
+	// Pixel centers are at half integer positions, with the first row at the top.
+	x := u*float64(b.image.levels[level].width) - 0.5
+	y := (1-v)*float64(b.image.levels[level].height) - 0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	left, top := int(x0), int(y0)
+
+	result := utils.MultiplyColorFloat(b.texel(level, left, top), (1-fx)*(1-fy))
+	result = utils.ColorAddition(result, utils.MultiplyColorFloat(b.texel(level, left+1, top), fx*(1-fy)))
+	result = utils.ColorAddition(result, utils.MultiplyColorFloat(b.texel(level, left, top+1), (1-fx)*fy))
+	result = utils.ColorAddition(result, utils.MultiplyColorFloat(b.texel(level, left+1, top+1), fx*fy))
+	return result
+}
+
+// trilinear interpolates between the bilinear lookups of the two levels around the fractional level of detail.
+func (b *Bitmap) trilinear(u, v, detail float64) utils.Color {
+	last := len(b.image.levels) - 1
+	if detail <= 0 || math.IsNaN(detail) {
+		return b.bilinear(0, u, v)
+	}
+	if detail >= float64(last) {
+		return b.bilinear(last, u, v)
+	}
+
+	level := int(detail)
+	weight := detail - float64(level)
+	return utils.ColorAddition(utils.MultiplyColorFloat(b.bilinear(level, u, v), 1-weight), utils.MultiplyColorFloat(b.bilinear(level+1, u, v), weight))
+}
+
+// ewa filters the elliptical footprint given by its two axes in pixels of the full image.
+// The level of detail follows the minor axis, which is lengthened if the ellipse is too eccentric.
+func (b *Bitmap) ewa(u, v float64, major, minor [2]float64) utils.Color {
+	majorLength, minorLength := math.Hypot(major[0], major[1]), math.Hypot(minor[0], minor[1])
+	if majorLength < minorLength {
+		major, minor = minor, major
+		majorLength, minorLength = minorLength, majorLength
+	}
+	if minorLength == 0 {
+		return b.bilinear(0, u, v)
+	}
+	if minorLength*maxAnisotropy < majorLength {
+		scale := majorLength / (minorLength * maxAnisotropy)
+		minor = [2]float64{minor[0] * scale, minor[1] * scale}
+		minorLength *= scale
+	}
+
+	detail := math.Max(0, math.Log2(minorLength))
+	level := int(detail)
+	weight := detail - float64(level)
+	result := b.ewaLevel(level, u, v, major, minor)
+	if weight > 0 {
+		result = utils.ColorAddition(utils.MultiplyColorFloat(result, 1-weight), utils.MultiplyColorFloat(b.ewaLevel(level+1, u, v, major, minor), weight))
+	}
+
+	return result
+}
+
+// ewaLevel sums the pixels of the level inside the ellipse with Gaussian weights.
+func (b *Bitmap) ewaLevel(level int, u, v float64, axis0, axis1 [2]float64) utils.Color {
+	if level >= len(b.image.levels)-1 {
+		return b.bilinear(len(b.image.levels)-1, u, v)
+	}
+
+	// Move the center and the axes to the pixels of the level.
+	current := &b.image.levels[level]
+	scaleX := float64(current.width) / float64(b.image.levels[0].width)
+	scaleY := float64(current.height) / float64(b.image.levels[0].height)
+	s := u*float64(current.width) - 0.5
+	t := (1-v)*float64(current.height) - 0.5
+	axis0 = [2]float64{axis0[0] * scaleX, axis0[1] * scaleY}
+	axis1 = [2]float64{axis1[0] * scaleX, axis1[1] * scaleY}
+
+	// The implicit equation A s^2 + B s t + C t^2 = 1 of the ellipse, widened by a pixel so it always covers some pixels.
+	a := axis0[1]*axis0[1] + axis1[1]*axis1[1] + 1
+	bb := -2 * (axis0[0]*axis0[1] + axis1[0]*axis1[1])
+	c := axis0[0]*axis0[0] + axis1[0]*axis1[0] + 1
+	f := 1 / (a*c - bb*bb/4)
+	a, bb, c = a*f, bb*f, c*f
+
+	// The bounding box of the ellipse.
+	determinant := 4*a*c - bb*bb
+	halfWidth := 2 * math.Sqrt(determinant*c) / determinant
+	halfHeight := 2 * math.Sqrt(determinant*a) / determinant
+
+	var sum utils.Color
+	weights := 0.0
+	for y := int(math.Ceil(t - halfHeight)); y <= int(math.Floor(t+halfHeight)); y++ {
+		dy := float64(y) - t
+		for x := int(math.Ceil(s - halfWidth)); x <= int(math.Floor(s+halfWidth)); x++ {
+			dx := float64(x) - s
+			radiusSqr := a*dx*dx + bb*dx*dy + c*dy*dy
+			if radiusSqr < 1 {
+				weight := math.Exp(-2*radiusSqr) - math.Exp(-2)
+				sum = utils.ColorAddition(sum, utils.MultiplyColorFloat(b.texel(level, x, y), weight))
+				weights += weight
+			}
+		}
+	}
+	if weights == 0 {
+		return b.bilinear(level, u, v)
+	}
+
+	return utils.DivideColorFloat(sum, weights)
+}
+
+// texel returns the pixel of the level at column x and row y, wrapped into the level.
+func (b *Bitmap) texel(level, x, y int) utils.Color {
+	current := &b.image.levels[level]
+	return current.at(b.wrapIndex(x, current.width), b.wrapIndex(y, current.height))
+}
+
+// transform applies the scale, rotation and offset to U and V.
+func (b *Bitmap) transform(u, v float64) (float64, float64) {
+	u *= b.scale[0]
+	v *= b.scale[1]
+	sin, cos := math.Sincos(b.rotation)
+	return cos*u - sin*v + b.offset[0], sin*u + cos*v + b.offset[1]
+}
+
+// transformDirection applies the scale and rotation to a change of U and V.
+func (b *Bitmap) transformDirection(du, dv float64) (float64, float64) {
+	du *= b.scale[0]
+	dv *= b.scale[1]
+	sin, cos := math.Sincos(b.rotation)
+	return cos*du - sin*dv, sin*du + cos*dv
+}
+
+// wrapIndex maps a pixel index outside of [0, size) into the image following the wrap mode.
+func (b *Bitmap) wrapIndex(index, size int) int {
+	switch b.wrap {
+	case WrapClamp:
+		return int(math.Max(0, math.Min(float64(index), float64(size-1))))
+	case WrapMirror:
+		index = ((index % (2 * size)) + 2*size) % (2 * size)
+		if index >= size {
+			index = 2*size - 1 - index
+		}
+		return index
+	default:
+		return ((index % size) + size) % size
+	}
+}
@@ -0,0 +1,120 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/utils"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBitmapSample(t *testing.T) {
+	// A 2 x 2 image with red, green on the top row and blue, gray on the bottom row.
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.RGBA{0, 255, 0, 255})
+	img.Set(0, 1, color.RGBA{0, 0, 255, 255})
+	img.Set(1, 1, color.RGBA{128, 128, 128, 255})
+	path := filepath.Join(t.TempDir(), "test.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(file, img)
+	file.Close()
+
+	sample := func(bitmap Bitmap, u, v float64) [3]float64 {
+		return bitmap.Sample(&IntersectionInfo{U: u, V: v})
+	}
+	near := func(lhs, rhs [3]float64) bool {
+		return math.Abs(lhs[0]-rhs[0]) < 1e-3 && math.Abs(lhs[1]-rhs[1]) < 1e-3 && math.Abs(lhs[2]-rhs[2]) < 1e-3
+	}
+
+	nearest, err := NewBitmap(path, false, WrapRepeat, FilterNearest, [2]float64{1, 1}, [2]float64{0, 0}, 0)
+	if err != nil || !near(sample(nearest, 0.25, 0.75), [3]float64{1, 0, 0}) || !near(sample(nearest, 0.75, 0.25), [3]float64{0.2158, 0.2158, 0.2158}) {
+		t.Errorf("Bitmap.Sample() failed!")
+	}
+
+	bilinear, err := NewBitmap(path, true, WrapRepeat, FilterBilinear, [2]float64{1, 1}, [2]float64{0, 0}, 0)
+	gray := 128.0 / 255
+	if err != nil || !near(sample(bilinear, 0.5, 0.5), [3]float64{(1 + gray) / 4, (1 + gray) / 4, (1 + gray) / 4}) {
+		t.Errorf("Bitmap.Sample() failed!")
+	}
+
+	clamp, _ := NewBitmap(path, false, WrapClamp, FilterNearest, [2]float64{1, 1}, [2]float64{0, 0}, 0)
+	mirror, _ := NewBitmap(path, false, WrapMirror, FilterNearest, [2]float64{1, 1}, [2]float64{0, 0}, 0)
+	if !near(sample(nearest, -0.25, 0.75), [3]float64{0, 1, 0}) || !near(sample(clamp, -0.25, 0.75), [3]float64{1, 0, 0}) ||
+		!near(sample(mirror, -0.25, 0.75), [3]float64{1, 0, 0}) || !near(sample(mirror, -0.75, 0.75), [3]float64{0, 1, 0}) {
+		t.Errorf("Bitmap.Sample() failed!")
+	}
+
+	rotated, _ := NewBitmap(path, false, WrapRepeat, FilterNearest, [2]float64{1, 1}, [2]float64{0, 0}, 90)
+	if !near(sample(rotated, 0.75, 0.25), [3]float64{0, 1, 0}) {
+		t.Errorf("Bitmap.Sample() failed!")
+	}
+
+	if nearest.image != clamp.image || nearest.image == bilinear.image {
+		t.Errorf("NewBitmap() failed!")
+	}
+
+	flat := append([]byte("#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y 1 +X 2\n"), 128, 64, 0, 129, 0, 0, 0, 0)
+	hdr, err := readHDR(bytes.NewReader(flat))
+	if err != nil || !near(hdr.at(0, 0), [3]float64{1, 0.5, 0}) || !near(hdr.at(1, 0), [3]float64{0, 0, 0}) {
+		t.Errorf("readHDR() failed!")
+	}
+
+	encoded := append([]byte("#?RADIANCE\n\n-Y 1 +X 8\n"), 2, 2, 0, 8, 136, 128, 136, 128, 136, 64, 136, 130)
+	hdr, err = readHDR(bytes.NewReader(encoded))
+	if err != nil || !near(hdr.at(7, 0), [3]float64{2, 2, 1}) {
+		t.Errorf("readHDR() failed!")
+	}
+}
+
+func TestFilteredTextures(t *testing.T) {
+	checker := NewChecker(utils.Color{1, 1, 1}, utils.Color{0, 0, 0}, 5)
+	info := IntersectionInfo{U: 0.25, V: 0.25}
+	if checker.Sample(&info) != (utils.Color{1, 1, 1}) {
+		t.Errorf("Checker.Sample() failed!")
+	}
+
+	// A footprint much larger than the checks averages them.
+	info.DUdx, info.DVdy = 10, 10
+	if color := checker.Sample(&info); math.Abs(color[0]-0.5) > 1e-9 {
+		t.Errorf("Checker.Sample() failed!")
+	}
+
+	// A footprint covering half of a check along U, centered on its border.
+	info = IntersectionInfo{U: 1, V: 0.5, DUdx: 0.5, DVdy: 0.1}
+	if color := checker.Sample(&info); math.Abs(color[0]-0.5) > 1e-9 {
+		t.Errorf("Checker.Sample() failed!")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < 16; i++ {
+		img.Set(i%4, i/4, color.RGBA{uint8(255 * ((i%4 + i/4) % 2)), 0, 0, 255})
+	}
+	path := filepath.Join(t.TempDir(), "checks.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(file, img)
+	file.Close()
+
+	for _, filter := range []int{FilterTrilinear, FilterEWA} {
+		bitmap, err := NewBitmap(path, true, WrapRepeat, filter, [2]float64{1, 1}, [2]float64{0, 0}, 0)
+		if err != nil || len(bitmap.image.levels) != 3 {
+			t.Fatalf("NewBitmap() failed!")
+		}
+
+		sharp := IntersectionInfo{U: 0.125, V: 0.875, DUdx: 1e-3, DVdy: 1e-3}
+		blurred := IntersectionInfo{U: 0.125, V: 0.875, DUdx: 1, DVdy: 1}
+		if bitmap.Sample(&sharp)[0] > 1e-3 || math.Abs(bitmap.Sample(&blurred)[0]-0.5) > 1e-3 {
+			t.Errorf("Bitmap.Sample() failed!")
+		}
+	}
+}
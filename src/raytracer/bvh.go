@@ -0,0 +1,185 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"sort"
+)
+
+// Maximal number of primitives in a leaf of the bounding volume hierarchy.
+const bvhLeafSize = 4
+
+// bvhPrimitives provides access to the primitives a bounding volume hierarchy is built over.
+type bvhPrimitives interface {
+	Len() int
+	BoundingBox(i int) mathutils.BoundingBox
+	Intersect(i int, ray *Ray, info *IntersectionInfo) bool
+}
+
+// bvhNode defines a node of the bounding volume hierarchy.
+// Inner nodes have count zero and their children are at index+1 and child.
+type bvhNode struct {
+	bounds mathutils.BoundingBox // The box containing all primitives below the node.
+	child  int                   // The second child of an inner node or the first primitive of a leaf.
+	count  int                   // The number of primitives in a leaf.
+	axis   int                   // The axis along which an inner node is split.
+}
+
+// bvh defines a bounding volume hierarchy over the primitives with finite bounding boxes.
+// The primitives with infinite bounding boxes are always tested.
+type bvh struct {
+	nodes     []bvhNode // The nodes in depth first order.
+	indices   []int     // The primitive indices referenced by the leaves.
+	unbounded []int     // The primitives with infinite bounding boxes.
+}
+
+// newBVH builds and returns a bounding volume hierarchy over the given primitives.
+func newBVH(primitives bvhPrimitives) bvh {
+	var result bvh
+	boxes := make([]mathutils.BoundingBox, primitives.Len())
+	for i := range boxes {
+		boxes[i] = primitives.BoundingBox(i)
+		if boxes[i].IsInfinite() {
+			result.unbounded = append(result.unbounded, i)
+		} else if !boxes[i].IsEmpty() {
+			result.indices = append(result.indices, i)
+		}
+	}
+
+	if len(result.indices) > 0 {
+		result.build(boxes, 0, len(result.indices))
+	}
+
+	return result
+}
+
+// build builds the subtree over indices[first:last] and returns the index of its root.
+func (b *bvh) build(boxes []mathutils.BoundingBox, first, last int) int {
+	bounds := mathutils.EmptyBoundingBox()
+	centers := mathutils.EmptyBoundingBox()
+	for _, index := range b.indices[first:last] {
+		bounds = mathutils.BoundingBoxUnion(bounds, boxes[index])
+		centers.AddPoint(boxes[index].Center())
+	}
+
+	current := len(b.nodes)
+	b.nodes = append(b.nodes, bvhNode{bounds: bounds})
+	if last-first <= bvhLeafSize {
+		b.nodes[current].child = first
+		b.nodes[current].count = last - first
+		return current
+	}
+
+	// Split at the median along the axis where the centers are spread the most.
+	extent := mathutils.VectorSubstraction(centers.Max, centers.Min)
+	axis := 0
+	if extent.Y > extent.X && extent.Y >= extent.Z {
+		axis = 1
+	} else if extent.Z > extent.X && extent.Z > extent.Y {
+		axis = 2
+	}
+
+	indices := b.indices[first:last]
+	sort.Slice(indices, func(i, j int) bool {
+		return vectorComponent(boxes[indices[i]].Center(), axis) < vectorComponent(boxes[indices[j]].Center(), axis)
+	})
+
+	middle := (first + last) / 2
+	b.build(boxes, first, middle)
+	second := b.build(boxes, middle, last)
+	b.nodes[current].child = second
+	b.nodes[current].axis = axis
+	return current
+}
+
+// intersect finds the closest intersection of the ray with the primitives.
+func (b *bvh) intersect(primitives bvhPrimitives, ray *Ray, info *IntersectionInfo) bool {
+	found := false
+	closestDistance := math.Inf(1)
+	var current IntersectionInfo
+
+	test := func(index int) {
+		if primitives.Intersect(index, ray, &current) && current.Distance < closestDistance {
+			closestDistance = current.Distance
+			*info = current
+			found = true
+		}
+	}
+
+	for _, index := range b.unbounded {
+		test(index)
+	}
+
+	if len(b.nodes) == 0 {
+		return found
+	}
+
+	inverseDirection := mathutils.NewVector(1/ray.Direction.X, 1/ray.Direction.Y, 1/ray.Direction.Z)
+	stack := make([]int, 1, 64)
+	for len(stack) > 0 {
+		nodeIndex := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		node := &b.nodes[nodeIndex]
+
+		near, _, ok := node.bounds.IntersectRay(ray.Start, inverseDirection)
+		if !ok || near > closestDistance {
+			continue
+		}
+
+		if node.count > 0 {
+			for _, index := range b.indices[node.child : node.child+node.count] {
+				test(index)
+			}
+			continue
+		}
+
+		// Visit the child on the side the ray comes from first.
+		if vectorComponent(ray.Direction, node.axis) < 0 {
+			stack = append(stack, nodeIndex+1, node.child)
+		} else {
+			stack = append(stack, node.child, nodeIndex+1)
+		}
+	}
+
+	return found
+}
+
+// boundingBox returns the box containing all primitives of the hierarchy.
+func (b *bvh) boundingBox() mathutils.BoundingBox {
+	if len(b.unbounded) > 0 {
+		return mathutils.InfiniteBoundingBox()
+	}
+	if len(b.nodes) == 0 {
+		return mathutils.EmptyBoundingBox()
+	}
+
+	return b.nodes[0].bounds
+}
+
+// vectorComponent returns the X, Y or Z component of v for axis 0, 1 or 2.
+func vectorComponent(v mathutils.Vector, axis int) float64 {
+	switch axis {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+// axisVector returns the vector with value as its X, Y or Z component for axis 0, 1 or 2 and zero elsewhere.
+func axisVector(axis int, value float64) mathutils.Vector {
+	var result mathutils.Vector
+	switch axis {
+	case 0:
+		result.X = value
+	case 1:
+		result.Y = value
+	default:
+		result.Z = value
+	}
+
+	return result
+}
@@ -50,8 +50,33 @@ func NewParallelCamera(position mathutils.Vector, yaw, pitch, roll, fov, aspectR
 	return ParallelCamera{position, topLeft, topRight, bottomLeft}
 }
 
+// NewTransformedCamera creates and returns a new pinhole camera placed in the world by transform.
+// In its own space the camera is at the origin, looks along Z and has Y up.
+// The fov is the vertical field of view in degrees.
+func NewTransformedCamera(transform mathutils.Transform, fov, aspectRatio float64) ParallelCamera {
+	y2d := math.Tan(mathutils.ToRadians(fov / 2.0))
+	x2d := y2d * aspectRatio
+
+	position := mathutils.MultiplyPointTransform(mathutils.NewVector(0, 0, 0), transform)
+	topLeft := mathutils.MultiplyPointTransform(mathutils.NewVector(-x2d, y2d, 1), transform)
+	topRight := mathutils.MultiplyPointTransform(mathutils.NewVector(x2d, y2d, 1), transform)
+	bottomLeft := mathutils.MultiplyPointTransform(mathutils.NewVector(-x2d, -y2d, 1), transform)
+
+	return ParallelCamera{position, topLeft, topRight, bottomLeft}
+}
+
 // GetScreenRay return the screen ray for the given coordinates.
+// The ray differentials go through the next pixels along X and Y.
 func (c *ParallelCamera) GetScreenRay(x, y float64) Ray {
+	ray := NewRay(c.position, c.screenDirection(x, y))
+	ray.HasDifferentials = true
+	ray.XStart, ray.XDirection = c.position, c.screenDirection(x+1, y)
+	ray.YStart, ray.YDirection = c.position, c.screenDirection(x, y+1)
+	return ray
+}
+
+// screenDirection returns the normalized direction from the camera to the given screen coordinates.
+func (c *ParallelCamera) screenDirection(x, y float64) mathutils.Vector {
 	direction := c.topLeft
 	width := mathutils.VectorSubstraction(c.topRight, c.topLeft)
 	height := mathutils.VectorSubstraction(c.bottomLeft, c.topLeft)
@@ -63,5 +88,5 @@ func (c *ParallelCamera) GetScreenRay(x, y float64) Ray {
 	direction = mathutils.VectorSubstraction(direction, c.position)
 	direction.Normalize()
 
-	return Ray{c.position, direction}
+	return direction
 }
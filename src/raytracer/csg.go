@@ -0,0 +1,145 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"sort"
+)
+
+// CSG operations
+const (
+	Union = iota
+	Intersection
+	Difference
+)
+
+// CSG defines a constructive solid geometry combining two or more solids.
+// A Difference keeps the inside of the first solid which is not inside any of the others.
+type CSG struct {
+	operation uint8   // The boolean operation.
+	children  []Solid // The combined solids.
+}
+
+// NewCSG creates and returns a new constructive solid geometry of the given operation and children.
+func NewCSG(operation uint8, children []Solid) CSG {
+	return CSG{operation, children}
+}
+
+// csgEvent holds a hit of one of the children together with the child which produced it.
+type csgEvent struct {
+	info     IntersectionInfo
+	child    int
+	entering bool
+}
+
+// contains tells if a point is inside the combination given whether it is inside each child.
+func (c *CSG) contains(inside []bool) bool {
+	switch c.operation {
+	case Union:
+		for _, value := range inside {
+			if value {
+				return true
+			}
+		}
+		return false
+
+	case Intersection:
+		for _, value := range inside {
+			if !value {
+				return false
+			}
+		}
+		return true
+
+	default:
+		if !inside[0] {
+			return false
+		}
+		for _, value := range inside[1:] {
+			if value {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// IntersectAll implements the IntersectAll method of the Solid interface for CSG.
+// The hits of all children are walked along the ray and the ones where the combination is entered or left are kept.
+func (c *CSG) IntersectAll(ray *Ray) []IntersectionInfo {
+	var events []csgEvent
+	for i, child := range c.children {
+		for _, info := range child.IntersectAll(ray) {
+			events = append(events, csgEvent{info, i, mathutils.DotProduct(ray.Direction, info.Normal) < 0})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].info.Distance < events[j].info.Distance
+	})
+
+	var hits []IntersectionInfo
+	inside := make([]bool, len(c.children))
+	wasInside := false
+	for _, event := range events {
+		inside[event.child] = event.entering
+		isInside := c.contains(inside)
+		if isInside == wasInside {
+			continue
+		}
+
+		// Leaving a subtracted child enters the combination, so the normal has to be flipped.
+		info := event.info
+		if isInside != event.entering {
+			info.Normal.UnaryMinus()
+		}
+		hits = append(hits, info)
+		wasInside = isInside
+	}
+
+	return hits
+}
+
+// Intersect implements the intersect method of the Geometry interface for CSG.
+func (c *CSG) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	for _, hit := range c.IntersectAll(ray) {
+		if hit.Distance > 1e-6 {
+			*info = hit
+			return true
+		}
+	}
+
+	return false
+}
+
+// BoundingBox implements the Bounded interface for CSG.
+func (c *CSG) BoundingBox() mathutils.BoundingBox {
+	boxes := make([]mathutils.BoundingBox, len(c.children))
+	for i, child := range c.children {
+		boxes[i] = mathutils.InfiniteBoundingBox()
+		if bounded, ok := child.(Bounded); ok {
+			boxes[i] = bounded.BoundingBox()
+		}
+	}
+
+	switch c.operation {
+	case Union:
+		result := mathutils.EmptyBoundingBox()
+		for _, box := range boxes {
+			result = mathutils.BoundingBoxUnion(result, box)
+		}
+		return result
+
+	case Intersection:
+		result := boxes[0]
+		for _, box := range boxes[1:] {
+			result.Min = mathutils.NewVector(math.Max(result.Min.X, box.Min.X), math.Max(result.Min.Y, box.Min.Y), math.Max(result.Min.Z, box.Min.Z))
+			result.Max = mathutils.NewVector(math.Min(result.Max.X, box.Max.X), math.Min(result.Max.Y, box.Max.Y), math.Min(result.Max.Z, box.Max.Z))
+		}
+		return result
+
+	default:
+		return boxes[0]
+	}
+}
@@ -0,0 +1,83 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCSGIntersect(t *testing.T) {
+	cube := NewCube(mathutils.NewVector(0, 0, 0), 4)
+	sphere := NewSphere(mathutils.NewVector(0, 0, 0), 1)
+	bigSphere := NewSphere(mathutils.NewVector(0, 0, 0), 2.5)
+	var info IntersectionInfo
+
+	difference := NewCSG(Difference, []Solid{&cube, &sphere})
+	ray := NewRay(mathutils.NewVector(-10, 0, 0), mathutils.NewVector(1, 0, 0))
+	hits := difference.IntersectAll(&ray)
+	if len(hits) != 4 || math.Abs(hits[1].Distance-9) > 1e-6 || !compareVectors(hits[1].Normal, mathutils.NewVector(1, 0, 0)) {
+		t.Errorf("CSG.IntersectAll() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 0, 0), mathutils.NewVector(1, 0, 0))
+	if !difference.Intersect(&ray, &info) || math.Abs(info.Distance-1) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(-1, 0, 0)) {
+		t.Errorf("CSG.Intersect() failed!")
+	}
+
+	intersection := NewCSG(Intersection, []Solid{&cube, &bigSphere})
+	ray = NewRay(mathutils.NewVector(-10, 0, 0), mathutils.NewVector(1, 0, 0))
+	if !intersection.Intersect(&ray, &info) || math.Abs(info.Distance-8) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(-1, 0, 0)) {
+		t.Errorf("CSG.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(-10, -10, 0), mathutils.NewVector(1, 1, 0))
+	ray.Direction.Normalize()
+	if !intersection.Intersect(&ray, &info) || math.Abs(info.Distance-(10*math.Sqrt2-2.5)) > 1e-6 {
+		t.Errorf("CSG.Intersect() failed!")
+	}
+
+	union := NewCSG(Union, []Solid{&sphere, &bigSphere})
+	ray = NewRay(mathutils.NewVector(-10, 0, 0), mathutils.NewVector(1, 0, 0))
+	if len(union.IntersectAll(&ray)) != 2 {
+		t.Errorf("CSG.IntersectAll() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(-10, 3, 0), mathutils.NewVector(1, 0, 0))
+	if union.Intersect(&ray, &info) {
+		t.Errorf("CSG.Intersect() failed!")
+	}
+
+	// A ray crossing the cube from edge to edge enters and leaves it once.
+	ray = NewRay(mathutils.NewVector(-10, -10, 0), mathutils.NewVector(1, 1, 0))
+	ray.Direction.Normalize()
+	if hits := cube.IntersectAll(&ray); len(hits) != 2 || math.Abs(hits[0].Distance-8*math.Sqrt2) > 1e-6 || math.Abs(hits[1].Distance-12*math.Sqrt2) > 1e-6 {
+		t.Errorf("Cube.IntersectAll() failed!")
+	}
+	hits = difference.IntersectAll(&ray)
+	if len(hits) != 4 || math.Abs(hits[0].Distance-8*math.Sqrt2) > 1e-6 || math.Abs(hits[3].Distance-12*math.Sqrt2) > 1e-6 {
+		t.Errorf("CSG.IntersectAll() failed!")
+	}
+
+	// A ray only touching an edge misses the cube.
+	ray = NewRay(mathutils.NewVector(-10, -6, 0), mathutils.NewVector(1, 1, 0))
+	ray.Direction.Normalize()
+	if len(cube.IntersectAll(&ray)) != 0 {
+		t.Errorf("Cube.IntersectAll() failed!")
+	}
+}
+
+func TestReadCSG(t *testing.T) {
+	// A pointed cone needs no top cap, an open cylinder does not enclose a volume.
+	reader := SceneReader{fileContent: strings.Fields(`Difference { geometry Cube { center 0 0 0 edge 2 }
+		geometry Cone { center 0 0 0 axis 0 1 0 radius 1 height 2 bottomCap true topCap false } } end`)}
+	if _, err := reader.readCSG("Difference"); err != nil || reader.fileContent[reader.position] != "end" {
+		t.Errorf("SceneReader.readCSG() failed!")
+	}
+
+	reader = SceneReader{fileContent: strings.Fields(`Difference { geometry Cube { center 0 0 0 edge 2 }
+		geometry Cylinder { center 0 0 0 axis 0 1 0 radius 1 height 2 caps false } } end`)}
+	if _, err := reader.readCSG("Difference"); err == nil {
+		t.Errorf("SceneReader.readCSG() failed!")
+	}
+}
@@ -0,0 +1,433 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Curve bases
+const (
+	CurveBezier = iota
+	CurveBSpline
+)
+
+// Curve shapes
+const (
+	CurveRibbon   = iota // A flat ribbon always facing the ray.
+	CurveCylinder        // A ribbon shaded with the normals of a cylinder around the curve.
+)
+
+// CurveStrand holds the control points of a strand and the width of the curve at every control point.
+type CurveStrand struct {
+	Points []mathutils.Vector
+	Widths []float64
+}
+
+// CurveData holds the strands of a curve file.
+// Bezier strands have 3n + 1 control points for n cubic segments,
+// B-spline strands have n + 3 control points for n uniform cubic spans.
+type CurveData struct {
+	Basis   int
+	Strands []CurveStrand
+}
+
+// Validate checks that every strand has a width per control point and a valid number of control points for the basis.
+func (c *CurveData) Validate() error {
+	for i, strand := range c.Strands {
+		if len(strand.Widths) != len(strand.Points) {
+			return fmt.Errorf("Strand %d has %d widths for %d control points", i, len(strand.Widths), len(strand.Points))
+		}
+		switch c.Basis {
+		case CurveBezier:
+			if len(strand.Points) < 4 || (len(strand.Points)-1)%3 != 0 {
+				return fmt.Errorf("Bezier strand %d has %d control points instead of 3n + 1", i, len(strand.Points))
+			}
+		case CurveBSpline:
+			if len(strand.Points) < 4 {
+				return fmt.Errorf("B-spline strand %d has only %d control points", i, len(strand.Points))
+			}
+		default:
+			return fmt.Errorf("Unknown curve basis %d", c.Basis)
+		}
+		for _, width := range strand.Widths {
+			if width < 0 {
+				return fmt.Errorf("Strand %d has a negative width", i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// curveSegment defines a cubic Bezier segment of a strand.
+type curveSegment struct {
+	points [4]mathutils.Vector // The Bezier control points.
+	width  [2]float64          // The width at the start and at the end of the segment.
+	u      [2]float64          // The curve parameter along the strand at the start and at the end of the segment.
+}
+
+// Curves defines a geometry made of thin curves like hair, fur or grass.
+// U runs from 0 at the root to 1 at the tip of each strand and V across its width.
+type Curves struct {
+	segments curveList // The Bezier segments of all strands.
+	bvh      bvh       // The acceleration structure over the segments.
+}
+
+// curveList implements bvhPrimitives for the segments of curves.
+type curveList struct {
+	segments []curveSegment
+	shape    int
+}
+
+func (c *curveList) Len() int {
+	return len(c.segments)
+}
+
+func (c *curveList) BoundingBox(i int) mathutils.BoundingBox {
+	segment := &c.segments[i]
+	box := mathutils.EmptyBoundingBox()
+	for _, point := range segment.points {
+		box.AddPoint(point)
+	}
+
+	// The curve stays inside the convex hull of the control points.
+	radius := math.Max(segment.width[0], segment.width[1]) / 2
+	offset := mathutils.NewVector(radius, radius, radius)
+	return mathutils.NewBoundingBox(mathutils.VectorSubstraction(box.Min, offset), mathutils.VectorAddition(box.Max, offset))
+}
+
+func (c *curveList) Intersect(i int, ray *Ray, info *IntersectionInfo) bool {
+	segment := &c.segments[i]
+
+	// Move the control points to a space where the ray starts at the origin and runs along Z.
+	axisX, axisY := mathutils.OrthonormalBasis(ray.Direction)
+	var projected [4]mathutils.Vector
+	for j, point := range segment.points {
+		offset := mathutils.VectorSubstraction(point, ray.Start)
+		projected[j] = mathutils.NewVector(mathutils.DotProduct(offset, axisX), mathutils.DotProduct(offset, axisY), mathutils.DotProduct(offset, ray.Direction))
+	}
+
+	// Subdivide until the segments are flat enough to be tested as straight lines,
+	// from the second differences of the control points.
+	curvature := 0.0
+	for j := 0; j < 2; j++ {
+		difference := mathutils.VectorAddition(projected[j], projected[j+2])
+		difference = mathutils.VectorSubstraction(difference, mathutils.VectorMultiply(projected[j+1], 2))
+		curvature = math.Max(curvature, difference.Length())
+	}
+	tolerance := math.Max(segment.width[0], segment.width[1]) / 20
+	depth := 0
+	if curvature > 0 && tolerance > 0 {
+		depth = int(math.Ceil(math.Log2(math.Sqrt2*6*curvature/(8*tolerance)) / 2))
+		depth = int(math.Max(0, math.Min(float64(depth), 10)))
+	}
+
+	hit := curveHit{distance: math.Inf(1)}
+	if !segment.intersect(projected, 0, 1, depth, &hit) {
+		return false
+	}
+
+	info.Distance = hit.distance
+	info.Position = mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, hit.distance))
+
+	point, tangent := evaluateBezier(segment.points, hit.t)
+	if tangent.LengthSqr() == 0 {
+		tangent = mathutils.VectorSubstraction(segment.points[3], segment.points[0])
+	}
+	side := mathutils.CrossProduct(ray.Direction, tangent)
+	if side.LengthSqr() == 0 {
+		side, _ = mathutils.OrthonormalBasis(ray.Direction)
+	}
+	side.Normalize()
+	facing := mathutils.CrossProduct(side, tangent)
+	facing.Normalize()
+	if mathutils.DotProduct(facing, ray.Direction) > 0 {
+		facing.UnaryMinus()
+	}
+
+	// The position across the width decides V, and the normal of cylinders turns around the tangent with it.
+	width := segment.width[0] + (segment.width[1]-segment.width[0])*hit.t
+	across := 0.0
+	if width > 0 {
+		across = 2 * mathutils.DotProduct(mathutils.VectorSubstraction(info.Position, point), side) / width
+		across = math.Max(-1, math.Min(across, 1))
+	}
+	info.Normal = facing
+	if c.shape == CurveCylinder {
+		info.Normal = mathutils.VectorAddition(mathutils.VectorMultiply(facing, math.Sqrt(1-across*across)), mathutils.VectorMultiply(side, across))
+		info.Normal.Normalize()
+	}
+
+	info.U = segment.u[0] + (segment.u[1]-segment.u[0])*hit.t
+	info.V = (across + 1) / 2
+	info.DPdu = mathutils.VectorMultiply(tangent, 1/(segment.u[1]-segment.u[0]))
+	info.DPdv = mathutils.VectorMultiply(side, width)
+
+	return true
+}
+
+// curveHit holds the closest hit found while subdividing a curve segment.
+type curveHit struct {
+	distance float64 // The distance along the ray.
+	t        float64 // The parameter along the segment.
+}
+
+// intersect tests the ray against the part of the segment between t0 and t1, given by its control points
+// in ray space, and updates hit when it finds a closer intersection.
+func (c *curveSegment) intersect(points [4]mathutils.Vector, t0, t1 float64, depth int, hit *curveHit) bool {
+	// Skip parts whose bounds, grown by the width, miss the ray.
+	radius := math.Max(c.width[0]+(c.width[1]-c.width[0])*t0, c.width[0]+(c.width[1]-c.width[0])*t1) / 2
+	box := mathutils.EmptyBoundingBox()
+	for _, point := range points {
+		box.AddPoint(point)
+	}
+	if box.Min.X > radius || box.Max.X < -radius || box.Min.Y > radius || box.Max.Y < -radius ||
+		box.Max.Z+radius < 1e-6 || box.Min.Z-radius > hit.distance {
+		return false
+	}
+
+	if depth > 0 {
+		first, second := splitBezier(points)
+		middle := (t0 + t1) / 2
+		foundFirst := c.intersect(first, t0, middle, depth-1, hit)
+		foundSecond := c.intersect(second, middle, t1, depth-1, hit)
+		return foundFirst || foundSecond
+	}
+
+	// The ray has to pass between the planes perpendicular to the curve at both ends.
+	if (points[1].X-points[0].X)*-points[0].X+(points[1].Y-points[0].Y)*-points[0].Y < 0 ||
+		(points[2].X-points[3].X)*-points[3].X+(points[2].Y-points[3].Y)*-points[3].Y < 0 {
+		return false
+	}
+
+	// Find the closest point of the straight part to the ray and compare its distance with the width.
+	chordX, chordY := points[3].X-points[0].X, points[3].Y-points[0].Y
+	length := chordX*chordX + chordY*chordY
+	if length == 0 {
+		return false
+	}
+	w := math.Max(0, math.Min((-points[0].X*chordX-points[0].Y*chordY)/length, 1))
+	t := t0 + (t1-t0)*w
+	width := c.width[0] + (c.width[1]-c.width[0])*t
+
+	// Hits closer than the width are ignored so that rays leaving a strand, like shadow rays, do not hit it again.
+	point, _ := evaluateBezier(points, w)
+	if point.X*point.X+point.Y*point.Y > width*width/4 || point.Z < width || point.Z >= hit.distance {
+		return false
+	}
+
+	hit.distance = point.Z
+	hit.t = t
+	return true
+}
+
+// evaluateBezier returns the point and the derivative of the cubic Bezier curve at t.
+func evaluateBezier(points [4]mathutils.Vector, t float64) (point, derivative mathutils.Vector) {
+	values, derivatives := bernstein(t)
+	for i, control := range points {
+		point.Add(mathutils.VectorMultiply(control, values[i]))
+		derivative.Add(mathutils.VectorMultiply(control, derivatives[i]))
+	}
+
+	return
+}
+
+// splitBezier splits the cubic Bezier curve in its middle with the de Casteljau algorithm.
+func splitBezier(points [4]mathutils.Vector) (first, second [4]mathutils.Vector) {
+	middle := func(a, b mathutils.Vector) mathutils.Vector {
+		return mathutils.VectorMultiply(mathutils.VectorAddition(a, b), 0.5)
+	}
+
+	p01, p12, p23 := middle(points[0], points[1]), middle(points[1], points[2]), middle(points[2], points[3])
+	p012, p123 := middle(p01, p12), middle(p12, p23)
+	center := middle(p012, p123)
+
+	return [4]mathutils.Vector{points[0], p01, p012, center}, [4]mathutils.Vector{center, p123, p23, points[3]}
+}
+
+// NewCurves creates and returns curves with the given shape from validated curve data.
+// B-spline spans are converted to the equivalent Bezier segments.
+func NewCurves(data CurveData, shape int) Curves {
+	segments := curveList{shape: shape}
+	for _, strand := range data.Strands {
+		points, widths := strand.Points, strand.Widths
+		if data.Basis == CurveBSpline {
+			points, widths = bsplineToBezier(points, widths)
+		}
+
+		count := (len(points) - 1) / 3
+		for i := 0; i < count; i++ {
+			var segment curveSegment
+			copy(segment.points[:], points[3*i:3*i+4])
+			segment.width = [2]float64{widths[3*i], widths[3*i+3]}
+			segment.u = [2]float64{float64(i) / float64(count), float64(i+1) / float64(count)}
+			segments.segments = append(segments.segments, segment)
+		}
+	}
+
+	return Curves{segments, newBVH(&segments)}
+}
+
+// bsplineToBezier converts the control points of a uniform cubic B-spline to the 3n + 1 control points
+// of the equivalent Bezier segments. The widths are converted like the points, keeping every other one.
+func bsplineToBezier(points []mathutils.Vector, widths []float64) ([]mathutils.Vector, []float64) {
+	combine := func(a, b, c mathutils.Vector, wa, wb, wc float64) mathutils.Vector {
+		result := mathutils.VectorMultiply(a, wa)
+		result.Add(mathutils.VectorMultiply(b, wb))
+		result.Add(mathutils.VectorMultiply(c, wc))
+		return result
+	}
+
+	var resultPoints []mathutils.Vector
+	var resultWidths []float64
+	for i := 0; i+3 < len(points); i++ {
+		p0, p1, p2, p3 := points[i], points[i+1], points[i+2], points[i+3]
+		w0, w1, w2, w3 := widths[i], widths[i+1], widths[i+2], widths[i+3]
+		if i == 0 {
+			resultPoints = append(resultPoints, combine(p0, p1, p2, 1.0/6, 4.0/6, 1.0/6))
+			resultWidths = append(resultWidths, (w0+4*w1+w2)/6)
+		}
+		resultPoints = append(resultPoints,
+			combine(p1, p2, p2, 2.0/3, 1.0/3, 0),
+			combine(p1, p2, p2, 1.0/3, 2.0/3, 0),
+			combine(p1, p2, p3, 1.0/6, 4.0/6, 1.0/6))
+		resultWidths = append(resultWidths, (2*w1+w2)/3, (w1+2*w2)/3, (w1+4*w2+w3)/6)
+	}
+
+	return resultPoints, resultWidths
+}
+
+// Intersect implements the intersect method of the Geometry interface for Curves.
+func (c *Curves) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	return c.bvh.intersect(&c.segments, ray, info)
+}
+
+// BoundingBox implements the Bounded interface for Curves.
+func (c *Curves) BoundingBox() mathutils.BoundingBox {
+	return c.bvh.boundingBox()
+}
+
+// curveMagic starts binary curve files.
+const curveMagic = "CRV1"
+
+// ReadCurves reads strands from a curve file, either text or binary.
+//
+// Text files start with the basis, bezier or bspline, followed by the strands. Every strand is
+// the number of its control points followed by, for every control point, x y z and width.
+//
+// Binary files start with CRV1 followed by little endian values: the basis as a uint32,
+// 0 for Bezier and 1 for B-spline, the number of strands as a uint32 and, for every strand,
+// the number of its control points as a uint32 followed by x y z and width as float32.
+func ReadCurves(reader io.Reader) (data CurveData, err error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return
+	}
+
+	if bytes.HasPrefix(content, []byte(curveMagic)) {
+		err = readBinaryCurves(bytes.NewReader(content[len(curveMagic):]), &data)
+	} else {
+		err = readTextCurves(content, &data)
+	}
+	if err != nil {
+		return
+	}
+
+	if len(data.Strands) == 0 {
+		err = fmt.Errorf("Curve file has no strands")
+		return
+	}
+
+	err = data.Validate()
+	return
+}
+
+// readTextCurves reads the strands of a text curve file.
+func readTextCurves(content []byte, data *CurveData) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Split(bufio.ScanWords)
+	if !scanner.Scan() {
+		return fmt.Errorf("Curve file is empty")
+	}
+	switch scanner.Text() {
+	case "bezier":
+		data.Basis = CurveBezier
+	case "bspline":
+		data.Basis = CurveBSpline
+	default:
+		return fmt.Errorf("Unknown curve basis %s", scanner.Text())
+	}
+
+	next := func() (float64, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.ErrUnexpectedEOF
+		}
+		return strconv.ParseFloat(scanner.Text(), 64)
+	}
+
+	for scanner.Scan() {
+		count, err := strconv.Atoi(scanner.Text())
+		if err != nil || count < 0 {
+			return fmt.Errorf("Strand %d: incorrect control point count %s", len(data.Strands), scanner.Text())
+		}
+
+		var strand CurveStrand
+		for i := 0; i < count; i++ {
+			var values [4]float64
+			for k := range values {
+				values[k], err = next()
+				if err != nil {
+					return fmt.Errorf("Strand %d: %v", len(data.Strands), err)
+				}
+			}
+			strand.Points = append(strand.Points, mathutils.NewVector(values[0], values[1], values[2]))
+			strand.Widths = append(strand.Widths, values[3])
+		}
+		data.Strands = append(data.Strands, strand)
+	}
+
+	return scanner.Err()
+}
+
+// readBinaryCurves reads the strands of a binary curve file after the magic.
+func readBinaryCurves(reader io.Reader, data *CurveData) error {
+	var header [2]uint32
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("Curve header: %v", err)
+	}
+	data.Basis = int(header[0])
+
+	for i := uint32(0); i < header[1]; i++ {
+		var count uint32
+		if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+			return fmt.Errorf("Strand %d: %v", i, err)
+		}
+		if count > 1<<24 {
+			return fmt.Errorf("Strand %d: incorrect control point count %d", i, count)
+		}
+
+		values := make([][4]float32, count)
+		if err := binary.Read(reader, binary.LittleEndian, values); err != nil {
+			return fmt.Errorf("Strand %d: %v", i, err)
+		}
+
+		var strand CurveStrand
+		for _, value := range values {
+			strand.Points = append(strand.Points, mathutils.NewVector(float64(value[0]), float64(value[1]), float64(value[2])))
+			strand.Widths = append(strand.Widths, float64(value[3]))
+		}
+		data.Strands = append(data.Strands, strand)
+	}
+
+	return nil
+}
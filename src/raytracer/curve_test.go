@@ -0,0 +1,59 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCurvesIntersect(t *testing.T) {
+	data, err := ReadCurves(strings.NewReader("bezier\n4\n0 0 0 0.2\n0 1 0 0.2\n0 2 0 0.2\n0 3 0 0.2\n"))
+	if err != nil || len(data.Strands) != 1 || len(data.Strands[0].Points) != 4 {
+		t.Fatalf("ReadCurves() failed!")
+	}
+
+	ribbon := NewCurves(data, CurveRibbon)
+	var info IntersectionInfo
+	ray := NewRay(mathutils.NewVector(0, 1.5, -5), mathutils.NewVector(0, 0, 1))
+	if !ribbon.Intersect(&ray, &info) || math.Abs(info.Distance-5) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 0, -1)) ||
+		math.Abs(info.U-0.5) > 1e-6 || math.Abs(info.V-0.5) > 1e-6 || !compareVectors(info.DPdu, mathutils.NewVector(0, 3, 0)) {
+		t.Errorf("Curves.Intersect() failed!")
+	}
+
+	cylinder := NewCurves(data, CurveCylinder)
+	ray = NewRay(mathutils.NewVector(0.05, 1.5, -5), mathutils.NewVector(0, 0, 1))
+	if !cylinder.Intersect(&ray, &info) || !compareVectors(info.Normal, mathutils.NewVector(0.5, 0, -math.Sqrt(0.75))) {
+		t.Errorf("Curves.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0.15, 1.5, -5), mathutils.NewVector(0, 0, 1))
+	if cylinder.Intersect(&ray, &info) {
+		t.Errorf("Curves.Intersect() failed!")
+	}
+
+	// The B-spline over four evenly spaced points only spans the middle part.
+	var buffer bytes.Buffer
+	buffer.WriteString("CRV1")
+	binary.Write(&buffer, binary.LittleEndian, [3]uint32{CurveBSpline, 1, 4})
+	binary.Write(&buffer, binary.LittleEndian, [16]float32{0, 0, 0, 0.2, 0, 1, 0, 0.2, 0, 2, 0, 0.2, 0, 3, 0, 0.2})
+	bspline, err := ReadCurves(&buffer)
+	if err != nil || bspline.Basis != CurveBSpline {
+		t.Fatalf("ReadCurves() failed!")
+	}
+	curves := NewCurves(bspline, CurveRibbon)
+	ray = NewRay(mathutils.NewVector(0, 1.5, -5), mathutils.NewVector(0, 0, 1))
+	if !curves.Intersect(&ray, &info) || math.Abs(info.U-0.5) > 1e-6 {
+		t.Errorf("Curves.Intersect() failed!")
+	}
+	ray = NewRay(mathutils.NewVector(0, 0.5, -5), mathutils.NewVector(0, 0, 1))
+	if curves.Intersect(&ray, &info) {
+		t.Errorf("Curves.Intersect() failed!")
+	}
+
+	if _, err := ReadCurves(strings.NewReader("bezier\n3\n0 0 0 1\n0 1 0 1\n0 2 0 1\n")); err == nil {
+		t.Errorf("ReadCurves() failed!")
+	}
+}
@@ -0,0 +1,260 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// densityGridMagic starts the binary density grid files.
+const densityGridMagic = "VOL1"
+
+// DensityGrid defines densities on a regular grid of voxels over the unit cube, interpolated between the voxel centers.
+type DensityGrid struct {
+	width, height, depth int
+	values               []float64 // The densities with X changing fastest, then Y, then Z.
+	maximum              float64   // The largest density.
+}
+
+// NewDensityGrid creates and returns a new density grid from the values, with X changing fastest, then Y, then Z.
+// The values must not be negative.
+func NewDensityGrid(width, height, depth int, values []float64) (DensityGrid, error) {
+	if width < 1 || height < 1 || depth < 1 || len(values) != width*height*depth {
+		return DensityGrid{}, fmt.Errorf("A density grid of %dx%dx%d voxels needs %d values", width, height, depth, width*height*depth)
+	}
+
+	maximum := 0.0
+	for _, value := range values {
+		if value < 0 || math.IsNaN(value) {
+			return DensityGrid{}, fmt.Errorf("The density grid has a negative density")
+		}
+		maximum = math.Max(maximum, value)
+	}
+
+	return DensityGrid{width, height, depth, values, maximum}, nil
+}
+
+// ReadDensityGrid reads a binary density grid: VOL1 followed by little endian values, the width, height
+// and depth as uint32 and the densities as float32, with X changing fastest, then Y, then Z.
+func ReadDensityGrid(reader io.Reader) (DensityGrid, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return DensityGrid{}, err
+	}
+	if !bytes.HasPrefix(content, []byte(densityGridMagic)) {
+		return DensityGrid{}, fmt.Errorf("Not a density grid file")
+	}
+
+	data := bytes.NewReader(content[len(densityGridMagic):])
+	var size [3]uint32
+	if err := binary.Read(data, binary.LittleEndian, &size); err != nil {
+		return DensityGrid{}, fmt.Errorf("Density grid header: %v", err)
+	}
+	count := uint64(size[0]) * uint64(size[1]) * uint64(size[2])
+	if count > 1<<28 || uint64(data.Len()) < 4*count {
+		return DensityGrid{}, fmt.Errorf("Incorrect density grid size %dx%dx%d", size[0], size[1], size[2])
+	}
+
+	densities := make([]float32, count)
+	if err := binary.Read(data, binary.LittleEndian, densities); err != nil {
+		return DensityGrid{}, fmt.Errorf("Density grid values: %v", err)
+	}
+	values := make([]float64, count)
+	for i, density := range densities {
+		values[i] = float64(density)
+	}
+
+	return NewDensityGrid(int(size[0]), int(size[1]), int(size[2]), values)
+}
+
+// NewNoiseDensityGrid creates and returns a grid with the given number of voxels along each axis filled with a cloud:
+// a ball fading out towards the faces of the unit cube, broken up by FBm noise of the scale and octaves.
+func NewNoiseDensityGrid(resolution int, scale, octaves float64) DensityGrid {
+	resolution = int(math.Max(1, float64(resolution)))
+	values := make([]float64, resolution*resolution*resolution)
+	for z := 0; z < resolution; z++ {
+		for y := 0; y < resolution; y++ {
+			for x := 0; x < resolution; x++ {
+				p := mathutils.NewVector(float64(x)+0.5, float64(y)+0.5, float64(z)+0.5)
+				p.Multiply(1 / float64(resolution))
+				offset := mathutils.VectorSubstraction(p, mathutils.NewVector(0.5, 0.5, 0.5))
+				falloff := 1 - 2*offset.Length()
+				noise := mathutils.FBm(mathutils.VectorMultiply(p, scale), octaves)
+				values[(z*resolution+y)*resolution+x] = math.Max(0, math.Min(2*falloff+noise-0.3, 1))
+			}
+		}
+	}
+
+	grid, _ := NewDensityGrid(resolution, resolution, resolution, values)
+	return grid
+}
+
+// at returns the density at the point of the unit cube, zero outside of it.
+func (g *DensityGrid) at(p mathutils.Vector) float64 {
+	if p.X < 0 || p.X > 1 || p.Y < 0 || p.Y > 1 || p.Z < 0 || p.Z > 1 {
+		return 0
+	}
+
+	// The voxel values are at their centers, the closest voxels are used up to the faces.
+	coordinates := func(t float64, size int) (int, int, float64) {
+		t = math.Max(0, math.Min(t*float64(size)-0.5, float64(size-1)))
+		low := int(t)
+		high := int(math.Min(float64(low+1), float64(size-1)))
+		return low, high, t - float64(low)
+	}
+	x0, x1, fx := coordinates(p.X, g.width)
+	y0, y1, fy := coordinates(p.Y, g.height)
+	z0, z1, fz := coordinates(p.Z, g.depth)
+	value := func(x, y, z int) float64 {
+		return g.values[(z*g.height+y)*g.width+x]
+	}
+
+	front := lerpFloat(lerpFloat(value(x0, y0, z0), value(x1, y0, z0), fx), lerpFloat(value(x0, y1, z0), value(x1, y1, z0), fx), fy)
+	back := lerpFloat(lerpFloat(value(x0, y0, z1), value(x1, y0, z1), fx), lerpFloat(value(x0, y1, z1), value(x1, y1, z1), fx), fy)
+	return lerpFloat(front, back, fz)
+}
+
+// lerpFloat interpolates linearly between a and b.
+func lerpFloat(a, b, t float64) float64 {
+	return a + t*(b-a)
+}
+
+// GridMedium defines a medium with the densities of a grid stretched over a box. It is rendered by delta tracking
+// against the largest density and its shadows by ratio tracking. The medium may glow, like the flames of a fire.
+type GridMedium struct {
+	grid       *DensityGrid
+	bounds     mathutils.BoundingBox // The box the grid is stretched over.
+	density    float64               // The extinction per unit of length for a grid density of one.
+	albedo     utils.Color           // The share of the extinction which is scattering.
+	emission   utils.Color           // The light emitted per unit of length for a grid density of one.
+	anisotropy float64               // The g of the Henyey-Greenstein phase function.
+	samples    int                   // The number of tracked paths along a ray.
+}
+
+// NewGridMedium creates and returns a new medium from the grid stretched over the bounds.
+func NewGridMedium(grid DensityGrid, bounds mathutils.BoundingBox, density float64, albedo, emission utils.Color,
+	anisotropy float64, samples int) GridMedium {
+	if samples < 1 {
+		samples = 1
+	}
+	anisotropy = math.Max(-0.99, math.Min(anisotropy, 0.99))
+
+	return GridMedium{&grid, bounds, density, albedo, emission, anisotropy, samples}
+}
+
+// SetBounds sets the box the grid is stretched over.
+func (g *GridMedium) SetBounds(bounds mathutils.BoundingBox) {
+	g.bounds = bounds
+}
+
+// densityAt returns the grid density at the point.
+func (g *GridMedium) densityAt(p mathutils.Vector) float64 {
+	size := mathutils.VectorSubstraction(g.bounds.Max, g.bounds.Min)
+	local := mathutils.VectorSubstraction(p, g.bounds.Min)
+	if size.X <= 0 || size.Y <= 0 || size.Z <= 0 {
+		return 0
+	}
+
+	return g.grid.at(mathutils.NewVector(local.X/size.X, local.Y/size.Y, local.Z/size.Z))
+}
+
+// clip returns the part of the distances along the ray inside the bounds, outside of which there is no medium.
+func (g *GridMedium) clip(start, direction mathutils.Vector, near, far float64) (float64, float64) {
+	inverseDirection := mathutils.NewVector(1/direction.X, 1/direction.Y, 1/direction.Z)
+	enter, leave, ok := g.bounds.IntersectRay(start, inverseDirection)
+	if !ok {
+		return 0, 0
+	}
+
+	return math.Max(near, enter), math.Min(far, leave)
+}
+
+// integrate implements participatingMedium for GridMedium. Every path steps through the medium by tentative
+// collisions for the largest extinction, of which the share given by the actual extinction are real.
+// The emission is collected at every tentative collision and the light of the scene is scattered at the real one.
+func (g *GridMedium) integrate(ray *Ray, near, far float64, behind utils.Color, scene *Scene) utils.Color {
+	majorant := g.density * g.grid.maximum
+	near, far = g.clip(ray.Start, ray.Direction, near, far)
+	if majorant <= 0 || far <= near {
+		return behind
+	}
+
+	var result utils.Color
+	for i := 0; i < g.samples; i++ {
+		random := newRandomSequence(ray.Start, ray.Direction, i)
+		for t := near; ; {
+			t -= math.Log(1-random.next()) / majorant
+			if t >= far {
+				result = utils.ColorAddition(result, behind)
+				break
+			}
+
+			point := mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, t))
+			density := g.densityAt(point)
+			result = utils.ColorAddition(result, utils.MultiplyColorFloat(g.emission, density/majorant))
+			if random.next()*majorant < density*g.density {
+				scattered := utils.ColorMultiplication(inScattered(ray, point, g.anisotropy, scene), g.albedo)
+				result = utils.ColorAddition(result, scattered)
+				break
+			}
+		}
+	}
+
+	return utils.MultiplyColorFloat(result, 1/float64(g.samples))
+}
+
+// segmentTransmittance implements participatingMedium for GridMedium by ratio tracking: every tentative collision
+// for twice the largest extinction lets through the share of the light which the actual extinction does not stop.
+// The doubled majorant and the average over the tracked paths keep the estimate from jumping between zero and one.
+func (g *GridMedium) segmentTransmittance(start, direction mathutils.Vector, length float64) utils.Color {
+	majorant := 2 * g.density * g.grid.maximum
+	near, far := g.clip(start, direction, 0, length)
+	if majorant <= 0 || far <= near {
+		return utils.Color{1, 1, 1}
+	}
+
+	total := 0.0
+	for i := 0; i < g.samples; i++ {
+		result := 1.0
+		random := newRandomSequence(start, direction, i)
+		for t := near - math.Log(1-random.next())/majorant; t < far && result > 1e-4; t -= math.Log(1-random.next()) / majorant {
+			point := mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, t))
+			result *= 1 - g.densityAt(point)*g.density/majorant
+		}
+		total += result
+	}
+	total /= float64(g.samples)
+
+	return utils.Color{total, total, total}
+}
+
+// randomSequence generates pseudo random numbers in [0, 1) by xorshift, seeded by a ray and an index,
+// so the tracking is the same every time the ray is traced.
+type randomSequence uint64
+
+// newRandomSequence creates and returns a sequence for the ray and the index.
+func newRandomSequence(start, direction mathutils.Vector, index int) randomSequence {
+	u, v := hashPosition(mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, 1.618)))
+	seed := uint64(u*(1<<24))<<24 ^ uint64(v*(1<<24)) ^ uint64(index+1)*0x9E3779B97F4A7C15
+	if seed == 0 {
+		seed = 1
+	}
+
+	return randomSequence(seed)
+}
+
+// next returns the next number of the sequence.
+func (r *randomSequence) next() float64 {
+	x := uint64(*r)
+	x ^= x << 13
+	x ^= x >> 7
+	x ^= x << 17
+	*r = randomSequence(x)
+
+	return float64(x>>11) / (1 << 53)
+}
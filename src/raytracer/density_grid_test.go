@@ -0,0 +1,68 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDensityGrid(t *testing.T) {
+	if _, err := NewDensityGrid(2, 2, 2, []float64{1}); err == nil {
+		t.Errorf("NewDensityGrid() failed!")
+	}
+
+	// The densities are interpolated between the voxel centers and kept beyond the outer centers.
+	var content bytes.Buffer
+	content.WriteString("VOL1")
+	binary.Write(&content, binary.LittleEndian, [3]uint32{2, 1, 1})
+	binary.Write(&content, binary.LittleEndian, []float32{0, 2})
+	grid, err := ReadDensityGrid(&content)
+	if err != nil || grid.maximum != 2 {
+		t.Fatalf("ReadDensityGrid() failed!")
+	}
+	if grid.at(mathutils.NewVector(0.5, 0.5, 0.5)) != 1 || grid.at(mathutils.NewVector(0.1, 0.5, 0.5)) != 0 ||
+		grid.at(mathutils.NewVector(0.9, 0.2, 0.9)) != 2 || grid.at(mathutils.NewVector(1.5, 0.5, 0.5)) != 0 {
+		t.Errorf("DensityGrid.at() failed!")
+	}
+	if cloud := NewNoiseDensityGrid(8, 4, 3); cloud.maximum <= 0 || cloud.at(mathutils.NewVector(0.01, 0.01, 0.01)) != 0 {
+		t.Errorf("NewNoiseDensityGrid() failed!")
+	}
+
+	// A constant grid lets through as much light as the homogeneous medium and glows by its emission.
+	constant, _ := NewDensityGrid(1, 1, 1, []float64{1})
+	bounds := mathutils.NewBoundingBox(mathutils.NewVector(-10, -10, -10), mathutils.NewVector(10, 10, 10))
+	medium := NewGridMedium(constant, bounds, 0.05, utils.Color{}, utils.Color{0.1, 0.05, 0}, 0, 512)
+	transmittance := medium.segmentTransmittance(mathutils.NewVector(0, -20, 0), mathutils.NewVector(0, 1, 0), 100)
+	if math.Abs(transmittance[0]-math.Exp(-1)) > 0.03 {
+		t.Errorf("GridMedium.segmentTransmittance() failed!")
+	}
+
+	scene := NewScene()
+	scene.Build()
+	ray := NewRay(mathutils.NewVector(0, 0, -50), mathutils.NewVector(0, 0, 1))
+	color := medium.integrate(&ray, 0, 100, utils.Color{1, 1, 1}, &scene)
+	for channel, emission := range []float64{0.1, 0.05, 0} {
+		expected := math.Exp(-1) + emission*(1-math.Exp(-1))/0.05
+		if math.Abs(color[channel]-expected) > 0.05 {
+			t.Errorf("GridMedium.integrate() failed!")
+		}
+	}
+
+	// The grid of a volume is stretched over the bounding box of its node.
+	reader := SceneReader{fileContent: strings.Fields(`Node { geometry Cube { center 0 0 0 edge 1 }
+		shader GridVolume { grid noise { resolution 4 } density 0.5 emission 1 0 0 }
+		transform { translate 0 5 0 scale 2 4 6 } } end`)}
+	node, err := reader.readNode(false)
+	if err != nil {
+		t.Fatalf("SceneReader.readNode() failed!")
+	}
+	volume := (*node.GetShader()).(*Volume).medium.(*GridMedium)
+	if volume.bounds != mathutils.NewBoundingBox(mathutils.NewVector(-1, 3, -3), mathutils.NewVector(1, 7, 3)) ||
+		volume.density != 0.5 || volume.emission != (utils.Color{1, 0, 0}) || volume.grid.width != 4 {
+		t.Errorf("SceneReader.readGridMedium() failed!")
+	}
+}
@@ -0,0 +1,270 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+	"sort"
+)
+
+// Kinds of environment.
+const (
+	EnvironmentConstant = iota // The same color in every direction.
+	EnvironmentGradient        // A blend from the bottom color straight down to the top color straight up.
+	EnvironmentMap             // An equirectangular image, with the top row straight up.
+	EnvironmentSky             // A daylight sky with a matching sun light.
+)
+
+// Environment defines the light arriving from infinitely far away, seen by the rays which miss the scene.
+// It lights the surfaces through importance samples with shadow rays.
+type Environment struct {
+	kind      int
+	color1    utils.Color // The constant color or the top color of the gradient.
+	color2    utils.Color // The bottom color of the gradient.
+	image     *bitmapLevel
+	sky       *preethamSky
+	sun       *DirectionalLight // The sun of the sky, nil when it is below the horizon.
+	rotation  float64           // The rotation of the map around the Y axis in radians.
+	intensity float64           // The multiplier of the colors.
+	samples   int               // The number of samples per shaded point.
+
+	rows    distribution1D   // The distribution of the rows of the map.
+	columns []distribution1D // The distribution of the columns in each row of the map.
+}
+
+// NewConstantEnvironment creates and returns an environment with the same color in every direction.
+func NewConstantEnvironment(color utils.Color, intensity float64, samples int) Environment {
+	return Environment{kind: EnvironmentConstant, color1: color, intensity: intensity, samples: samples}
+}
+
+// NewGradientEnvironment creates and returns an environment blending from bottom to top along the Y axis.
+func NewGradientEnvironment(top, bottom utils.Color, intensity float64, samples int) Environment {
+	return Environment{kind: EnvironmentGradient, color1: top, color2: bottom, intensity: intensity, samples: samples}
+}
+
+// NewMapEnvironment creates and returns an environment from an equirectangular image, preferably a Radiance HDR file,
+// rotated around the Y axis by the given degrees.
+func NewMapEnvironment(path string, rotationDegrees, intensity float64, samples int) (Environment, error) {
+	image, err := loadBitmapImage(path, false)
+	if err != nil {
+		return Environment{}, err
+	}
+
+	environment := Environment{kind: EnvironmentMap, image: &image.levels[0], rotation: mathutils.ToRadians(rotationDegrees),
+		intensity: intensity, samples: samples}
+	environment.buildDistribution(environment.image.width, environment.image.height, environment.image.at)
+
+	return environment, nil
+}
+
+// NewSkyEnvironment creates and returns a daylight sky. The sky is sampled like a map by its brightness.
+// The sun is not part of it and has to be added to the lights of the scene from Sun.
+func NewSkyEnvironment(settings Sky, intensity float64, samples int) Environment {
+	sky, sun := newPreethamSky(settings)
+	environment := Environment{kind: EnvironmentSky, sky: &sky, intensity: intensity, samples: samples}
+	if sun != nil {
+		sun.irradiance *= intensity
+		environment.sun = sun
+	}
+
+	// The sky is smooth, a coarse grid of directions is enough for its distribution.
+	const width, height = 128, 64
+	environment.buildDistribution(width, height, func(x, y int) utils.Color {
+		theta, phi := math.Pi*(float64(y)+0.5)/height, 2*math.Pi*(float64(x)+0.5)/width
+		return sky.radiance(mathutils.NewVector(math.Sin(theta)*math.Cos(phi), math.Cos(theta), math.Sin(theta)*math.Sin(phi)))
+	})
+
+	return environment
+}
+
+// buildDistribution sets up the sampling of the directions by the colors of an equirectangular grid,
+// with the top row straight up. The pixels are sampled by their brightness and the area they cover
+// on the sphere, which shrinks towards the poles.
+func (e *Environment) buildDistribution(width, height int, color func(x, y int) utils.Color) {
+	rowWeights := make([]float64, height)
+	e.columns = make([]distribution1D, height)
+	for y := 0; y < height; y++ {
+		sinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(height))
+		weights := make([]float64, width)
+		for x := range weights {
+			weights[x] = luminance(color(x, y)) * sinTheta
+		}
+		e.columns[y] = newDistribution1D(weights)
+		rowWeights[y] = e.columns[y].average
+	}
+	e.rows = newDistribution1D(rowWeights)
+}
+
+// Sun returns the sun light matching a sky, false for the other kinds or when the sun is below the horizon.
+func (e *Environment) Sun() (DirectionalLight, bool) {
+	if e.sun == nil {
+		return DirectionalLight{}, false
+	}
+
+	return *e.sun, true
+}
+
+// Radiance returns the color of the environment in the direction, which has to be normalized.
+func (e *Environment) Radiance(direction mathutils.Vector) utils.Color {
+	var result utils.Color
+	switch e.kind {
+	case EnvironmentConstant:
+		result = e.color1
+	case EnvironmentGradient:
+		result = mixColors(e.color2, e.color1, 0.5+0.5*direction.Y)
+	case EnvironmentMap:
+		u, v := e.toMap(direction)
+		x := int(math.Min(u*float64(e.image.width), float64(e.image.width-1)))
+		y := int(math.Min(v*float64(e.image.height), float64(e.image.height-1)))
+		result = e.image.at(x, y)
+	case EnvironmentSky:
+		result = e.sky.radiance(direction)
+	}
+
+	return utils.MultiplyColorFloat(result, e.intensity)
+}
+
+// toMap returns the map coordinates in [0, 1) of the direction, U around the Y axis and V from the top.
+func (e *Environment) toMap(direction mathutils.Vector) (float64, float64) {
+	u := (math.Atan2(direction.Z, direction.X) - e.rotation) / (2 * math.Pi)
+	u -= math.Floor(u)
+	v := math.Acos(math.Max(-1, math.Min(direction.Y, 1))) / math.Pi
+
+	return u, v
+}
+
+// sample returns a direction for lighting a surface with the normal and the density of the choice by solid angle.
+// The map and the sky are sampled by their brightness and the other kinds by the cosine to the normal.
+func (e *Environment) sample(normal mathutils.Vector, u1, u2 float64) (mathutils.Vector, float64) {
+	if e.columns == nil {
+		return cosineDirection(normal, u1, u2), math.Sqrt(math.Max(0, 1-u1)) / math.Pi
+	}
+
+	v, row, rowDensity := e.rows.sample(u1)
+	u, _, columnDensity := e.columns[row].sample(u2)
+	theta, phi := v*math.Pi, 2*math.Pi*u+e.rotation
+	sinTheta := math.Sin(theta)
+	if sinTheta == 0 {
+		return mathutils.Vector{}, 0
+	}
+
+	direction := mathutils.NewVector(sinTheta*math.Cos(phi), math.Cos(theta), sinTheta*math.Sin(phi))
+	return direction, rowDensity * columnDensity / (2 * math.Pi * math.Pi * sinTheta)
+}
+
+// environmentLighting returns the light of the environment reflected by the surface hit by the ray. The reflectance
+// gives the share of the light arriving from a direction which the surface reflects, including the cosine factor.
+// Without an environment the result is black.
+func environmentLighting(ray *Ray, info *IntersectionInfo, scene *Scene, reflectance func(toLight mathutils.Vector) utils.Color) utils.Color {
+	environment := scene.environment
+	var result utils.Color
+	if environment == nil || environment.samples <= 0 {
+		return result
+	}
+
+	// The samples are a Hammersley set shifted by a hash of the position, so neighbouring points get different ones.
+	normal := mathutils.Faceforward(ray.Direction, info.Normal)
+	shiftU, shiftV := hashPosition(info.Position)
+	for i := 0; i < environment.samples; i++ {
+		u1 := math.Mod((float64(i)+0.5)/float64(environment.samples)+shiftU, 1)
+		u2 := math.Mod(radicalInverse(uint(i))+shiftV, 1)
+		direction, density := environment.sample(normal, u1, u2)
+		if density <= 0 {
+			continue
+		}
+
+		share := reflectance(direction)
+		if share == (utils.Color{}) {
+			continue
+		}
+
+		side := normal
+		if mathutils.DotProduct(side, direction) < 0 {
+			side.UnaryMinus()
+		}
+		visible := visibility(mathutils.VectorAddition(info.Position, mathutils.VectorMultiply(side, 1e-5)), direction, math.Inf(1), scene)
+		if visible == (utils.Color{}) {
+			continue
+		}
+
+		light := utils.ColorMultiplication(utils.ColorMultiplication(environment.Radiance(direction), share), visible)
+		result = utils.ColorAddition(result, utils.MultiplyColorFloat(light, 1/(density*float64(environment.samples))))
+	}
+
+	return result
+}
+
+// hashPosition returns two pseudo random numbers in [0, 1) which depend on the position.
+func hashPosition(p mathutils.Vector) (float64, float64) {
+	hash := uint64(14695981039346656037)
+	for _, value := range [3]float64{p.X, p.Y, p.Z} {
+		hash ^= math.Float64bits(value)
+		hash *= 1099511628211
+		hash ^= hash >> 29
+	}
+
+	return float64(hash>>40) / (1 << 24), float64(hash&(1<<24-1)) / (1 << 24)
+}
+
+// radicalInverse mirrors the binary digits of i around the point, giving the van der Corput sequence.
+func radicalInverse(i uint) float64 {
+	result, digit := 0.0, 0.5
+	for ; i > 0; i >>= 1 {
+		result += float64(i&1) * digit
+		digit /= 2
+	}
+
+	return result
+}
+
+// distribution1D holds a piecewise constant distribution over [0, 1) for sampling proportionally to a function.
+type distribution1D struct {
+	function []float64
+	cdf      []float64 // The integral of the function up to the start of each piece, normalized to end at 1.
+	average  float64   // The average of the function, zero if it is zero everywhere.
+}
+
+// newDistribution1D creates and returns the distribution of the function values over pieces of equal width.
+// A function which is zero everywhere is sampled uniformly.
+func newDistribution1D(function []float64) distribution1D {
+	result := distribution1D{function: function, cdf: make([]float64, len(function)+1)}
+	for i, value := range function {
+		result.cdf[i+1] = result.cdf[i] + value/float64(len(function))
+	}
+
+	result.average = result.cdf[len(function)]
+	for i := range result.cdf {
+		if result.average > 0 {
+			result.cdf[i] /= result.average
+		} else {
+			result.cdf[i] = float64(i) / float64(len(function))
+		}
+	}
+
+	return result
+}
+
+// sample returns the point in [0, 1) for the uniform random number, the piece holding it and the density.
+func (d *distribution1D) sample(u float64) (float64, int, float64) {
+	index := sort.SearchFloat64s(d.cdf, u)
+	if index > 0 {
+		index--
+	}
+	for index < len(d.function)-1 && d.cdf[index+1] <= u {
+		index++
+	}
+
+	width := d.cdf[index+1] - d.cdf[index]
+	offset := 0.0
+	if width > 0 {
+		offset = (u - d.cdf[index]) / width
+	}
+
+	density := 1.0
+	if d.average > 0 {
+		density = d.function[index] / d.average
+	}
+
+	return (float64(index) + offset) / float64(len(d.function)), index, density
+}
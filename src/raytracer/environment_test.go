@@ -0,0 +1,66 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvironment(t *testing.T) {
+	gradient := NewGradientEnvironment(utils.Color{0, 0, 1}, utils.Color{1, 0, 0}, 2, 16)
+	if gradient.Radiance(mathutils.NewVector(0, 1, 0)) != (utils.Color{0, 0, 2}) ||
+		gradient.Radiance(mathutils.NewVector(0, -1, 0)) != (utils.Color{2, 0, 0}) {
+		t.Errorf("Environment.Radiance() failed!")
+	}
+
+	distribution := newDistribution1D([]float64{0, 1, 3})
+	if x, index, density := distribution.sample(0.5); index != 2 || math.Abs(x-(2+1.0/3)/3) > 1e-9 || math.Abs(density-2.25) > 1e-9 {
+		t.Errorf("distribution1D.sample() failed!")
+	}
+
+	// A white environment lights a white lambert surface to white, whatever the samples.
+	scene := NewScene()
+	scene.Build()
+	scene.SetEnvironment(NewConstantEnvironment(utils.Color{1, 1, 1}, 1, 8))
+	ray := NewRay(mathutils.NewVector(0, 1, 0), mathutils.NewVector(0, -1, 0))
+	info := IntersectionInfo{Position: mathutils.NewVector(0.3, 0, 0.7), Normal: mathutils.NewVector(0, 1, 0)}
+	lambert := func(toLight mathutils.Vector) utils.Color {
+		cosTheta := math.Max(0, toLight.Y) / math.Pi
+		return utils.Color{cosTheta, cosTheta, cosTheta}
+	}
+	if light := environmentLighting(&ray, &info, &scene, lambert); math.Abs(light[0]-1) > 1e-9 {
+		t.Errorf("environmentLighting() failed!")
+	}
+
+	// The samples of a map go to its only bright pixel, in the row above the horizon and the column facing +X.
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for i := 0; i < 32; i++ {
+		img.Set(i%8, i/8, color.RGBA{0, 0, 0, 255})
+	}
+	img.Set(4, 1, color.RGBA{255, 255, 255, 255})
+	path := filepath.Join(t.TempDir(), "sky.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(file, img)
+	file.Close()
+
+	environment, err := NewMapEnvironment(path, 180, 1, 16)
+	if err != nil {
+		t.Fatalf("NewMapEnvironment() failed!")
+	}
+	for i := 0; i < 16; i++ {
+		direction, density := environment.sample(mathutils.NewVector(0, 1, 0), (float64(i)+0.5)/16, radicalInverse(uint(i))+1.0/32)
+		if density <= 0 || direction.Y < 0 || direction.Y > math.Sqrt2/2 || direction.X < 0 ||
+			environment.Radiance(direction) != (utils.Color{1, 1, 1}) {
+			t.Errorf("Environment.sample() failed!")
+		}
+	}
+}
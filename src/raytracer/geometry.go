@@ -3,7 +3,10 @@ package raytracer
 
 import (
 	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"fmt"
 	"math"
+	"sort"
 )
 
 //Plane orientation
@@ -19,6 +22,75 @@ type IntersectionInfo struct {
 	Normal   mathutils.Vector // Normal at the given position.
 	Distance float64          // Distance to the camera.
 	U, V     float64          // U and V coordinates.
+
+	VertexColor    utils.Color // Color interpolated from the vertices of meshes that have vertex colors.
+	HasVertexColor bool        // Whether the hit geometry provides VertexColor.
+
+	DPdu, DPdv mathutils.Vector // Derivatives of the position along U and V, zero if the geometry does not provide them.
+
+	DUdx, DVdx float64 // Change of U and V to the next pixel along X, zero without ray differentials.
+	DUdy, DVdy float64 // Change of U and V to the next pixel along Y, zero without ray differentials.
+
+	shader    *Shader       // The shader of the closest node above the hit geometry which has one.
+	triplanar *triplanarHit // The projections of the closest node above the hit geometry with triplanar mapping.
+}
+
+// reset clears the optional information which not every geometry fills in.
+func (i *IntersectionInfo) reset() {
+	i.shader = nil
+	i.triplanar = nil
+	i.HasVertexColor = false
+	i.DPdu = mathutils.Vector{}
+	i.DPdv = mathutils.Vector{}
+	i.DUdx, i.DVdx, i.DUdy, i.DVdy = 0, 0, 0, 0
+}
+
+// computeDifferentials estimates how much U and V change from the hit to the next pixels, which textures use as
+// their filter footprint. The differential rays are intersected with the tangent plane of the hit and the offsets
+// are expressed in DPdu and DPdv by least squares.
+func (i *IntersectionInfo) computeDifferentials(ray *Ray) {
+	i.DUdx, i.DVdx, i.DUdy, i.DVdy = 0, 0, 0, 0
+	if !ray.HasDifferentials {
+		return
+	}
+
+	offset := func(start, direction mathutils.Vector) (mathutils.Vector, bool) {
+		denominator := mathutils.DotProduct(i.Normal, direction)
+		if math.Abs(denominator) < 1e-12 {
+			return mathutils.Vector{}, false
+		}
+		distance := mathutils.DotProduct(i.Normal, mathutils.VectorSubstraction(i.Position, start)) / denominator
+		return mathutils.VectorSubstraction(mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, distance)), i.Position), true
+	}
+	dPdx, okX := offset(ray.XStart, ray.XDirection)
+	dPdy, okY := offset(ray.YStart, ray.YDirection)
+
+	if !okX || !okY {
+		return
+	}
+
+	i.DUdx, i.DVdx = uvOffset(i.DPdu, i.DPdv, dPdx)
+	i.DUdy, i.DVdy = uvOffset(i.DPdu, i.DPdv, dPdy)
+}
+
+// uvOffset returns the changes of U and V which best explain the offset dP of the position by least squares,
+// or zeros if the derivatives are degenerate.
+func uvOffset(dPdu, dPdv, dP mathutils.Vector) (float64, float64) {
+	uu := mathutils.DotProduct(dPdu, dPdu)
+	uv := mathutils.DotProduct(dPdu, dPdv)
+	vv := mathutils.DotProduct(dPdv, dPdv)
+	determinant := uu*vv - uv*uv
+	if math.Abs(determinant) < 1e-20 {
+		return 0, 0
+	}
+
+	u := mathutils.DotProduct(dPdu, dP)
+	v := mathutils.DotProduct(dPdv, dP)
+	du, dv := (vv*u-uv*v)/determinant, (uu*v-uv*u)/determinant
+	if math.IsNaN(du) || math.IsNaN(dv) || math.IsInf(du, 0) || math.IsInf(dv, 0) {
+		return 0, 0
+	}
+	return du, dv
 }
 
 // Geometry provides a interface for intersection.
@@ -26,78 +98,264 @@ type Geometry interface {
 	Intersect(*Ray, *IntersectionInfo) bool
 }
 
-// Plane defines a plane in the 3-dimentional space.
+// Solid provides an interface for closed geometries which can enumerate all their hits along a ray.
+// IntersectAll returns the hits along the whole line of the ray, including the ones behind its start,
+// sorted by distance and with normals pointing out of the solid.
+type Solid interface {
+	Geometry
+	IntersectAll(*Ray) []IntersectionInfo
+}
+
+// Bounded provides an interface for geometries that can report a bounding box.
+// The box can be infinite for geometries like infinite planes.
+type Bounded interface {
+	BoundingBox() mathutils.BoundingBox
+}
+
+// Plane defines a plane in the 3-dimentional space given by a point and a normal.
+// The plane can be bounded along its tangent vectors by width and height.
 type Plane struct {
-	center      mathutils.Vector // Center of the plane.
-	limit       float64          // How far the plane stretches.
-	orientation uint8            // Plane orientation.
+	center    mathutils.Vector // A point on the plane.
+	origin    mathutils.Vector // The point where U and V are zero.
+	normal    mathutils.Vector // The normal of the plane.
+	tangent   mathutils.Vector // The direction of the U coordinate.
+	bitangent mathutils.Vector // The direction of the V coordinate.
+	width     float64          // How far the plane stretches along the tangent, zero for infinite.
+	height    float64          // How far the plane stretches along the bitangent, zero for infinite.
 }
 
-// NewPlane creates a new plane with the given center, limit, orientation and returns it.
+// NewPlane creates a new axis-aligned square plane with the given center, limit, orientation and returns it.
+// U and V are the world coordinates along the axes of the plane.
 func NewPlane(center mathutils.Vector, limit float64, orientation uint8) Plane {
-	return Plane{center, limit, orientation}
+	var origin mathutils.Vector
+	switch orientation {
+	case XY:
+		return Plane{center, origin, mathutils.NewVector(0, 0, 1), mathutils.NewVector(1, 0, 0), mathutils.NewVector(0, 1, 0), limit, limit}
+	case XZ:
+		return Plane{center, origin, mathutils.NewVector(0, 1, 0), mathutils.NewVector(1, 0, 0), mathutils.NewVector(0, 0, 1), limit, limit}
+	default:
+		return Plane{center, origin, mathutils.NewVector(1, 0, 0), mathutils.NewVector(0, 1, 0), mathutils.NewVector(0, 0, 1), limit, limit}
+	}
+}
+
+// NewOrientedPlane creates a new plane through center with the given normal and returns it.
+// A zero width or height leaves the plane infinite in that direction. U and V start at the center.
+func NewOrientedPlane(center, normal mathutils.Vector, width, height float64) Plane {
+	normal.Normalize()
+	tangent, bitangent := mathutils.OrthonormalBasis(normal)
+	return Plane{center, center, normal, tangent, bitangent, width, height}
 }
 
 // Intersect implements the intersect method of the Geometry interface for Plane.
 func (p *Plane) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	distance, ok := intersectPlane(ray, p.center, p.normal)
+	if !ok {
+		return false
+	}
 
-	var start, direction, plane float64
+	position := mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, distance))
+	relativePosition := mathutils.VectorSubstraction(position, p.center)
+	u := mathutils.DotProduct(relativePosition, p.tangent)
+	v := mathutils.DotProduct(relativePosition, p.bitangent)
+	if p.width > 0 && math.Abs(u) > p.width/2 || p.height > 0 && math.Abs(v) > p.height/2 {
+		return false
+	}
 
-	if p.orientation == XY {
-		start = ray.Start.Z
-		direction = ray.Direction.Z
-		plane = p.center.Z
-	} else if p.orientation == XZ {
-		start = ray.Start.Y
-		direction = ray.Direction.Y
-		plane = p.center.Y
-	} else {
-		start = ray.Start.X
-		direction = ray.Direction.X
-		plane = p.center.X
+	offset := mathutils.VectorSubstraction(p.center, p.origin)
+	info.Position = position
+	info.Distance = distance
+	info.U = u + mathutils.DotProduct(offset, p.tangent)
+	info.V = v + mathutils.DotProduct(offset, p.bitangent)
+	info.DPdu = p.tangent
+	info.DPdv = p.bitangent
+	info.Normal = mathutils.Faceforward(ray.Direction, p.normal)
+	return true
+}
+
+// BoundingBox implements the Bounded interface for Plane.
+func (p *Plane) BoundingBox() mathutils.BoundingBox {
+	if p.width <= 0 || p.height <= 0 {
+		return mathutils.InfiniteBoundingBox()
+	}
+
+	result := mathutils.EmptyBoundingBox()
+	for _, u := range []float64{-p.width / 2, p.width / 2} {
+		for _, v := range []float64{-p.height / 2, p.height / 2} {
+			result.AddPoint(mathutils.VectorAddition(p.center, mathutils.VectorAddition(mathutils.VectorMultiply(p.tangent, u), mathutils.VectorMultiply(p.bitangent, v))))
+		}
+	}
+
+	return result
+}
+
+// Disc defines a flat disc, optionally with a hole in the middle, in the 3-dimentional space.
+type Disc struct {
+	center      mathutils.Vector // The center of the disc.
+	normal      mathutils.Vector // The normal of the disc.
+	tangent     mathutils.Vector // The direction where the U coordinate starts.
+	bitangent   mathutils.Vector // The direction a quarter turn after the tangent.
+	radius      float64          // The outer radius of the disc.
+	innerRadius float64          // The radius of the hole in the disc.
+}
+
+// NewDisc creates and returns a new disc with the given center, normal, radius and inner radius.
+// The inner radius must be smaller than the radius.
+func NewDisc(center, normal mathutils.Vector, radius, innerRadius float64) (Disc, error) {
+	if innerRadius < 0 || innerRadius >= radius {
+		return Disc{}, fmt.Errorf("The inner radius %g of a disc must be from 0 to below its radius %g", innerRadius, radius)
 	}
 
-	if direction >= 0.0 && start > plane || direction <= 0.0 && start < plane {
+	normal.Normalize()
+	tangent, bitangent := mathutils.OrthonormalBasis(normal)
+	return Disc{center, normal, tangent, bitangent, radius, innerRadius}, nil
+}
+
+// Intersect implements the intersect method of the Geometry interface for Disc.
+// U goes around the disc and V goes from the outer to the inner edge.
+func (d *Disc) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	distance, ok := intersectPlane(ray, d.center, d.normal)
+	if !ok {
 		return false
 	}
 
-	multiplayer := (start - plane) / -direction
+	position := mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, distance))
+	relativePosition := mathutils.VectorSubstraction(position, d.center)
+	radius := relativePosition.Length()
+	if radius > d.radius || radius < d.innerRadius {
+		return false
+	}
 
-	info.Position = ray.Direction
-	info.Position.Multiply(multiplayer)
-	info.Position = mathutils.VectorAddition(info.Position, ray.Start)
-	info.Distance = multiplayer
+	angle := math.Atan2(mathutils.DotProduct(relativePosition, d.bitangent), mathutils.DotProduct(relativePosition, d.tangent))
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
 
-	if p.orientation == XY {
-		if math.Abs(p.center.X-info.Position.X) > p.limit/2 || math.Abs(p.center.Y-info.Position.Y) > p.limit/2 {
-			return false
-		}
-		info.U = info.Position.X
-		info.V = info.Position.Y
-		info.Normal = mathutils.NewVector(0, 0, 1)
-	} else if p.orientation == XZ {
-		if math.Abs(p.center.X-info.Position.X) > p.limit/2 || math.Abs(p.center.Z-info.Position.Z) > p.limit/2 {
-			return false
-		}
-		info.U = info.Position.X
-		info.V = info.Position.Z
-		info.Normal = mathutils.NewVector(0, 1, 0)
-	} else {
-		if math.Abs(p.center.Y-info.Position.Y) > p.limit/2 || math.Abs(p.center.Z-info.Position.Z) > p.limit/2 {
-			return false
-		}
-		info.U = info.Position.Y
-		info.V = info.Position.Z
-		info.Normal = mathutils.NewVector(1, 0, 0)
+	info.Position = position
+	info.Distance = distance
+	info.U = angle / (2 * math.Pi)
+	info.V = (d.radius - radius) / (d.radius - d.innerRadius)
+	info.DPdu = mathutils.VectorMultiply(mathutils.CrossProduct(d.normal, relativePosition), 2*math.Pi)
+	if radius > 0 {
+		info.DPdv = mathutils.VectorMultiply(relativePosition, -(d.radius-d.innerRadius)/radius)
+	}
+	info.Normal = mathutils.Faceforward(ray.Direction, d.normal)
+	return true
+}
+
+// BoundingBox implements the Bounded interface for Disc.
+func (d *Disc) BoundingBox() mathutils.BoundingBox {
+	extent := mathutils.NewVector(d.radius*math.Sqrt(1-d.normal.X*d.normal.X), d.radius*math.Sqrt(1-d.normal.Y*d.normal.Y), d.radius*math.Sqrt(1-d.normal.Z*d.normal.Z))
+	return mathutils.NewBoundingBox(mathutils.VectorSubstraction(d.center, extent), mathutils.VectorAddition(d.center, extent))
+}
+
+// Quad defines a parallelogram given by a corner and two edge vectors in the 3-dimentional space.
+type Quad struct {
+	corner mathutils.Vector // The corner where both edges start.
+	edge1  mathutils.Vector // The edge along which U grows.
+	edge2  mathutils.Vector // The edge along which V grows.
+	normal mathutils.Vector // The normal of the quad.
+}
+
+// NewQuad creates and returns a new quad with the given corner and edges, which must not be parallel.
+func NewQuad(corner, edge1, edge2 mathutils.Vector) (Quad, error) {
+	normal := mathutils.CrossProduct(edge1, edge2)
+	if normal.LengthSqr() <= 1e-12*edge1.LengthSqr()*edge2.LengthSqr() {
+		return Quad{}, fmt.Errorf("The edges of a quad must not be zero or parallel")
+	}
+
+	normal.Normalize()
+	return Quad{corner, edge1, edge2, normal}, nil
+}
+
+// Intersect implements the intersect method of the Geometry interface for Quad.
+// U and V go from 0 to 1 along the first and the second edge.
+func (q *Quad) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	distance, ok := intersectPlane(ray, q.corner, q.normal)
+	if !ok {
+		return false
 	}
 
-	if mathutils.DotProduct(ray.Direction, info.Normal) > 0 {
-		info.Normal.UnaryMinus()
+	position := mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, distance))
+	relativePosition := mathutils.VectorSubstraction(position, q.corner)
+	cross := mathutils.CrossProduct(q.edge1, q.edge2)
+	w := mathutils.VectorMultiply(cross, 1/cross.LengthSqr())
+	u := mathutils.DotProduct(w, mathutils.CrossProduct(relativePosition, q.edge2))
+	v := mathutils.DotProduct(w, mathutils.CrossProduct(q.edge1, relativePosition))
+	if u < 0 || u > 1 || v < 0 || v > 1 {
+		return false
 	}
 
+	info.Position = position
+	info.Distance = distance
+	info.U = u
+	info.V = v
+	info.DPdu = q.edge1
+	info.DPdv = q.edge2
+	info.Normal = mathutils.Faceforward(ray.Direction, q.normal)
 	return true
 }
 
+// BoundingBox implements the Bounded interface for Quad.
+func (q *Quad) BoundingBox() mathutils.BoundingBox {
+	result := mathutils.EmptyBoundingBox()
+	result.AddPoint(q.corner)
+	result.AddPoint(mathutils.VectorAddition(q.corner, q.edge1))
+	result.AddPoint(mathutils.VectorAddition(q.corner, q.edge2))
+	result.AddPoint(mathutils.VectorAddition(q.corner, mathutils.VectorAddition(q.edge1, q.edge2)))
+	return result
+}
+
+// sortHits sorts the hits by distance.
+func sortHits(hits []IntersectionInfo) {
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Distance < hits[j].Distance
+	})
+}
+
+// intersectPlane returns the distance along the ray to the plane through point with the given normal.
+func intersectPlane(ray *Ray, point, normal mathutils.Vector) (float64, bool) {
+	denominator := mathutils.DotProduct(ray.Direction, normal)
+	if math.Abs(denominator) < 1e-12 {
+		return 0, false
+	}
+
+	distance := mathutils.DotProduct(mathutils.VectorSubstraction(point, ray.Start), normal) / denominator
+	if distance < 0 {
+		return 0, false
+	}
+
+	return distance, true
+}
+
+// intersectTriangle returns the distance along the ray to the triangle abc
+// and the barycentric weights of b and c at the intersection.
+func intersectTriangle(ray *Ray, a, b, c mathutils.Vector) (distance, beta, gamma float64, ok bool) {
+	edge1 := mathutils.VectorSubstraction(b, a)
+	edge2 := mathutils.VectorSubstraction(c, a)
+	p := mathutils.CrossProduct(ray.Direction, edge2)
+	determinant := mathutils.DotProduct(edge1, p)
+	if math.Abs(determinant) < 1e-12 {
+		return
+	}
+
+	inverse := 1 / determinant
+	s := mathutils.VectorSubstraction(ray.Start, a)
+	beta = mathutils.DotProduct(s, p) * inverse
+	if beta < 0 || beta > 1 {
+		return
+	}
+
+	q := mathutils.CrossProduct(s, edge1)
+	gamma = mathutils.DotProduct(ray.Direction, q) * inverse
+	if gamma < 0 || beta+gamma > 1 {
+		return
+	}
+
+	distance = mathutils.DotProduct(edge2, q) * inverse
+	ok = distance > 1e-6
+	return
+}
+
 // Sphere defines a sphere in the 3-dimentional space.
 type Sphere struct {
 	center mathutils.Vector // The center of the sphere.
@@ -129,11 +387,31 @@ func (s *Sphere) Intersect(ray *Ray, info *IntersectionInfo) bool {
 	}
 
 	if x2 < 0 || (x1 >= 0 && x1 < x2) {
-		info.Distance = x1
+		s.fillHit(ray, x1, info)
 	} else {
-		info.Distance = x2
+		s.fillHit(ray, x2, info)
+	}
+
+	return true
+}
+
+// IntersectAll implements the IntersectAll method of the Solid interface for Sphere.
+func (s *Sphere) IntersectAll(ray *Ray) []IntersectionInfo {
+	H := mathutils.VectorSubstraction(ray.Start, s.center)
+	roots := mathutils.SolveQuadratic(ray.Direction.LengthSqr(), 2*mathutils.DotProduct(H, ray.Direction), H.LengthSqr()-s.radius*s.radius)
+	if len(roots) < 2 {
+		return nil
 	}
 
+	hits := make([]IntersectionInfo, 2)
+	s.fillHit(ray, roots[0], &hits[0])
+	s.fillHit(ray, roots[1], &hits[1])
+	return hits
+}
+
+// fillHit fills info with the point of the sphere at the given distance along the ray.
+func (s *Sphere) fillHit(ray *Ray, distance float64, info *IntersectionInfo) {
+	info.Distance = distance
 	info.Position = mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, info.Distance))
 	info.Normal = mathutils.VectorSubstraction(info.Position, s.center)
 	info.Normal.Normalize()
@@ -142,7 +420,20 @@ func (s *Sphere) Intersect(ray *Ray, info *IntersectionInfo) bool {
 	info.V = math.Asin(relativePosition.Y / s.radius)
 	info.U = (info.U + math.Pi) / (2 * math.Pi)
 	info.V = -(info.V + math.Pi/2) / math.Pi
-	return true
+
+	// The derivatives of the longitude and latitude, scaled to U and V.
+	info.DPdu = mathutils.NewVector(-2*math.Pi*relativePosition.Z, 0, 2*math.Pi*relativePosition.X)
+	radial := math.Sqrt(relativePosition.X*relativePosition.X + relativePosition.Z*relativePosition.Z)
+	if radial > 0 {
+		info.DPdv = mathutils.NewVector(relativePosition.Y*relativePosition.X/radial, -radial, relativePosition.Y*relativePosition.Z/radial)
+		info.DPdv.Multiply(math.Pi)
+	}
+}
+
+// BoundingBox implements the Bounded interface for Sphere.
+func (s *Sphere) BoundingBox() mathutils.BoundingBox {
+	extent := mathutils.NewVector(s.radius, s.radius, s.radius)
+	return mathutils.NewBoundingBox(mathutils.VectorSubstraction(s.center, extent), mathutils.VectorAddition(s.center, extent))
 }
 
 // Cube defines a cube with walls parallel to the XYZ axis in the 3-dimentional space.
@@ -181,13 +472,8 @@ func (c *Cube) intersectSide(ray *Ray, normal mathutils.Vector, info *Intersecti
 		info.Position = ip
 		info.Distance = distance
 		info.Normal = normal
-		if normal.Y == 0 {
-			info.U = ip.X + ip.Z
-			info.V = ip.Y
-		} else {
-			info.U = ip.X
-			info.V = ip.Z
-		}
+		info.U, info.V = cubeUV(ip, normal)
+		info.DPdu, info.DPdv = boxDerivatives(normal)
 
 		return true
 	}
@@ -208,3 +494,507 @@ func (c *Cube) Intersect(ray *Ray, info *IntersectionInfo) bool {
 	return info.Distance < 1e99
 
 }
+
+// IntersectAll implements the IntersectAll method of the Solid interface for Cube.
+// The ray enters the cube at the last of the sides it crosses inwards and leaves it at the first
+// of the sides it crosses outwards, so a ray through an edge or a corner gives one entry and one exit.
+func (c *Cube) IntersectAll(ray *Ray) []IntersectionInfo {
+	near, far := math.Inf(-1), math.Inf(1)
+	var nearNormal, farNormal mathutils.Vector
+	for axis := 0; axis < 3; axis++ {
+		start := vectorComponent(ray.Start, axis) - vectorComponent(c.center, axis)
+		direction := vectorComponent(ray.Direction, axis)
+		if math.Abs(direction) < 1e-12 {
+			if math.Abs(start) > c.edge/2 {
+				return nil
+			}
+			continue
+		}
+
+		// The side facing the ray is entered, the opposite one is left.
+		side := -math.Copysign(1, direction)
+		enter := (side*c.edge/2 - start) / direction
+		leave := (-side*c.edge/2 - start) / direction
+		if enter > near {
+			near = enter
+			nearNormal = axisVector(axis, side)
+		}
+		if leave < far {
+			far = leave
+			farNormal = axisVector(axis, -side)
+		}
+	}
+
+	// A ray only touching an edge or a corner does not pass through the cube.
+	if far-near <= 1e-9 {
+		return nil
+	}
+
+	hits := make([]IntersectionInfo, 0, 2)
+	for _, hit := range []struct {
+		distance float64
+		normal   mathutils.Vector
+	}{{near, nearNormal}, {far, farNormal}} {
+		ip := mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, hit.distance))
+		u, v := cubeUV(ip, hit.normal)
+		dPdu, dPdv := boxDerivatives(hit.normal)
+		hits = append(hits, IntersectionInfo{Position: ip, Normal: hit.normal, Distance: hit.distance, U: u, V: v, DPdu: dPdu, DPdv: dPdv})
+	}
+
+	return hits
+}
+
+// cubeUV returns the U and V coordinates of a point on the side of a cube with the given normal.
+func cubeUV(ip, normal mathutils.Vector) (float64, float64) {
+	if normal.Y == 0 {
+		return ip.X + ip.Z, ip.Y
+	}
+
+	return ip.X, ip.Z
+}
+
+// boxDerivatives returns DPdu and DPdv for U and V projected along the dominant axis of the normal,
+// as for cubeUV and boxUV. Along the sides U is X + Z, so each of X and Z contributes half of it.
+func boxDerivatives(normal mathutils.Vector) (mathutils.Vector, mathutils.Vector) {
+	x, y, z := math.Abs(normal.X), math.Abs(normal.Y), math.Abs(normal.Z)
+	if y >= x && y >= z {
+		return mathutils.NewVector(1, 0, 0), mathutils.NewVector(0, 0, 1)
+	}
+
+	return mathutils.NewVector(0.5, 0, 0.5), mathutils.NewVector(0, 1, 0)
+}
+
+// BoundingBox implements the Bounded interface for Cube.
+func (c *Cube) BoundingBox() mathutils.BoundingBox {
+	extent := mathutils.NewVector(c.edge/2, c.edge/2, c.edge/2)
+	return mathutils.NewBoundingBox(mathutils.VectorSubstraction(c.center, extent), mathutils.VectorAddition(c.center, extent))
+}
+
+// localFrame defines an orthonormal coordinate system whose Y axis is the axis of a primitive.
+type localFrame struct {
+	origin mathutils.Vector // The origin of the frame in world space.
+	x      mathutils.Vector // The X axis of the frame in world space.
+	y      mathutils.Vector // The Y axis of the frame in world space.
+	z      mathutils.Vector // The Z axis of the frame in world space.
+}
+
+// newLocalFrame creates and returns a local frame with the given origin and Y axis.
+func newLocalFrame(origin, axis mathutils.Vector) localFrame {
+	axis.Normalize()
+	x, z := mathutils.OrthonormalBasis(axis)
+	return localFrame{origin, x, axis, z}
+}
+
+// toLocal returns the ray start and direction in the local frame.
+func (f *localFrame) toLocal(ray *Ray) (start, direction mathutils.Vector) {
+	relativeStart := mathutils.VectorSubstraction(ray.Start, f.origin)
+	start = mathutils.NewVector(mathutils.DotProduct(relativeStart, f.x), mathutils.DotProduct(relativeStart, f.y), mathutils.DotProduct(relativeStart, f.z))
+	direction = mathutils.NewVector(mathutils.DotProduct(ray.Direction, f.x), mathutils.DotProduct(ray.Direction, f.y), mathutils.DotProduct(ray.Direction, f.z))
+	return
+}
+
+// toWorld returns the given local direction in world space.
+func (f *localFrame) toWorld(direction mathutils.Vector) mathutils.Vector {
+	result := mathutils.VectorMultiply(f.x, direction.X)
+	result.Add(mathutils.VectorMultiply(f.y, direction.Y))
+	result.Add(mathutils.VectorMultiply(f.z, direction.Z))
+	return result
+}
+
+// boundingBox returns the bounding box of a shape around the Y axis of the frame.
+// The shape reaches halfHeight along the axis and radius away from it, and is additionally padded by padding.
+func (f *localFrame) boundingBox(halfHeight, radius, padding float64) mathutils.BoundingBox {
+	extent := func(axis float64) float64 {
+		return math.Abs(axis)*halfHeight + radius*math.Sqrt(math.Max(0, 1-axis*axis)) + padding
+	}
+
+	size := mathutils.NewVector(extent(f.y.X), extent(f.y.Y), extent(f.y.Z))
+	return mathutils.NewBoundingBox(mathutils.VectorSubstraction(f.origin, size), mathutils.VectorAddition(f.origin, size))
+}
+
+// localHit holds a candidate intersection in the local frame of a primitive.
+type localHit struct {
+	distance float64
+	normal   mathutils.Vector
+	u, v     float64
+	dPdv     mathutils.Vector // The derivative along V, DPdu follows from U going around the Y axis.
+	point    mathutils.Vector // The hit point in the local frame.
+}
+
+// allHits returns the given hits transformed from the local frame into world space, sorted by distance.
+func allHits(ray *Ray, frame *localFrame, hits []localHit) []IntersectionInfo {
+	result := make([]IntersectionInfo, len(hits))
+	for i := range hits {
+		hits[i].fill(ray, frame, &result[i])
+	}
+
+	sortHits(result)
+	return result
+}
+
+// closest returns the closest hit in front of the ray start.
+func closest(hits []localHit) (localHit, bool) {
+	var best localHit
+	found := false
+	for _, hit := range hits {
+		if hit.distance > 1e-6 && (!found || hit.distance < best.distance) {
+			best = hit
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// fill fills info with the hit transformed from the given local frame into world space.
+func (h *localHit) fill(ray *Ray, frame *localFrame, info *IntersectionInfo) {
+	info.Distance = h.distance
+	info.Position = mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, h.distance))
+	info.Normal = frame.toWorld(h.normal)
+	info.Normal.Normalize()
+	info.U = h.u
+	info.V = h.v
+	info.DPdu = frame.toWorld(aroundAxis(h.point))
+	info.DPdv = frame.toWorld(h.dPdv)
+}
+
+// aroundAxis returns the derivative of the point along the azimuth U around the local Y axis.
+func aroundAxis(point mathutils.Vector) mathutils.Vector {
+	return mathutils.NewVector(-2*math.Pi*point.Z, 0, 2*math.Pi*point.X)
+}
+
+// awayFromAxis returns the unit vector from the local Y axis to the point, perpendicular to the axis.
+func awayFromAxis(point mathutils.Vector) mathutils.Vector {
+	radial := mathutils.NewVector(point.X, 0, point.Z)
+	if radial.LengthSqr() == 0 {
+		return mathutils.NewVector(1, 0, 0)
+	}
+	radial.Normalize()
+	return radial
+}
+
+// azimuth returns the angle of the point around the local Y axis mapped to [0, 1).
+func azimuth(point mathutils.Vector) float64 {
+	angle := math.Atan2(point.Z, point.X)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+
+	return angle / (2 * math.Pi)
+}
+
+// Cylinder defines a cylinder, optionally closed with caps, in the 3-dimentional space.
+type Cylinder struct {
+	frame     localFrame // The frame with the axis of the cylinder as Y axis.
+	radius    float64    // The radius of the cylinder.
+	height    float64    // The height of the cylinder.
+	bottomCap bool       // Whether the cylinder is closed at its bottom.
+	topCap    bool       // Whether the cylinder is closed at its top.
+}
+
+// NewCylinder creates and returns a new cylinder centered at center and oriented along axis,
+// closed with a cap at the bottom and at the top if requested.
+func NewCylinder(center, axis mathutils.Vector, radius, height float64, bottomCap, topCap bool) Cylinder {
+	return Cylinder{newLocalFrame(center, axis), radius, height, bottomCap, topCap}
+}
+
+// hits returns all the hits of the ray with the cylinder, including the ones behind the ray start.
+func (c *Cylinder) hits(ray *Ray) []localHit {
+	start, direction := c.frame.toLocal(ray)
+	var hits []localHit
+
+	roots := mathutils.SolveQuadratic(direction.X*direction.X+direction.Z*direction.Z,
+		2*(start.X*direction.X+start.Z*direction.Z),
+		start.X*start.X+start.Z*start.Z-c.radius*c.radius)
+	for _, distance := range roots {
+		point := mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, distance))
+		if math.Abs(point.Y) > c.height/2 {
+			continue
+		}
+		hits = append(hits, localHit{distance, mathutils.NewVector(point.X, 0, point.Z), azimuth(point), point.Y/c.height + 0.5, mathutils.NewVector(0, c.height, 0), point})
+	}
+
+	hits = append(hits, intersectCaps(start, direction, c.height/2, c.radius, c.radius, c.bottomCap, c.topCap)...)
+
+	return hits
+}
+
+// intersectCaps returns the hits with the caps at -level and +level with the given radii,
+// for the caps which close the ends.
+func intersectCaps(start, direction mathutils.Vector, level, bottomRadius, topRadius float64, bottomCap, topCap bool) []localHit {
+	var hits []localHit
+	if math.Abs(direction.Y) < 1e-12 {
+		return hits
+	}
+
+	for _, side := range []float64{-1, 1} {
+		radius, closed := bottomRadius, bottomCap
+		if side > 0 {
+			radius, closed = topRadius, topCap
+		}
+		if !closed {
+			continue
+		}
+
+		distance := (side*level - start.Y) / direction.Y
+		point := mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, distance))
+		distanceFromAxis := math.Sqrt(point.X*point.X + point.Z*point.Z)
+		if distanceFromAxis > radius {
+			continue
+		}
+		hits = append(hits, localHit{distance, mathutils.NewVector(0, side, 0), azimuth(point), distanceFromAxis / radius, mathutils.VectorMultiply(awayFromAxis(point), radius), point})
+	}
+
+	return hits
+}
+
+// Intersect implements the intersect method of the Geometry interface for Cylinder.
+func (c *Cylinder) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	hit, ok := closest(c.hits(ray))
+	if !ok {
+		return false
+	}
+
+	hit.fill(ray, &c.frame, info)
+	return true
+}
+
+// IntersectAll implements the IntersectAll method of the Solid interface for Cylinder.
+func (c *Cylinder) IntersectAll(ray *Ray) []IntersectionInfo {
+	return allHits(ray, &c.frame, c.hits(ray))
+}
+
+// BoundingBox implements the Bounded interface for Cylinder.
+func (c *Cylinder) BoundingBox() mathutils.BoundingBox {
+	return c.frame.boundingBox(c.height/2, c.radius, 0)
+}
+
+// Cone defines a cone or a truncated cone, optionally closed with caps, in the 3-dimentional space.
+type Cone struct {
+	frame     localFrame // The frame with the axis of the cone as Y axis.
+	radius    float64    // The radius at the bottom of the cone.
+	topRadius float64    // The radius at the top of the cone, zero for a pointed cone.
+	height    float64    // The height of the cone.
+	bottomCap bool       // Whether the cone is closed at its bottom.
+	topCap    bool       // Whether the cone is closed at its top.
+}
+
+// NewCone creates and returns a new cone centered at center and pointing along axis,
+// closed with a cap at the bottom and at the top if requested.
+func NewCone(center, axis mathutils.Vector, radius, topRadius, height float64, bottomCap, topCap bool) Cone {
+	return Cone{newLocalFrame(center, axis), radius, topRadius, height, bottomCap, topCap}
+}
+
+// hits returns all the hits of the ray with the cone, including the ones behind the ray start.
+func (c *Cone) hits(ray *Ray) []localHit {
+	start, direction := c.frame.toLocal(ray)
+	var hits []localHit
+
+	// The radius at height y is r0 + k*y, measured from the bottom of the cone.
+	slope := (c.topRadius - c.radius) / c.height
+	startY := start.Y + c.height/2
+	startRadius := c.radius + slope*startY
+	roots := mathutils.SolveQuadratic(direction.X*direction.X+direction.Z*direction.Z-slope*slope*direction.Y*direction.Y,
+		2*(start.X*direction.X+start.Z*direction.Z-slope*startRadius*direction.Y),
+		start.X*start.X+start.Z*start.Z-startRadius*startRadius)
+	for _, distance := range roots {
+		point := mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, distance))
+		if math.Abs(point.Y) > c.height/2 {
+			continue
+		}
+		radius := c.radius + slope*(point.Y+c.height/2)
+		dPdv := mathutils.VectorMultiply(awayFromAxis(point), slope*c.height)
+		dPdv.Y = c.height
+		hits = append(hits, localHit{distance, mathutils.NewVector(point.X, -slope*radius, point.Z), azimuth(point), point.Y/c.height + 0.5, dPdv, point})
+	}
+
+	hits = append(hits, intersectCaps(start, direction, c.height/2, c.radius, c.topRadius, c.bottomCap, c.topCap)...)
+
+	return hits
+}
+
+// Intersect implements the intersect method of the Geometry interface for Cone.
+func (c *Cone) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	hit, ok := closest(c.hits(ray))
+	if !ok {
+		return false
+	}
+
+	hit.fill(ray, &c.frame, info)
+	return true
+}
+
+// IntersectAll implements the IntersectAll method of the Solid interface for Cone.
+func (c *Cone) IntersectAll(ray *Ray) []IntersectionInfo {
+	return allHits(ray, &c.frame, c.hits(ray))
+}
+
+// BoundingBox implements the Bounded interface for Cone.
+func (c *Cone) BoundingBox() mathutils.BoundingBox {
+	return c.frame.boundingBox(c.height/2, math.Max(c.radius, c.topRadius), 0)
+}
+
+// Capsule defines a cylinder closed with two hemispheres in the 3-dimentional space.
+type Capsule struct {
+	frame  localFrame // The frame with the axis of the capsule as Y axis.
+	radius float64    // The radius of the capsule.
+	height float64    // The height of the cylindrical part of the capsule.
+}
+
+// NewCapsule creates and returns a new capsule centered at center and oriented along axis.
+func NewCapsule(center, axis mathutils.Vector, radius, height float64) Capsule {
+	return Capsule{newLocalFrame(center, axis), radius, height}
+}
+
+// hits returns all the hits of the ray with the capsule, including the ones behind the ray start.
+func (c *Capsule) hits(ray *Ray) []localHit {
+	start, direction := c.frame.toLocal(ray)
+	var hits []localHit
+
+	// V goes along the profile of the capsule from the bottom to the top pole.
+	profileLength := c.height + math.Pi*c.radius
+	roots := mathutils.SolveQuadratic(direction.X*direction.X+direction.Z*direction.Z,
+		2*(start.X*direction.X+start.Z*direction.Z),
+		start.X*start.X+start.Z*start.Z-c.radius*c.radius)
+	for _, distance := range roots {
+		point := mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, distance))
+		if math.Abs(point.Y) > c.height/2 {
+			continue
+		}
+		v := (math.Pi/2*c.radius + point.Y + c.height/2) / profileLength
+		hits = append(hits, localHit{distance, mathutils.NewVector(point.X, 0, point.Z), azimuth(point), v, mathutils.NewVector(0, profileLength, 0), point})
+	}
+
+	for _, side := range []float64{-1, 1} {
+		center := mathutils.NewVector(0, side*c.height/2, 0)
+		relativeStart := mathutils.VectorSubstraction(start, center)
+		roots = mathutils.SolveQuadratic(direction.LengthSqr(), 2*mathutils.DotProduct(relativeStart, direction), relativeStart.LengthSqr()-c.radius*c.radius)
+		for _, distance := range roots {
+			point := mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, distance))
+			if side*point.Y < c.height/2 {
+				continue
+			}
+			normal := mathutils.VectorSubstraction(point, center)
+			angle := math.Asin(math.Max(-1, math.Min(1, normal.Y/c.radius)))
+			v := (c.radius*(angle+math.Pi/2) + math.Max(0, side)*c.height) / profileLength
+			dPdv := mathutils.VectorMultiply(awayFromAxis(point), -math.Sin(angle)*profileLength)
+			dPdv.Y = math.Cos(angle) * profileLength
+			hits = append(hits, localHit{distance, normal, azimuth(point), v, dPdv, point})
+		}
+	}
+
+	return hits
+}
+
+// Intersect implements the intersect method of the Geometry interface for Capsule.
+func (c *Capsule) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	hit, ok := closest(c.hits(ray))
+	if !ok {
+		return false
+	}
+
+	hit.fill(ray, &c.frame, info)
+	return true
+}
+
+// IntersectAll implements the IntersectAll method of the Solid interface for Capsule.
+func (c *Capsule) IntersectAll(ray *Ray) []IntersectionInfo {
+	return allHits(ray, &c.frame, c.hits(ray))
+}
+
+// BoundingBox implements the Bounded interface for Capsule.
+func (c *Capsule) BoundingBox() mathutils.BoundingBox {
+	return c.frame.boundingBox(c.height/2, 0, c.radius)
+}
+
+// Torus defines a ring shaped torus in the 3-dimentional space.
+type Torus struct {
+	frame       localFrame // The frame with the axis of the torus as Y axis.
+	majorRadius float64    // The distance from the center to the middle of the tube.
+	minorRadius float64    // The radius of the tube.
+}
+
+// NewTorus creates and returns a new torus centered at center and lying around axis.
+func NewTorus(center, axis mathutils.Vector, majorRadius, minorRadius float64) Torus {
+	return Torus{newLocalFrame(center, axis), majorRadius, minorRadius}
+}
+
+// hits returns all the hits of the ray with the torus, including the ones behind the ray start.
+func (t *Torus) hits(ray *Ray) []localHit {
+	start, direction := t.frame.toLocal(ray)
+	var hits []localHit
+
+	// Move the start next to the torus to keep the quartic well conditioned.
+	directionSqr := direction.LengthSqr()
+	shift := -mathutils.DotProduct(start, direction)/directionSqr - (t.majorRadius+t.minorRadius)/math.Sqrt(directionSqr)
+	start = mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, shift))
+
+	majorSqr := t.majorRadius * t.majorRadius
+	startDirection := mathutils.DotProduct(start, direction)
+	startSqr := start.LengthSqr()
+	k := startSqr + majorSqr - t.minorRadius*t.minorRadius
+	roots := mathutils.SolveQuartic(directionSqr*directionSqr,
+		4*directionSqr*startDirection,
+		4*startDirection*startDirection+2*directionSqr*k-4*majorSqr*(directionSqr-direction.Y*direction.Y),
+		4*startDirection*k-8*majorSqr*(startDirection-start.Y*direction.Y),
+		k*k-4*majorSqr*(startSqr-start.Y*start.Y))
+
+	for _, distance := range roots {
+		distance = t.refine(start, direction, distance)
+		point := mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, distance))
+		g := point.LengthSqr() + majorSqr - t.minorRadius*t.minorRadius
+		normal := mathutils.NewVector(point.X*(g-2*majorSqr), point.Y*g, point.Z*(g-2*majorSqr))
+
+		radial := mathutils.NewVector(point.X, 0, point.Z)
+		radial.Normalize()
+		tube := mathutils.VectorSubstraction(point, mathutils.VectorMultiply(radial, t.majorRadius))
+		angle := math.Atan2(tube.Y, mathutils.DotProduct(tube, radial))
+		if angle < 0 {
+			angle += 2 * math.Pi
+		}
+		dPdv := mathutils.VectorMultiply(radial, -2*math.Pi*tube.Y)
+		dPdv.Y = 2 * math.Pi * mathutils.DotProduct(tube, radial)
+		hits = append(hits, localHit{distance + shift, normal, azimuth(point), angle / (2 * math.Pi), dPdv, point})
+	}
+
+	return hits
+}
+
+// refine improves a root of the torus equation with a few Newton iterations.
+func (t *Torus) refine(start, direction mathutils.Vector, distance float64) float64 {
+	majorSqr := t.majorRadius * t.majorRadius
+	for i := 0; i < 3; i++ {
+		point := mathutils.VectorAddition(start, mathutils.VectorMultiply(direction, distance))
+		g := point.LengthSqr() + majorSqr - t.minorRadius*t.minorRadius
+		value := g*g - 4*majorSqr*(point.X*point.X+point.Z*point.Z)
+		gradient := mathutils.NewVector(4*point.X*(g-2*majorSqr), 4*point.Y*g, 4*point.Z*(g-2*majorSqr))
+		derivative := mathutils.DotProduct(gradient, direction)
+		if math.Abs(derivative) < 1e-12 {
+			break
+		}
+		distance -= value / derivative
+	}
+
+	return distance
+}
+
+// Intersect implements the intersect method of the Geometry interface for Torus.
+func (t *Torus) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	hit, ok := closest(t.hits(ray))
+	if !ok {
+		return false
+	}
+
+	hit.fill(ray, &t.frame, info)
+	return true
+}
+
+// IntersectAll implements the IntersectAll method of the Solid interface for Torus.
+func (t *Torus) IntersectAll(ray *Ray) []IntersectionInfo {
+	return allHits(ray, &t.frame, t.hits(ray))
+}
+
+// BoundingBox implements the Bounded interface for Torus.
+func (t *Torus) BoundingBox() mathutils.BoundingBox {
+	return t.frame.boundingBox(0, t.majorRadius, t.minorRadius)
+}
@@ -0,0 +1,305 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"testing"
+)
+
+func compareVectors(lhs, rhs mathutils.Vector) bool {
+	return math.Abs(lhs.X-rhs.X) < 1e-6 && math.Abs(lhs.Y-rhs.Y) < 1e-6 && math.Abs(lhs.Z-rhs.Z) < 1e-6
+}
+
+func TestPlaneIntersect(t *testing.T) {
+	// The axis-aligned planes use the world coordinates as U and V.
+	plane := NewPlane(mathutils.NewVector(10, 0, 20), 8, XZ)
+	ray := NewRay(mathutils.NewVector(11, 5, 22), mathutils.NewVector(0, -1, 0))
+	var info IntersectionInfo
+	if !plane.Intersect(&ray, &info) || math.Abs(info.Distance-5) > 1e-9 || info.U != 11 || info.V != 22 ||
+		!compareVectors(info.Normal, mathutils.NewVector(0, 1, 0)) {
+		t.Errorf("Plane.Intersect() failed!")
+	}
+	ray = NewRay(mathutils.NewVector(15, 5, 20), mathutils.NewVector(0, -1, 0))
+	if plane.Intersect(&ray, &info) {
+		t.Errorf("Plane.Intersect() failed!")
+	}
+	ray = NewRay(mathutils.NewVector(11, 5, 22), mathutils.NewVector(0, 1, 0))
+	if plane.Intersect(&ray, &info) {
+		t.Errorf("Plane.Intersect() failed!")
+	}
+
+	// The oriented planes start U and V at the center and face the ray.
+	plane = NewOrientedPlane(mathutils.NewVector(0, 0, 5), mathutils.NewVector(0, 0, 2), 0, 0)
+	ray = NewRay(mathutils.NewVector(100, -50, 10), mathutils.NewVector(0, 0, -1))
+	if !plane.Intersect(&ray, &info) || math.Abs(info.Distance-5) > 1e-9 ||
+		math.Abs(info.U*info.U+info.V*info.V-12500) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 0, 1)) {
+		t.Errorf("Plane.Intersect() failed!")
+	}
+	ray = NewRay(mathutils.NewVector(0, 0, 10), mathutils.NewVector(1, 0, 0))
+	if plane.Intersect(&ray, &info) {
+		t.Errorf("Plane.Intersect() failed!")
+	}
+}
+
+func TestDiscIntersect(t *testing.T) {
+	disc, err := NewDisc(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 1)
+	if err != nil {
+		t.Fatalf("NewDisc() failed!")
+	}
+
+	// V goes from the outer edge to the hole.
+	ray := NewRay(mathutils.NewVector(1.5, 3, 0), mathutils.NewVector(0, -1, 0))
+	var info IntersectionInfo
+	if !disc.Intersect(&ray, &info) || math.Abs(info.Distance-3) > 1e-9 || math.Abs(info.V-0.5) > 1e-9 ||
+		!compareVectors(info.Normal, mathutils.NewVector(0, 1, 0)) {
+		t.Errorf("Disc.Intersect() failed!")
+	}
+	for _, x := range []float64{0.5, 2.5} {
+		ray = NewRay(mathutils.NewVector(x, 3, 0), mathutils.NewVector(0, -1, 0))
+		if disc.Intersect(&ray, &info) {
+			t.Errorf("Disc.Intersect() failed!")
+		}
+	}
+
+	if _, err := NewDisc(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 2); err == nil {
+		t.Errorf("NewDisc() failed!")
+	}
+	if _, err := NewDisc(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 3); err == nil {
+		t.Errorf("NewDisc() failed!")
+	}
+}
+
+func TestQuadIntersect(t *testing.T) {
+	quad, err := NewQuad(mathutils.NewVector(0, 0, 0), mathutils.NewVector(2, 0, 0), mathutils.NewVector(1, 0, 4))
+	if err != nil {
+		t.Fatalf("NewQuad() failed!")
+	}
+
+	// U and V follow the edges of the parallelogram.
+	ray := NewRay(mathutils.NewVector(2, -3, 2), mathutils.NewVector(0, 1, 0))
+	var info IntersectionInfo
+	if !quad.Intersect(&ray, &info) || math.Abs(info.Distance-3) > 1e-9 || math.Abs(info.U-0.75) > 1e-9 ||
+		math.Abs(info.V-0.5) > 1e-9 || !compareVectors(info.Normal, mathutils.NewVector(0, -1, 0)) {
+		t.Errorf("Quad.Intersect() failed!")
+	}
+	ray = NewRay(mathutils.NewVector(-0.5, -3, 2), mathutils.NewVector(0, 1, 0))
+	if quad.Intersect(&ray, &info) {
+		t.Errorf("Quad.Intersect() failed!")
+	}
+
+	if _, err := NewQuad(mathutils.NewVector(0, 0, 0), mathutils.NewVector(2, 0, 0), mathutils.NewVector(-4, 0, 0)); err == nil {
+		t.Errorf("NewQuad() failed!")
+	}
+	if _, err := NewQuad(mathutils.NewVector(0, 0, 0), mathutils.NewVector(2, 0, 0), mathutils.NewVector(0, 0, 0)); err == nil {
+		t.Errorf("NewQuad() failed!")
+	}
+}
+
+func TestCylinderIntersect(t *testing.T) {
+	cylinder := NewCylinder(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 4, true, true)
+	var info IntersectionInfo
+
+	ray := NewRay(mathutils.NewVector(0, 0, -10), mathutils.NewVector(0, 0, 1))
+	if !cylinder.Intersect(&ray, &info) || math.Abs(info.Distance-8) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 0, -1)) {
+		t.Errorf("Cylinder.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 10, 0), mathutils.NewVector(0, -1, 0))
+	if !cylinder.Intersect(&ray, &info) || math.Abs(info.Distance-8) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 1, 0)) {
+		t.Errorf("Cylinder.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 0, 0), mathutils.NewVector(1, 0, 0))
+	if !cylinder.Intersect(&ray, &info) || math.Abs(info.Distance-2) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(1, 0, 0)) {
+		t.Errorf("Cylinder.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 3, -10), mathutils.NewVector(0, 0, 1))
+	if cylinder.Intersect(&ray, &info) {
+		t.Errorf("Cylinder.Intersect() failed!")
+	}
+
+	open := NewCylinder(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 4, false, false)
+	ray = NewRay(mathutils.NewVector(0, 10, 0), mathutils.NewVector(0, -1, 0))
+	if open.Intersect(&ray, &info) {
+		t.Errorf("Cylinder.Intersect() failed!")
+	}
+
+	// A cup closed only at the bottom is seen from above through its open top.
+	cup := NewCylinder(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 4, true, false)
+	if !cup.Intersect(&ray, &info) || math.Abs(info.Distance-12) > 1e-6 {
+		t.Errorf("Cylinder.Intersect() failed!")
+	}
+}
+
+func TestConeIntersect(t *testing.T) {
+	cone := NewCone(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 0, 4, true, true)
+	var info IntersectionInfo
+
+	ray := NewRay(mathutils.NewVector(0, 0, -10), mathutils.NewVector(0, 0, 1))
+	if !cone.Intersect(&ray, &info) || math.Abs(info.Distance-9) > 1e-6 {
+		t.Errorf("Cone.Intersect() failed!")
+	}
+
+	expectedNormal := mathutils.NewVector(0, 1, -2)
+	expectedNormal.Normalize()
+	if !compareVectors(info.Normal, expectedNormal) {
+		t.Errorf("Cone.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, -10, 0), mathutils.NewVector(0, 1, 0))
+	if !cone.Intersect(&ray, &info) || math.Abs(info.Distance-8) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, -1, 0)) {
+		t.Errorf("Cone.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0))
+	if !cone.Intersect(&ray, &info) || math.Abs(info.Distance-2) > 1e-6 {
+		t.Errorf("Cone.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 1.5, -10), mathutils.NewVector(1, 0, 0))
+	if cone.Intersect(&ray, &info) {
+		t.Errorf("Cone.Intersect() failed!")
+	}
+
+	// A funnel open at the top lets a ray down the axis through to the bottom cap.
+	funnel := NewCone(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 1, 2, 4, true, false)
+	ray = NewRay(mathutils.NewVector(0, 10, 0), mathutils.NewVector(0, -1, 0))
+	if !funnel.Intersect(&ray, &info) || math.Abs(info.Distance-12) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, -1, 0)) {
+		t.Errorf("Cone.Intersect() failed!")
+	}
+}
+
+func TestCapsuleIntersect(t *testing.T) {
+	capsule := NewCapsule(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 1, 4)
+	var info IntersectionInfo
+
+	ray := NewRay(mathutils.NewVector(0, 10, 0), mathutils.NewVector(0, -1, 0))
+	if !capsule.Intersect(&ray, &info) || math.Abs(info.Distance-7) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 1, 0)) {
+		t.Errorf("Capsule.Intersect() failed!")
+	}
+
+	if math.Abs(info.V-1) > 1e-6 {
+		t.Errorf("Capsule.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(-10, 1, 0), mathutils.NewVector(1, 0, 0))
+	if !capsule.Intersect(&ray, &info) || math.Abs(info.Distance-9) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(-1, 0, 0)) {
+		t.Errorf("Capsule.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, -1, 0))
+	if !capsule.Intersect(&ray, &info) || math.Abs(info.Distance-3) > 1e-6 {
+		t.Errorf("Capsule.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(-10, 2.9, 0.9), mathutils.NewVector(1, 0, 0))
+	if capsule.Intersect(&ray, &info) {
+		t.Errorf("Capsule.Intersect() failed!")
+	}
+}
+
+func TestTorusIntersect(t *testing.T) {
+	torus := NewTorus(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 3, 1)
+	var info IntersectionInfo
+
+	ray := NewRay(mathutils.NewVector(-10, 0, 0), mathutils.NewVector(1, 0, 0))
+	if !torus.Intersect(&ray, &info) || math.Abs(info.Distance-6) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(-1, 0, 0)) {
+		t.Errorf("Torus.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 0, 0), mathutils.NewVector(1, 0, 0))
+	if !torus.Intersect(&ray, &info) || math.Abs(info.Distance-2) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(-1, 0, 0)) {
+		t.Errorf("Torus.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(3, 10, 0), mathutils.NewVector(0, -1, 0))
+	if !torus.Intersect(&ray, &info) || math.Abs(info.Distance-9) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 1, 0)) {
+		t.Errorf("Torus.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 10, 0), mathutils.NewVector(0, -1, 0))
+	if torus.Intersect(&ray, &info) {
+		t.Errorf("Torus.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(3, 0, 0), mathutils.NewVector(0, 1, 0))
+	if !torus.Intersect(&ray, &info) || math.Abs(info.Distance-1) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 1, 0)) {
+		t.Errorf("Torus.Intersect() failed!")
+	}
+}
+
+func TestComputeDifferentials(t *testing.T) {
+	// The U and V changes estimated from DPdu and DPdv have to match the hits of slightly offset rays.
+	check := func(geometry Geometry, start, direction mathutils.Vector) bool {
+		direction.Normalize()
+		offsetX, offsetY := mathutils.OrthonormalBasis(direction)
+		ray := NewRay(start, direction)
+		ray.HasDifferentials = true
+		ray.XStart, ray.XDirection = start, mathutils.VectorAddition(direction, mathutils.VectorMultiply(offsetX, 1e-5))
+		ray.YStart, ray.YDirection = start, mathutils.VectorAddition(direction, mathutils.VectorMultiply(offsetY, 1e-5))
+
+		var info, infoX, infoY IntersectionInfo
+		rayX, rayY := NewRay(ray.XStart, ray.XDirection), NewRay(ray.YStart, ray.YDirection)
+		if !geometry.Intersect(&ray, &info) || !geometry.Intersect(&rayX, &infoX) || !geometry.Intersect(&rayY, &infoY) {
+			return false
+		}
+		info.computeDifferentials(&ray)
+
+		near := func(estimate, actual float64) bool {
+			return math.Abs(estimate-actual) <= 1e-9+1e-2*math.Abs(actual)
+		}
+		return (info.DUdx != 0 || info.DVdx != 0) && near(info.DUdx, infoX.U-info.U) && near(info.DVdx, infoX.V-info.V) &&
+			near(info.DUdy, infoY.U-info.U) && near(info.DVdy, infoY.V-info.V)
+	}
+
+	down := mathutils.NewVector(0.1, -1, 0.2)
+	forward := mathutils.NewVector(0.1, 0.2, 1)
+	plane := NewOrientedPlane(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 0, 0)
+	disc, _ := NewDisc(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 0.5)
+	quad, _ := NewQuad(mathutils.NewVector(0, 0, 0), mathutils.NewVector(2, 0, 0), mathutils.NewVector(0, 0, 3))
+	sphere := NewSphere(mathutils.NewVector(0, 0, 0), 2)
+	cube := NewCube(mathutils.NewVector(0, 0, 0), 2)
+	cylinder := NewCylinder(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 4, true, true)
+	cone := NewCone(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 2, 0.5, 4, true, true)
+	capsule := NewCapsule(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 1, 2)
+	torus := NewTorus(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 1, 0), 3, 1)
+	mesh := NewMesh(MeshData{
+		Vertices: []mathutils.Vector{mathutils.NewVector(0, 0, 0), mathutils.NewVector(4, 0, 0), mathutils.NewVector(0, 0, 4)},
+		UVs:      [][2]float64{{0, 0}, {0, 2}, {3, 0}},
+		Polygons: [][]int{{0, 1, 2}},
+	})
+
+	cases := []struct {
+		name             string
+		geometry         Geometry
+		start, direction mathutils.Vector
+	}{
+		{"Plane", &plane, mathutils.NewVector(0.5, 3, 0.5), down},
+		{"Disc", &disc, mathutils.NewVector(1, 3, 0.3), down},
+		{"Quad", &quad, mathutils.NewVector(0.5, 1, 0.7), down},
+		{"Sphere", &sphere, mathutils.NewVector(0.3, 0.5, -5), forward},
+		{"Cube", &cube, mathutils.NewVector(0.3, -0.6, -5), forward},
+		{"Cube", &cube, mathutils.NewVector(0.3, 5, -0.6), down},
+		{"Cylinder", &cylinder, mathutils.NewVector(0.3, 0.5, -5), forward},
+		{"Cylinder", &cylinder, mathutils.NewVector(0.3, 5, 0.4), down},
+		{"Cone", &cone, mathutils.NewVector(0.3, -1.5, -5), forward},
+		{"Capsule", &capsule, mathutils.NewVector(0.3, 0.5, -5), forward},
+		{"Torus", &torus, mathutils.NewVector(2, -0.5, -8), forward},
+		{"Mesh", &mesh, mathutils.NewVector(1, 3, 1), down},
+	}
+	for _, c := range cases {
+		if !check(c.geometry, c.start, c.direction) {
+			t.Errorf("%s derivatives failed!", c.name)
+		}
+	}
+
+	// Without differentials the footprint stays empty.
+	var info IntersectionInfo
+	ray := NewRay(mathutils.NewVector(0.3, 0.5, -5), mathutils.NewVector(0, 0, 1))
+	sphere.Intersect(&ray, &info)
+	info.computeDifferentials(&ray)
+	if info.DUdx != 0 || info.DVdx != 0 || info.DUdy != 0 || info.DVdy != 0 {
+		t.Errorf("IntersectionInfo.computeDifferentials() failed!")
+	}
+}
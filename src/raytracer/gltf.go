@@ -0,0 +1,762 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GLTFScene holds the content of a glTF 2.0 file converted to the types of the raytracer.
+// glTF uses right-handed coordinates, the raytracer left-handed ones, so Z is mirrored.
+type GLTFScene struct {
+	Root    Node             // A node holding the whole node hierarchy.
+	Cameras []ParallelCamera // The perspective cameras in the order they are found in the node hierarchy.
+	Lights  []Light          // The point lights.
+
+	SpotLights        []SpotLight        // The spot lights, fading out between the angles of their cones.
+	DirectionalLights []DirectionalLight // The directional lights, with hard shadows.
+}
+
+// gltfDocument holds the parts of the glTF JSON used by the importer.
+type gltfDocument struct {
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+	Materials   []gltfMaterial   `json:"materials"`
+	Cameras     []gltfCamera     `json:"cameras"`
+	Extensions  struct {
+		LightsPunctual struct {
+			Lights []gltfLight `json:"lights"`
+		} `json:"KHR_lights_punctual"`
+	} `json:"extensions"`
+}
+
+type gltfNode struct {
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Camera      *int      `json:"camera"`
+	Matrix      []float64 `json:"matrix"`
+	Translation []float64 `json:"translation"`
+	Rotation    []float64 `json:"rotation"`
+	Scale       []float64 `json:"scale"`
+	Extensions  struct {
+		LightsPunctual *struct {
+			Light int `json:"light"`
+		} `json:"KHR_lights_punctual"`
+	} `json:"extensions"`
+}
+
+type gltfMesh struct {
+	Primitives []struct {
+		Attributes map[string]int `json:"attributes"`
+		Indices    *int           `json:"indices"`
+		Material   *int           `json:"material"`
+		Mode       *int           `json:"mode"`
+	} `json:"primitives"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type gltfBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type gltfMaterial struct {
+	PBRMetallicRoughness struct {
+		BaseColorFactor []float64 `json:"baseColorFactor"`
+		MetallicFactor  *float64  `json:"metallicFactor"`
+		RoughnessFactor *float64  `json:"roughnessFactor"`
+	} `json:"pbrMetallicRoughness"`
+}
+
+type gltfCamera struct {
+	Type        string `json:"type"`
+	Perspective *struct {
+		AspectRatio float64 `json:"aspectRatio"`
+		Yfov        float64 `json:"yfov"`
+	} `json:"perspective"`
+}
+
+type gltfLight struct {
+	Type      string    `json:"type"`
+	Color     []float64 `json:"color"`
+	Intensity *float64  `json:"intensity"`
+	Spot      struct {
+		InnerConeAngle float64  `json:"innerConeAngle"`
+		OuterConeAngle *float64 `json:"outerConeAngle"`
+	} `json:"spot"`
+}
+
+// glTF primitive modes and accessor component types.
+const (
+	gltfTriangles     = 4
+	gltfTriangleStrip = 5
+	gltfTriangleFan   = 6
+
+	gltfByte          = 5120
+	gltfUnsignedByte  = 5121
+	gltfShort         = 5122
+	gltfUnsignedShort = 5123
+	gltfUnsignedInt   = 5125
+	gltfFloat         = 5126
+)
+
+// gltfLoader holds the state of the conversion of a glTF document.
+type gltfLoader struct {
+	document  gltfDocument
+	directory string      // The directory of the glTF file, external buffers are relative to it.
+	buffers   [][]byte    // The content of the buffers.
+	meshes    []*Geometry // The converted meshes, shared by all nodes using them.
+	shaders   []*Shader   // The converted materials.
+	fallback  *Shader     // The shader of primitives without a material.
+	result    GLTFScene
+}
+
+// ReadGLTF reads a glTF 2.0 file, either JSON (.gltf) with external or embedded buffers, or binary (.glb).
+// It imports the triangle meshes, the node transformations, the perspective cameras,
+// the point and spot lights of KHR_lights_punctual and the metallic-roughness material factors.
+// The materials become Phong shaders and the vertex colors are used through a VertexColor texture.
+func ReadGLTF(path string) (scene GLTFScene, err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	loader := gltfLoader{directory: filepath.Dir(path)}
+	var binaryChunk []byte
+	jsonChunk := content
+	if bytes.HasPrefix(content, []byte("glTF")) {
+		jsonChunk, binaryChunk, err = splitGLB(content)
+		if err != nil {
+			return
+		}
+	}
+
+	err = json.Unmarshal(jsonChunk, &loader.document)
+	if err != nil {
+		err = fmt.Errorf("glTF: %v", err)
+		return
+	}
+
+	err = loader.loadBuffers(binaryChunk)
+	if err != nil {
+		return
+	}
+
+	err = loader.convert()
+	return loader.result, err
+}
+
+// splitGLB returns the JSON and binary chunks of a binary glTF file.
+func splitGLB(content []byte) (jsonChunk, binaryChunk []byte, err error) {
+	if len(content) < 20 || binary.LittleEndian.Uint32(content[4:8]) != 2 {
+		err = fmt.Errorf("glTF: unsupported binary container")
+		return
+	}
+
+	for offset := 12; offset+8 <= len(content); {
+		length := int(binary.LittleEndian.Uint32(content[offset : offset+4]))
+		chunkType := string(content[offset+4 : offset+8])
+		if offset+8+length > len(content) {
+			err = fmt.Errorf("glTF: truncated binary chunk")
+			return
+		}
+
+		chunk := content[offset+8 : offset+8+length]
+		switch chunkType {
+		case "JSON":
+			jsonChunk = chunk
+		case "BIN\x00":
+			binaryChunk = chunk
+		}
+		offset += 8 + (length+3)/4*4
+	}
+
+	if jsonChunk == nil {
+		err = fmt.Errorf("glTF: binary file without JSON chunk")
+	}
+	return
+}
+
+// loadBuffers loads the buffers from the binary chunk, data URIs or files next to the glTF file.
+func (l *gltfLoader) loadBuffers(binaryChunk []byte) error {
+	for i, buffer := range l.document.Buffers {
+		var data []byte
+		var err error
+		switch {
+		case buffer.URI == "":
+			if binaryChunk == nil {
+				return fmt.Errorf("glTF buffer %d has no data", i)
+			}
+			data = binaryChunk
+		case strings.HasPrefix(buffer.URI, "data:"):
+			comma := strings.Index(buffer.URI, ",")
+			if comma < 0 || !strings.HasSuffix(buffer.URI[:comma], ";base64") {
+				return fmt.Errorf("glTF buffer %d has an unsupported data URI", i)
+			}
+			data, err = base64.StdEncoding.DecodeString(buffer.URI[comma+1:])
+		default:
+			var path string
+			path, err = url.PathUnescape(buffer.URI)
+			if err == nil {
+				data, err = os.ReadFile(filepath.Join(l.directory, path))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("glTF buffer %d: %v", i, err)
+		}
+
+		if len(data) < buffer.ByteLength {
+			return fmt.Errorf("glTF buffer %d has %d bytes, expected %d", i, len(data), buffer.ByteLength)
+		}
+		l.buffers = append(l.buffers, data)
+	}
+
+	return nil
+}
+
+// convert converts the default scene, or all root nodes if there is no scene.
+func (l *gltfLoader) convert() error {
+	var fallback Shader = &Phong{color: utils.Color{0.8, 0.8, 0.8}, specularMultiplier: 0.04, specularExponent: 1}
+	l.fallback = &fallback
+	for _, material := range l.document.Materials {
+		shader := material.toShader(false)
+		l.shaders = append(l.shaders, &shader)
+	}
+	l.meshes = make([]*Geometry, len(l.document.Meshes))
+
+	var roots []int
+	switch {
+	case len(l.document.Scenes) > 0:
+		scene := 0
+		if l.document.Scene != nil {
+			scene = *l.document.Scene
+		}
+		if scene < 0 || scene >= len(l.document.Scenes) {
+			return fmt.Errorf("glTF scene %d does not exist", scene)
+		}
+		roots = l.document.Scenes[scene].Nodes
+	default:
+		isChild := make([]bool, len(l.document.Nodes))
+		for _, node := range l.document.Nodes {
+			for _, child := range node.Children {
+				if child >= 0 && child < len(isChild) {
+					isChild[child] = true
+				}
+			}
+		}
+		for i := range l.document.Nodes {
+			if !isChild[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+
+	// The mirroring along Z turns the right-handed glTF space into the left-handed one of the raytracer.
+	mirror := mathutils.ScalingTransform(mathutils.NewVector(1, 1, -1))
+	var nodes []Node
+	for _, index := range roots {
+		node, err := l.convertNode(index, mirror, 0)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, node)
+	}
+
+	group := NewGroup(nodes)
+	l.result.Root.SetGeometry(&group)
+	l.result.Root.SetTransform(mirror)
+	return nil
+}
+
+// convertNode converts a glTF node and its children. The parent transformation goes from the parent space to the raytracer world.
+func (l *gltfLoader) convertNode(index int, parent mathutils.Transform, depth int) (node Node, err error) {
+	if index < 0 || index >= len(l.document.Nodes) {
+		err = fmt.Errorf("glTF node %d does not exist", index)
+		return
+	}
+	if depth > len(l.document.Nodes) {
+		err = fmt.Errorf("glTF node hierarchy has a cycle")
+		return
+	}
+
+	gltfNode := &l.document.Nodes[index]
+	local, err := gltfNode.localTransform()
+	if err != nil {
+		err = fmt.Errorf("glTF node %d: %v", index, err)
+		return
+	}
+	world := mathutils.TransformMultiplication(local, parent)
+
+	var children []Node
+	if gltfNode.Mesh != nil {
+		var mesh *Geometry
+		mesh, err = l.convertMesh(*gltfNode.Mesh)
+		if err != nil {
+			return
+		}
+		children = append(children, NewNode(mesh, nil))
+	}
+
+	for _, child := range gltfNode.Children {
+		var childNode Node
+		childNode, err = l.convertNode(child, world, depth+1)
+		if err != nil {
+			return
+		}
+		children = append(children, childNode)
+	}
+
+	if gltfNode.Camera != nil {
+		l.convertCamera(*gltfNode.Camera, world)
+	}
+	if gltfNode.Extensions.LightsPunctual != nil {
+		l.convertLight(gltfNode.Extensions.LightsPunctual.Light, world)
+	}
+
+	group := NewGroup(children)
+	node.SetGeometry(&group)
+	if !node.SetTransform(local) {
+		err = fmt.Errorf("glTF node %d: transformation cannot be inverted", index)
+	}
+	return
+}
+
+// localTransform returns the transformation of the node relative to its parent.
+func (n *gltfNode) localTransform() (mathutils.Transform, error) {
+	if n.Matrix != nil {
+		if len(n.Matrix) != 16 {
+			return mathutils.Transform{}, fmt.Errorf("matrix must have 16 values")
+		}
+
+		// glTF matrices are column major for column vectors, which reads row by row as the matrix for row vectors.
+		var result mathutils.Transform
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				result[i][j] = n.Matrix[i*4+j]
+			}
+		}
+		return result, nil
+	}
+
+	scale, rotation, translation := mathutils.NewTransform(), mathutils.NewTransform(), mathutils.NewTransform()
+	if n.Scale != nil {
+		if len(n.Scale) != 3 {
+			return mathutils.Transform{}, fmt.Errorf("scale must have 3 values")
+		}
+		scale = mathutils.ScalingTransform(mathutils.NewVector(n.Scale[0], n.Scale[1], n.Scale[2]))
+	}
+	if n.Rotation != nil {
+		if len(n.Rotation) != 4 {
+			return mathutils.Transform{}, fmt.Errorf("rotation must have 4 values")
+		}
+		rotation = mathutils.QuaternionTransform(n.Rotation[0], n.Rotation[1], n.Rotation[2], n.Rotation[3])
+	}
+	if n.Translation != nil {
+		if len(n.Translation) != 3 {
+			return mathutils.Transform{}, fmt.Errorf("translation must have 3 values")
+		}
+		translation = mathutils.TranslationTransform(mathutils.NewVector(n.Translation[0], n.Translation[1], n.Translation[2]))
+	}
+
+	return mathutils.TransformMultiplication(mathutils.TransformMultiplication(scale, rotation), translation), nil
+}
+
+// convertCamera adds the perspective camera placed by the world transformation of its node.
+func (l *gltfLoader) convertCamera(index int, world mathutils.Transform) {
+	if index < 0 || index >= len(l.document.Cameras) || l.document.Cameras[index].Perspective == nil {
+		return
+	}
+
+	perspective := l.document.Cameras[index].Perspective
+	aspectRatio := perspective.AspectRatio
+	if aspectRatio <= 0 {
+		aspectRatio = 640.0 / 480.0
+	}
+
+	// glTF cameras look along -Z, mirroring their space first makes them look along Z.
+	mirror := mathutils.ScalingTransform(mathutils.NewVector(1, 1, -1))
+	transform := mathutils.TransformMultiplication(mirror, world)
+	l.result.Cameras = append(l.result.Cameras, NewTransformedCamera(transform, perspective.Yfov*180/math.Pi, aspectRatio))
+}
+
+// convertLight adds the point, spot or directional light placed by the world transformation of its node.
+// Directional and spot lights shine along the -Z axis of their node. Spot lights fade out between their inner and
+// outer cone angles, which default to 0 and a quarter of pi like in KHR_lights_punctual.
+func (l *gltfLoader) convertLight(index int, world mathutils.Transform) {
+	lights := l.document.Extensions.LightsPunctual.Lights
+	if index < 0 || index >= len(lights) {
+		return
+	}
+	kind := lights[index].Type
+	if kind != "point" && kind != "spot" && kind != "directional" {
+		return
+	}
+
+	color := utils.Color{1, 1, 1}
+	if len(lights[index].Color) == 3 {
+		color = utils.Color{lights[index].Color[0], lights[index].Color[1], lights[index].Color[2]}
+	}
+	intensity := 1.0
+	if lights[index].Intensity != nil {
+		intensity = *lights[index].Intensity
+	}
+
+	if kind == "directional" {
+		direction := mathutils.MultiplyDirectionTransform(mathutils.NewVector(0, 0, 1), world)
+		l.result.DirectionalLights = append(l.result.DirectionalLights, NewDirectionalLight(direction, color, intensity, 0, 1))
+		return
+	}
+
+	position := mathutils.MultiplyPointTransform(mathutils.NewVector(0, 0, 0), world)
+	if kind == "spot" {
+		direction := mathutils.MultiplyDirectionTransform(mathutils.NewVector(0, 0, -1), world)
+		outer := math.Pi / 4
+		if lights[index].Spot.OuterConeAngle != nil {
+			outer = *lights[index].Spot.OuterConeAngle
+		}
+		l.result.SpotLights = append(l.result.SpotLights, NewSpotLight(position, direction, color, intensity,
+			lights[index].Spot.InnerConeAngle*180/math.Pi, outer*180/math.Pi))
+		return
+	}
+	l.result.Lights = append(l.result.Lights, NewLight(position, color, intensity))
+}
+
+// convertMesh converts a mesh to a group with a node for every primitive. The mesh is converted only once.
+func (l *gltfLoader) convertMesh(index int) (*Geometry, error) {
+	if index < 0 || index >= len(l.document.Meshes) {
+		return nil, fmt.Errorf("glTF mesh %d does not exist", index)
+	}
+	if l.meshes[index] != nil {
+		return l.meshes[index], nil
+	}
+
+	var nodes []Node
+	for i, primitive := range l.document.Meshes[index].Primitives {
+		mode := gltfTriangles
+		if primitive.Mode != nil {
+			mode = *primitive.Mode
+		}
+		if mode != gltfTriangles && mode != gltfTriangleStrip && mode != gltfTriangleFan {
+			// Points and lines have no surface to render.
+			continue
+		}
+
+		data, err := l.convertPrimitive(primitive.Attributes, primitive.Indices, mode)
+		if err != nil {
+			return nil, fmt.Errorf("glTF mesh %d primitive %d: %v", index, i, err)
+		}
+
+		shader := l.fallback
+		if primitive.Material != nil {
+			if *primitive.Material < 0 || *primitive.Material >= len(l.shaders) {
+				return nil, fmt.Errorf("glTF mesh %d primitive %d: material %d does not exist", index, i, *primitive.Material)
+			}
+			shader = l.shaders[*primitive.Material]
+		}
+		if len(data.Colors) > 0 {
+			material := gltfMaterial{}
+			if primitive.Material != nil {
+				material = l.document.Materials[*primitive.Material]
+			}
+			vertexColorShader := material.toShader(true)
+			shader = &vertexColorShader
+		}
+
+		mesh := NewMesh(data)
+		node := NewNode(nil, shader)
+		node.SetGeometry(&mesh)
+		nodes = append(nodes, node)
+	}
+
+	group := NewGroup(nodes)
+	var geometry Geometry = &group
+	l.meshes[index] = &geometry
+	return &geometry, nil
+}
+
+// convertPrimitive reads the vertex attributes and triangles of a mesh primitive.
+func (l *gltfLoader) convertPrimitive(attributes map[string]int, indices *int, mode int) (data MeshData, err error) {
+	position, ok := attributes["POSITION"]
+	if !ok {
+		err = fmt.Errorf("no POSITION attribute")
+		return
+	}
+
+	// Accessors without a buffer view may not have more elements than the buffers hold for the others.
+	primitiveAccessors := []int{}
+	for _, index := range attributes {
+		primitiveAccessors = append(primitiveAccessors, index)
+	}
+	if indices != nil {
+		primitiveAccessors = append(primitiveAccessors, *indices)
+	}
+	limit := l.elementLimit(primitiveAccessors)
+
+	positions, err := l.readAccessor(position, limit, 3)
+	if err != nil {
+		return
+	}
+	for _, p := range positions {
+		data.Vertices = append(data.Vertices, mathutils.NewVector(p[0], p[1], p[2]))
+	}
+
+	if index, ok := attributes["NORMAL"]; ok {
+		var normals [][]float64
+		normals, err = l.readAccessor(index, limit, 3)
+		if err != nil {
+			return
+		}
+		for _, n := range normals {
+			data.Normals = append(data.Normals, mathutils.NewVector(n[0], n[1], n[2]))
+		}
+	}
+
+	if index, ok := attributes["TEXCOORD_0"]; ok {
+		var uvs [][]float64
+		uvs, err = l.readAccessor(index, limit, 2)
+		if err != nil {
+			return
+		}
+		// glTF puts the V origin at the top of the image.
+		for _, uv := range uvs {
+			data.UVs = append(data.UVs, [2]float64{uv[0], 1 - uv[1]})
+		}
+	}
+
+	if index, ok := attributes["COLOR_0"]; ok {
+		var colors [][]float64
+		colors, err = l.readAccessor(index, limit, 3, 4)
+		if err != nil {
+			return
+		}
+		for _, c := range colors {
+			data.Colors = append(data.Colors, utils.Color{c[0], c[1], c[2]})
+		}
+	}
+
+	var vertexIndices []int
+	if indices != nil {
+		var values [][]float64
+		values, err = l.readAccessor(*indices, limit, 1)
+		if err != nil {
+			return
+		}
+		for _, value := range values {
+			vertexIndices = append(vertexIndices, int(value[0]))
+		}
+	} else {
+		for i := range data.Vertices {
+			vertexIndices = append(vertexIndices, i)
+		}
+	}
+
+	switch mode {
+	case gltfTriangles:
+		for i := 0; i+2 < len(vertexIndices); i += 3 {
+			data.Polygons = append(data.Polygons, []int{vertexIndices[i], vertexIndices[i+1], vertexIndices[i+2]})
+		}
+	case gltfTriangleStrip:
+		for i := 0; i+2 < len(vertexIndices); i++ {
+			if i%2 == 0 {
+				data.Polygons = append(data.Polygons, []int{vertexIndices[i], vertexIndices[i+1], vertexIndices[i+2]})
+			} else {
+				data.Polygons = append(data.Polygons, []int{vertexIndices[i+1], vertexIndices[i], vertexIndices[i+2]})
+			}
+		}
+	case gltfTriangleFan:
+		for i := 1; i+1 < len(vertexIndices); i++ {
+			data.Polygons = append(data.Polygons, []int{vertexIndices[0], vertexIndices[i], vertexIndices[i+1]})
+		}
+	}
+
+	err = data.Validate()
+	return
+}
+
+// elementLimit returns the largest number of elements the buffers can hold for the accessors with a buffer view,
+// every element taking at least a byte.
+func (l *gltfLoader) elementLimit(accessors []int) int {
+	limit := 0
+	for _, index := range accessors {
+		if index < 0 || index >= len(l.document.Accessors) {
+			continue
+		}
+		accessor := &l.document.Accessors[index]
+		if accessor.BufferView == nil || *accessor.BufferView < 0 || *accessor.BufferView >= len(l.document.BufferViews) {
+			continue
+		}
+		view := &l.document.BufferViews[*accessor.BufferView]
+		if view.Buffer < 0 || view.Buffer >= len(l.buffers) {
+			continue
+		}
+		limit = int(math.Max(float64(limit), math.Min(float64(accessor.Count), float64(len(l.buffers[view.Buffer])))))
+	}
+
+	return limit
+}
+
+// readAccessor reads the elements of an accessor, which must have one of the given numbers of components.
+// An accessor without a buffer view may have at most limit elements.
+func (l *gltfLoader) readAccessor(index, limit int, components ...int) ([][]float64, error) {
+	if index < 0 || index >= len(l.document.Accessors) {
+		return nil, fmt.Errorf("accessor %d does not exist", index)
+	}
+
+	accessor := &l.document.Accessors[index]
+	if accessor.Sparse != nil {
+		return nil, fmt.Errorf("accessor %d is sparse, which is not supported", index)
+	}
+
+	count := map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4}[accessor.Type]
+	allowed := false
+	for _, c := range components {
+		allowed = allowed || c == count
+	}
+	if !allowed {
+		return nil, fmt.Errorf("accessor %d has unexpected type %s", index, accessor.Type)
+	}
+
+	size := map[int]int{gltfByte: 1, gltfUnsignedByte: 1, gltfShort: 2, gltfUnsignedShort: 2, gltfUnsignedInt: 4, gltfFloat: 4}[accessor.ComponentType]
+	if size == 0 {
+		return nil, fmt.Errorf("accessor %d has unknown component type %d", index, accessor.ComponentType)
+	}
+
+	if accessor.Count < 0 || accessor.ByteOffset < 0 {
+		return nil, fmt.Errorf("accessor %d has a negative count or byte offset", index)
+	}
+
+	if accessor.BufferView == nil {
+		if accessor.Count > limit {
+			return nil, fmt.Errorf("accessor %d has more elements than the other accessors of its primitive", index)
+		}
+
+		// Accessors without a buffer view are all zeros.
+		result := make([][]float64, accessor.Count)
+		for i := range result {
+			result[i] = make([]float64, count)
+		}
+		return result, nil
+	}
+
+	if *accessor.BufferView < 0 || *accessor.BufferView >= len(l.document.BufferViews) {
+		return nil, fmt.Errorf("accessor %d references missing buffer view %d", index, *accessor.BufferView)
+	}
+	view := &l.document.BufferViews[*accessor.BufferView]
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteStride < 0 {
+		return nil, fmt.Errorf("buffer view %d has a negative byte offset, length or stride", *accessor.BufferView)
+	}
+	if view.Buffer < 0 || view.Buffer >= len(l.buffers) || view.ByteOffset > len(l.buffers[view.Buffer]) ||
+		view.ByteLength > len(l.buffers[view.Buffer])-view.ByteOffset {
+		return nil, fmt.Errorf("buffer view %d is outside of its buffer", *accessor.BufferView)
+	}
+	data := l.buffers[view.Buffer][view.ByteOffset : view.ByteOffset+view.ByteLength]
+
+	stride := view.ByteStride
+	if stride == 0 {
+		stride = size * count
+	}
+	// The last element has to end inside the view, compared without multiplying the count, which may overflow.
+	available := len(data) - accessor.ByteOffset
+	if accessor.Count > 0 && (available < size*count || accessor.Count-1 > (available-size*count)/stride) {
+		return nil, fmt.Errorf("accessor %d is outside of its buffer view", index)
+	}
+
+	result := make([][]float64, accessor.Count)
+	for i := range result {
+		result[i] = make([]float64, count)
+		for j := range result[i] {
+			offset := accessor.ByteOffset + stride*i + size*j
+			result[i][j] = readGLTFComponent(data[offset:], accessor.ComponentType, accessor.Normalized)
+		}
+	}
+
+	return result, nil
+}
+
+// readGLTFComponent decodes a single little endian component, mapping normalized integers to [0, 1] or [-1, 1].
+func readGLTFComponent(data []byte, componentType int, normalized bool) float64 {
+	var value, maximum float64
+	switch componentType {
+	case gltfByte:
+		value, maximum = float64(int8(data[0])), 127
+	case gltfUnsignedByte:
+		value, maximum = float64(data[0]), 255
+	case gltfShort:
+		value, maximum = float64(int16(binary.LittleEndian.Uint16(data))), 32767
+	case gltfUnsignedShort:
+		value, maximum = float64(binary.LittleEndian.Uint16(data)), 65535
+	case gltfUnsignedInt:
+		value, maximum = float64(binary.LittleEndian.Uint32(data)), 4294967295
+	default:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(data)))
+	}
+
+	if normalized {
+		return math.Max(value/maximum, -1)
+	}
+	return value
+}
+
+// toShader maps the metallic-roughness factors to a Phong shader.
+// Metals get a stronger highlight and rougher surfaces a wider one.
+// With vertexColors the base color comes from the mesh vertices.
+func (m *gltfMaterial) toShader(vertexColors bool) Shader {
+	pbr := &m.PBRMetallicRoughness
+	color := utils.Color{1, 1, 1}
+	if len(pbr.BaseColorFactor) == 4 {
+		color = utils.Color{pbr.BaseColorFactor[0], pbr.BaseColorFactor[1], pbr.BaseColorFactor[2]}
+	}
+	metallic, roughness := 1.0, 1.0
+	if pbr.MetallicFactor != nil {
+		metallic = *pbr.MetallicFactor
+	}
+	if pbr.RoughnessFactor != nil {
+		roughness = *pbr.RoughnessFactor
+	}
+
+	// The Phong exponent matching a Beckmann distribution with the roughness alpha = roughness^2.
+	alpha := math.Max(roughness*roughness, 0.01)
+	phong := &Phong{
+		color:              color,
+		specularMultiplier: (0.04 + 0.96*metallic) * (1 - roughness*0.9),
+		specularExponent:   math.Min(math.Max(2/(alpha*alpha)-2, 1), 10000),
+	}
+	if vertexColors {
+		phong.SetTexture(&VertexColor{color})
+	}
+
+	return phong
+}
@@ -0,0 +1,112 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testGLTF is a triangle in the XY plane moved 2 along Z, with a camera, a point light at the origin
+// and a spot light 5 along Z shining back along -Z.
+// The buffer holds the positions (0 0 0), (1 0 0), (0 1 0) as floats.
+const testGLTF = `{
+	"asset": {"version": "2.0"},
+	"scene": 0,
+	"scenes": [{"nodes": [0, 1, 2]}],
+	"nodes": [
+		{"mesh": 0, "translation": [0, 0, 2], "extensions": {"KHR_lights_punctual": {"light": 0}}},
+		{"camera": 0, "translation": [0, 0, 10]},
+		{"translation": [0, 0, 5], "extensions": {"KHR_lights_punctual": {"light": 1}}}
+	],
+	"meshes": [{"primitives": [{"attributes": {"POSITION": 0}, "material": 0}]}],
+	"materials": [{"pbrMetallicRoughness": {"baseColorFactor": [1, 0, 0, 1], "metallicFactor": 0}}],
+	"cameras": [{"type": "perspective", "perspective": {"yfov": 1, "znear": 0.1}}],
+	"extensions": {"KHR_lights_punctual": {"lights": [{"type": "point", "intensity": 5},
+		{"type": "spot", "intensity": 25, "spot": {"innerConeAngle": 0.1, "outerConeAngle": 0.5}}]}},
+	"accessors": [{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"}],
+	"bufferViews": [{"buffer": 0, "byteLength": 36}],
+	"buffers": [{"byteLength": 36%s}]
+}`
+
+func TestReadGLTF(t *testing.T) {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, [9]float32{0, 0, 0, 1, 0, 0, 0, 1, 0})
+	directory := t.TempDir()
+	os.WriteFile(filepath.Join(directory, "triangle.bin"), buffer.Bytes(), 0644)
+	os.WriteFile(filepath.Join(directory, "triangle.gltf"), []byte(strings.Replace(testGLTF, "%s", `, "uri": "triangle.bin"`, 1)), 0644)
+
+	jsonChunk := []byte(strings.Replace(testGLTF, "%s", "", 1))
+	for len(jsonChunk)%4 != 0 {
+		jsonChunk = append(jsonChunk, ' ')
+	}
+	var glb bytes.Buffer
+	glb.WriteString("glTF")
+	binary.Write(&glb, binary.LittleEndian, [2]uint32{2, uint32(12 + 8 + len(jsonChunk) + 8 + buffer.Len())})
+	binary.Write(&glb, binary.LittleEndian, uint32(len(jsonChunk)))
+	glb.WriteString("JSON")
+	glb.Write(jsonChunk)
+	binary.Write(&glb, binary.LittleEndian, uint32(buffer.Len()))
+	glb.WriteString("BIN\x00")
+	glb.Write(buffer.Bytes())
+	os.WriteFile(filepath.Join(directory, "triangle.glb"), glb.Bytes(), 0644)
+
+	for _, name := range []string{"triangle.gltf", "triangle.glb"} {
+		gltf, err := ReadGLTF(filepath.Join(directory, name))
+		if err != nil || len(gltf.Cameras) != 1 || len(gltf.Lights) != 1 || len(gltf.SpotLights) != 1 {
+			t.Fatalf("ReadGLTF() failed! %v", err)
+		}
+
+		// The right-handed glTF space is mirrored along Z.
+		if !compareVectors(gltf.Lights[0].position, mathutils.NewVector(0, 0, -2)) || !compareVectors(gltf.Cameras[0].position, mathutils.NewVector(0, 0, -10)) {
+			t.Errorf("ReadGLTF() failed!")
+		}
+
+		// The spot light reaches the origin along its axis, but not the points outside of its cone.
+		spot := gltf.SpotLights[0]
+		if sample := spot.sampleLight(mathutils.NewVector(0, 0, 0))[0]; math.Abs(sample.intensity[0]-1) > 1e-6 {
+			t.Errorf("ReadGLTF() failed!")
+		}
+		if sample := spot.sampleLight(mathutils.NewVector(5, 0, 0))[0]; sample.intensity[0] != 0 {
+			t.Errorf("ReadGLTF() failed!")
+		}
+
+		var info IntersectionInfo
+		ray := NewRay(mathutils.NewVector(0.25, 0.25, -10), mathutils.NewVector(0, 0, 1))
+		if !gltf.Root.Intersect(&ray, &info) || math.Abs(info.Distance-8) > 1e-6 || info.shader == nil {
+			t.Errorf("ReadGLTF() failed!")
+		}
+
+		// The camera looks along -Z in glTF, so along Z after mirroring.
+		cameraRay := gltf.Cameras[0].GetScreenRay(320, 240)
+		if !compareVectors(cameraRay.Direction, mathutils.NewVector(0, 0, 1)) {
+			t.Errorf("ReadGLTF() failed!")
+		}
+	}
+
+	if _, err := ReadGLTF(filepath.Join(directory, "missing.gltf")); err == nil {
+		t.Errorf("ReadGLTF() failed!")
+	}
+
+	// Negative or oversized counts, offsets, lengths and strides are rejected instead of slicing out of bounds.
+	for _, broken := range []struct{ old, new string }{
+		{`"count": 3`, `"count": -3`},
+		{`"count": 3`, `"count": 3, "byteOffset": -12`},
+		{`{"buffer": 0, "byteLength": 36}`, `{"buffer": 0, "byteOffset": -12, "byteLength": 36}`},
+		{`{"buffer": 0, "byteLength": 36}`, `{"buffer": 0, "byteLength": -36}`},
+		{`{"buffer": 0, "byteLength": 36}`, `{"buffer": 0, "byteLength": 36, "byteStride": -12}`},
+		{`"count": 3`, `"count": 1000000000000000000`},
+		{`"count": 3`, `"count": 3, "byteOffset": 1000000000000000000`},
+		{`{"bufferView": 0, "componentType": 5126, "count": 3`, `{"componentType": 5126, "count": 1000000000000000000`},
+	} {
+		document := strings.Replace(strings.Replace(testGLTF, "%s", `, "uri": "triangle.bin"`, 1), broken.old, broken.new, 1)
+		os.WriteFile(filepath.Join(directory, "broken.gltf"), []byte(document), 0644)
+		if _, err := ReadGLTF(filepath.Join(directory, "broken.gltf")); err == nil {
+			t.Errorf("ReadGLTF() failed!")
+		}
+	}
+}
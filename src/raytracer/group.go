@@ -0,0 +1,47 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import "GoRaytracer/src/mathutils"
+
+// Group defines a geometry made of scene nodes, each one placed relative to the group.
+// A node in a group without a shader is shaded with the shader of the group's node.
+type Group struct {
+	nodes nodeList // The child nodes of the group.
+	bvh   bvh      // The acceleration structure over the child nodes.
+}
+
+// nodeList implements bvhPrimitives for a slice of nodes.
+type nodeList []Node
+
+func (n nodeList) Len() int {
+	return len(n)
+}
+
+func (n nodeList) BoundingBox(i int) mathutils.BoundingBox {
+	return n[i].BoundingBox()
+}
+
+func (n nodeList) Intersect(i int, ray *Ray, info *IntersectionInfo) bool {
+	return n[i].Intersect(ray, info)
+}
+
+// NewGroup creates and returns a new group of the given nodes.
+// The nodes must not be changed after the group is created.
+func NewGroup(nodes []Node) Group {
+	return Group{nodes, newBVH(nodeList(nodes))}
+}
+
+// GetNodes returns the child nodes of the group.
+func (g *Group) GetNodes() []Node {
+	return g.nodes
+}
+
+// Intersect implements the intersect method of the Geometry interface for Group.
+func (g *Group) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	return g.bvh.intersect(g.nodes, ray, info)
+}
+
+// BoundingBox implements the Bounded interface for Group.
+func (g *Group) BoundingBox() mathutils.BoundingBox {
+	return g.bvh.boundingBox()
+}
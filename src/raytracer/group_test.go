@@ -0,0 +1,47 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"testing"
+)
+
+func TestGroupIntersect(t *testing.T) {
+	var groupShader, childShader Shader = &Lambert{}, &Phong{}
+	var nodes []Node
+	for i := 0; i < 10; i++ {
+		var sphere Geometry = &Sphere{mathutils.NewVector(0, 0, 0), 1}
+		node := NewNode(&sphere, nil)
+		node.SetTransform(mathutils.TranslationTransform(mathutils.NewVector(float64(i)*5, 0, 0)))
+		if i == 3 {
+			node.SetShader(childShader)
+		}
+		nodes = append(nodes, node)
+	}
+
+	group := NewGroup(nodes)
+	var geometry Geometry = &group
+	node := NewNode(&geometry, &groupShader)
+	node.SetTransform(mathutils.TranslationTransform(mathutils.NewVector(0, 10, 0)))
+	var info IntersectionInfo
+
+	ray := NewRay(mathutils.NewVector(15, 10, -10), mathutils.NewVector(0, 0, 1))
+	if !node.Intersect(&ray, &info) || math.Abs(info.Distance-9) > 1e-6 || *info.shader != childShader {
+		t.Errorf("Group.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(-10, 10, 0), mathutils.NewVector(1, 0, 0))
+	if !node.Intersect(&ray, &info) || math.Abs(info.Distance-9) > 1e-6 || *info.shader != groupShader {
+		t.Errorf("Group.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(17.5, 10, -10), mathutils.NewVector(0, 0, 1))
+	if node.Intersect(&ray, &info) {
+		t.Errorf("Group.Intersect() failed!")
+	}
+
+	box := node.BoundingBox()
+	if !compareVectors(box.Min, mathutils.NewVector(-1, 9, -1)) || !compareVectors(box.Max, mathutils.NewVector(46, 11, 1)) {
+		t.Errorf("Group.BoundingBox() failed!")
+	}
+}
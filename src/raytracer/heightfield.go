@@ -0,0 +1,214 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png" // Registers the PNG decoder for the height maps.
+	"io"
+	"math"
+)
+
+// Heightfield defines a terrain given by a regular grid of elevations.
+// Every grid cell is made of two triangles, the grid lies in the XZ plane and the elevation is along Y.
+type Heightfield struct {
+	heights     [][]float64          // The elevations in [0, 1], indexed by row along Z and column along X.
+	normals     [][]mathutils.Vector // The smooth normals at the grid points.
+	origin      mathutils.Vector     // The corner of the grid with the smallest X and Z.
+	cellWidth   float64              // The size of a cell along X.
+	cellDepth   float64              // The size of a cell along Z.
+	heightScale float64              // The elevation of the grid points with height 1.
+	bounds      mathutils.BoundingBox
+}
+
+// ReadHeightmap decodes an image and returns its brightness in [0, 1] as elevations.
+// The first image row ends up as the last row of the elevations, so the top of the image is at the far end along Z.
+func ReadHeightmap(reader io.Reader) ([][]float64, error) {
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() < 2 || bounds.Dy() < 2 {
+		return nil, fmt.Errorf("Height map must be at least 2x2 pixels")
+	}
+
+	heights := make([][]float64, bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := make([]float64, bounds.Dx())
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray := color.Gray16Model.Convert(img.At(x, y)).(color.Gray16)
+			row[x-bounds.Min.X] = float64(gray.Y) / math.MaxUint16
+		}
+		heights[bounds.Max.Y-1-y] = row
+	}
+
+	return heights, nil
+}
+
+// NewHeightfield creates and returns a heightfield over the elevations.
+// The base of the heightfield is centered at center and it spans width along X and depth along Z.
+func NewHeightfield(heights [][]float64, center mathutils.Vector, width, depth, heightScale float64) Heightfield {
+	rows, columns := len(heights), len(heights[0])
+	h := Heightfield{
+		heights:     heights,
+		origin:      mathutils.NewVector(center.X-width/2, center.Y, center.Z-depth/2),
+		cellWidth:   width / float64(columns-1),
+		cellDepth:   depth / float64(rows-1),
+		heightScale: heightScale,
+	}
+
+	minHeight, maxHeight := math.Inf(1), math.Inf(-1)
+	h.normals = make([][]mathutils.Vector, rows)
+	for z := range heights {
+		h.normals[z] = make([]mathutils.Vector, columns)
+		for x := range heights[z] {
+			minHeight = math.Min(minHeight, heights[z][x])
+			maxHeight = math.Max(maxHeight, heights[z][x])
+
+			// Central differences of the elevation, one sided at the borders.
+			left, right := math.Max(float64(x-1), 0), math.Min(float64(x+1), float64(columns-1))
+			back, front := math.Max(float64(z-1), 0), math.Min(float64(z+1), float64(rows-1))
+			dx := (h.height(int(right), z) - h.height(int(left), z)) / ((right - left) * h.cellWidth)
+			dz := (h.height(x, int(front)) - h.height(x, int(back))) / ((front - back) * h.cellDepth)
+			normal := mathutils.NewVector(-dx, 1, -dz)
+			normal.Normalize()
+			h.normals[z][x] = normal
+		}
+	}
+
+	h.bounds = mathutils.NewBoundingBox(
+		mathutils.NewVector(h.origin.X, h.origin.Y+minHeight*heightScale, h.origin.Z),
+		mathutils.NewVector(h.origin.X+width, h.origin.Y+maxHeight*heightScale, h.origin.Z+depth))
+	return h
+}
+
+// height returns the scaled elevation of the grid point.
+func (h *Heightfield) height(x, z int) float64 {
+	return h.heights[z][x] * h.heightScale
+}
+
+// point returns the position of the grid point.
+func (h *Heightfield) point(x, z int) mathutils.Vector {
+	return mathutils.NewVector(h.origin.X+float64(x)*h.cellWidth, h.origin.Y+h.height(x, z), h.origin.Z+float64(z)*h.cellDepth)
+}
+
+// Intersect implements the intersect method of the Geometry interface for Heightfield.
+// The grid cells along the ray are visited in order with a 2D digital differential analyzer,
+// skipping the cells whose elevation range the ray does not cross.
+func (h *Heightfield) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	inverseDirection := mathutils.NewVector(1/ray.Direction.X, 1/ray.Direction.Y, 1/ray.Direction.Z)
+	near, far, ok := h.bounds.IntersectRay(ray.Start, inverseDirection)
+	if !ok {
+		return false
+	}
+	near = math.Max(near, 0)
+
+	rows, columns := len(h.heights), len(h.heights[0])
+	entry := mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, near))
+	cellX := clampCell((entry.X-h.origin.X)/h.cellWidth, columns-1)
+	cellZ := clampCell((entry.Z-h.origin.Z)/h.cellDepth, rows-1)
+
+	// A vertical ray stays in the cell it enters.
+	if ray.Direction.X == 0 && ray.Direction.Z == 0 {
+		return h.intersectCell(ray, cellX, cellZ, near, far, info)
+	}
+
+	stepX, nextX, deltaX := dda(ray.Start.X, ray.Direction.X, h.origin.X, h.cellWidth, cellX)
+	stepZ, nextZ, deltaZ := dda(ray.Start.Z, ray.Direction.Z, h.origin.Z, h.cellDepth, cellZ)
+
+	cellEntry := near
+	for cellX >= 0 && cellX < columns-1 && cellZ >= 0 && cellZ < rows-1 && cellEntry <= far {
+		cellExit := math.Min(math.Min(nextX, nextZ), far)
+		if h.intersectCell(ray, cellX, cellZ, cellEntry, cellExit, info) {
+			return true
+		}
+		if cellExit >= far {
+			break
+		}
+
+		cellEntry = cellExit
+		if nextX < nextZ {
+			cellX += stepX
+			nextX += deltaX
+		} else {
+			cellZ += stepZ
+			nextZ += deltaZ
+		}
+	}
+
+	return false
+}
+
+// clampCell returns the cell containing the grid coordinate, clamped to the cells of the grid.
+func clampCell(coordinate float64, cells int) int {
+	return int(math.Max(0, math.Min(math.Floor(coordinate), float64(cells-1))))
+}
+
+// dda returns the step direction, the distance to the first cell boundary
+// and the distance between cell boundaries along one axis of the grid.
+func dda(start, direction, origin, size float64, cell int) (step int, next, delta float64) {
+	switch {
+	case direction > 0:
+		return 1, (origin + float64(cell+1)*size - start) / direction, size / direction
+	case direction < 0:
+		return -1, (origin + float64(cell)*size - start) / direction, -size / direction
+	default:
+		return 0, math.Inf(1), math.Inf(1)
+	}
+}
+
+// intersectCell intersects the ray with the two triangles of a cell crossed by the ray between entry and exit.
+func (h *Heightfield) intersectCell(ray *Ray, x, z int, entry, exit float64, info *IntersectionInfo) bool {
+	cellMin := math.Min(math.Min(h.height(x, z), h.height(x+1, z)), math.Min(h.height(x, z+1), h.height(x+1, z+1)))
+	cellMax := math.Max(math.Max(h.height(x, z), h.height(x+1, z)), math.Max(h.height(x, z+1), h.height(x+1, z+1)))
+	entryY := ray.Start.Y + ray.Direction.Y*entry - h.origin.Y
+	exitY := ray.Start.Y + ray.Direction.Y*exit - h.origin.Y
+	if math.Max(entryY, exitY) < cellMin-1e-9 || math.Min(entryY, exitY) > cellMax+1e-9 {
+		return false
+	}
+
+	type corner struct{ x, z int }
+	triangles := [2][3]corner{
+		{{x, z}, {x + 1, z}, {x, z + 1}},
+		{{x + 1, z}, {x + 1, z + 1}, {x, z + 1}},
+	}
+
+	found := false
+	for _, triangle := range triangles {
+		a, b, c := triangle[0], triangle[1], triangle[2]
+		distance, beta, gamma, ok := intersectTriangle(ray, h.point(a.x, a.z), h.point(b.x, b.z), h.point(c.x, c.z))
+		if !ok || (found && distance >= info.Distance) {
+			continue
+		}
+
+		found = true
+		alpha := 1 - beta - gamma
+		normal := mathutils.VectorMultiply(h.normals[a.z][a.x], alpha)
+		normal.Add(mathutils.VectorMultiply(h.normals[b.z][b.x], beta))
+		normal.Add(mathutils.VectorMultiply(h.normals[c.z][c.x], gamma))
+		normal.Normalize()
+
+		info.Distance = distance
+		info.Position = mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, distance))
+		info.Normal = normal
+		width := h.cellWidth * float64(len(h.heights[0])-1)
+		depth := h.cellDepth * float64(len(h.heights)-1)
+		info.U = (info.Position.X - h.origin.X) / width
+		info.V = (info.Position.Z - h.origin.Z) / depth
+
+		// U and V follow X and Z, the height changes along the tangent plane of the normal.
+		info.DPdu = mathutils.NewVector(width, -width*normal.X/normal.Y, 0)
+		info.DPdv = mathutils.NewVector(0, -depth*normal.Z/normal.Y, depth)
+	}
+
+	return found
+}
+
+// BoundingBox implements the Bounded interface for Heightfield.
+func (h *Heightfield) BoundingBox() mathutils.BoundingBox {
+	return h.bounds
+}
@@ -0,0 +1,65 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHeightfieldIntersect(t *testing.T) {
+	heights := [][]float64{
+		{0, 0, 0},
+		{0, 1, 0},
+		{0, 0, 0},
+	}
+	heightfield := NewHeightfield(heights, mathutils.NewVector(0, 0, 0), 4, 4, 2)
+	var info IntersectionInfo
+
+	ray := NewRay(mathutils.NewVector(0, 10, 0), mathutils.NewVector(0, -1, 0))
+	if !heightfield.Intersect(&ray, &info) || math.Abs(info.Distance-8) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 1, 0)) {
+		t.Errorf("Heightfield.Intersect() failed!")
+	}
+	if math.Abs(info.U-0.5) > 1e-6 || math.Abs(info.V-0.5) > 1e-6 {
+		t.Errorf("Heightfield.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(-10, 0.5, 0), mathutils.NewVector(1, 0, 0))
+	if !heightfield.Intersect(&ray, &info) || math.Abs(info.Distance-8.5) > 1e-6 || info.Normal.X >= 0 {
+		t.Errorf("Heightfield.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(-10, 2.5, 0), mathutils.NewVector(1, 0, 0))
+	if heightfield.Intersect(&ray, &info) {
+		t.Errorf("Heightfield.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(-11.5, 10, -11.5), mathutils.NewVector(1, -1, 1))
+	ray.Direction.Normalize()
+	if !heightfield.Intersect(&ray, &info) || !compareVectors(info.Position, mathutils.NewVector(-1.5, 0, -1.5)) {
+		t.Errorf("Heightfield.Intersect() failed!")
+	}
+
+	// Vertical rays, like shadow rays to a light overhead, are tested against the cell they enter.
+	slope := NewHeightfield([][]float64{{0, 1}, {0, 1}}, mathutils.NewVector(0, 0, 0), 2, 2, 1)
+	ray = NewRay(mathutils.NewVector(-0.5, 5, 0.2), mathutils.NewVector(0, -1, 0))
+	if !slope.Intersect(&ray, &info) || math.Abs(info.Distance-4.75) > 1e-6 {
+		t.Errorf("Heightfield.Intersect() failed!")
+	}
+	ray = NewRay(mathutils.NewVector(-0.5, 0.6, 0.2), mathutils.NewVector(0, 1, 0))
+	if slope.Intersect(&ray, &info) {
+		t.Errorf("Heightfield.Intersect() failed!")
+	}
+}
+
+func TestReadHeightfield(t *testing.T) {
+	// The errors of the height map name the file.
+	path := filepath.Join(t.TempDir(), "terrain.png")
+	os.WriteFile(path, []byte("not an image"), 0644)
+	reader := SceneReader{fileContent: strings.Fields("Heightfield { file " + path + " center 0 0 0 }")}
+	if _, err := reader.readHeightfield(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("SceneReader.readHeightfield() failed!")
+	}
+}
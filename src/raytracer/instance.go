@@ -0,0 +1,44 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import "GoRaytracer/src/mathutils"
+
+// Prototype defines a geometry and an optional shader which are shared by many instances.
+// The geometry keeps its own acceleration structure, so it is built and stored only once.
+type Prototype struct {
+	geometry *Geometry // The shared geometry.
+	shader   *Shader   // The shared shader, nil if the prototype has none.
+}
+
+// NewPrototype creates and returns a new prototype with the given geometry and shader.
+// The shader can be nil.
+func NewPrototype(geometry Geometry, shader Shader) *Prototype {
+	prototype := &Prototype{&geometry, nil}
+	if shader != nil {
+		prototype.shader = &shader
+	}
+
+	return prototype
+}
+
+// GetGeometry returns the shared geometry of the prototype.
+func (p *Prototype) GetGeometry() *Geometry {
+	return p.geometry
+}
+
+// GetShader returns the shared shader of the prototype.
+func (p *Prototype) GetShader() *Shader {
+	return p.shader
+}
+
+// NewInstance creates and returns a node that places the prototype with the given transformation.
+// The shader, if not nil, is used instead of the shared shader of the prototype.
+// Returns false if the transformation cannot be inverted.
+func (p *Prototype) NewInstance(shader *Shader, transform mathutils.Transform) (Node, bool) {
+	if shader == nil {
+		shader = p.shader
+	}
+
+	node := NewNode(p.geometry, shader)
+	return node, node.SetTransform(transform)
+}
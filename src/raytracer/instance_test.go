@@ -0,0 +1,37 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"testing"
+)
+
+func TestPrototypeNewInstance(t *testing.T) {
+	var prototypeShader, instanceShader Shader = &Lambert{}, &Phong{}
+	prototype := NewPrototype(&Sphere{mathutils.NewVector(0, 0, 0), 1}, prototypeShader)
+
+	first, ok := prototype.NewInstance(nil, mathutils.TranslationTransform(mathutils.NewVector(-5, 0, 0)))
+	if !ok {
+		t.Errorf("Prototype.NewInstance() failed!")
+	}
+
+	second, ok := prototype.NewInstance(&instanceShader, mathutils.ScalingTransform(mathutils.NewVector(2, 2, 2)))
+	if !ok || first.GetGeometry() != second.GetGeometry() {
+		t.Errorf("Prototype.NewInstance() failed!")
+	}
+
+	var info IntersectionInfo
+	ray := NewRay(mathutils.NewVector(-5, 0, -10), mathutils.NewVector(0, 0, 1))
+	if !first.Intersect(&ray, &info) || math.Abs(info.Distance-9) > 1e-6 || *info.shader != prototypeShader {
+		t.Errorf("Prototype.NewInstance() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 0, -10), mathutils.NewVector(0, 0, 1))
+	if !second.Intersect(&ray, &info) || math.Abs(info.Distance-8) > 1e-6 || *info.shader != instanceShader {
+		t.Errorf("Prototype.NewInstance() failed!")
+	}
+
+	if _, ok = prototype.NewInstance(nil, mathutils.ScalingTransform(mathutils.NewVector(0, 1, 1))); ok {
+		t.Errorf("Prototype.NewInstance() failed!")
+	}
+}
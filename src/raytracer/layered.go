@@ -0,0 +1,159 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+)
+
+// maxTraceDepth limits the number of reflections of a ray, deeper rays only see the background.
+const maxTraceDepth = 4
+
+// Blend defines a shader mixing two shaders, like dust over paint. The share of the second shader
+// is given by the factor, or by the luminance of the mask texture if there is one.
+type Blend struct {
+	first, second Shader
+	factor        float64 // The share of the second shader without a mask.
+	mask          *Texture
+}
+
+// NewBlend creates and returns a new blend of the two shaders, with the factor as the share of the second.
+func NewBlend(first, second Shader, factor float64) Blend {
+	return Blend{first: first, second: second, factor: factor}
+}
+
+// SetMask sets the texture whose luminance gives the share of the second shader.
+func (b *Blend) SetMask(texture Texture) {
+	b.mask = &texture
+}
+
+// Shade implements a blend shader, a shader with no share is not evaluated.
+func (b *Blend) Shade(ray *Ray, info *IntersectionInfo, scene *Scene) utils.Color {
+	factor := b.factor
+	if b.mask != nil {
+		mask := sampleTexture(*b.mask, info)
+		factor = luminance(mask)
+	}
+	factor = math.Max(0, math.Min(factor, 1))
+
+	// Every shader gets its own copy of the intersection, which it may change.
+	var result utils.Color
+	if factor < 1 {
+		first := *info
+		result = utils.MultiplyColorFloat(b.first.Shade(ray, &first, scene), 1-factor)
+	}
+	if factor > 0 {
+		second := *info
+		result = utils.ColorAddition(result, utils.MultiplyColorFloat(b.second.Shade(ray, &second, scene), factor))
+	}
+
+	return result
+}
+
+// Coated defines a shader with a clear dielectric coat over a base shader, like car paint or varnished wood.
+// The coat reflects the scene by the Fresnel factor of its index of refraction, sharply or blurred by
+// the roughness, and lets the rest of the light through to the base, tinted by its color.
+type Coated struct {
+	base      Shader
+	color     utils.Color // The tint of the light passing through the coat.
+	ior       float64     // The index of refraction of the coat.
+	roughness float64     // The blur of the reflection, from 0 for a mirror to 1.
+	samples   int         // The number of reflected rays for a rough coat.
+	surfaceDetail
+}
+
+// NewCoated creates and returns a new coat over the base shader, with a white tint.
+func NewCoated(base Shader, ior, roughness float64, samples int) Coated {
+	return Coated{base: base, color: utils.Color{1, 1, 1}, ior: ior, roughness: roughness, samples: int(math.Max(1, float64(samples)))}
+}
+
+// SetColor sets the tint of the light passing through the coat.
+func (c *Coated) SetColor(color utils.Color) {
+	c.color = color
+}
+
+// Shade implements a coated shader. The highlights of the lights follow the normalized Phong lobe
+// matching the roughness, which keeps a small one even for a mirror-like coat.
+func (c *Coated) Shade(ray *Ray, info *IntersectionInfo, scene *Scene) utils.Color {
+	shading := c.shadingInfo(info)
+	normal := mathutils.Faceforward(ray.Direction, shading.Normal)
+	cosView := math.Max(0, -mathutils.DotProduct(ray.Direction, normal))
+	fresnel := schlickFresnel(cosView, c.ior)
+
+	base := *info
+	result := utils.ColorMultiplication(c.base.Shade(ray, &base, scene), utils.MultiplyColorFloat(c.color, 1-fresnel))
+
+	mirror := mathutils.Reflect(ray.Direction, normal)
+	exponent := phongExponent(math.Max(c.roughness, 0.05))
+	var highlight utils.Color
+	start := mathutils.VectorAddition(info.Position, mathutils.VectorMultiply(normal, 1e-5))
+	for _, sample := range sceneLightSamples(scene, info.Position) {
+		cosTheta := mathutils.DotProduct(normal, sample.toLight)
+		cosAlpha := mathutils.DotProduct(mirror, sample.toLight)
+		if cosTheta <= 0 || cosAlpha <= 0 {
+			continue
+		}
+		lobe := (exponent + 2) / 2 * math.Pow(cosAlpha, exponent) * cosTheta
+		light := utils.ColorMultiplication(sample.intensity, visibility(start, sample.toLight, sample.distance, scene))
+		highlight = utils.ColorAddition(highlight, utils.MultiplyColorFloat(light, lobe))
+	}
+	highlight = utils.ColorMultiplication(highlight, scene.ambientLight)
+
+	reflection := c.reflection(ray, start, normal, mirror, scene)
+	return utils.ColorAddition(result, utils.MultiplyColorFloat(utils.ColorAddition(highlight, reflection), fresnel))
+}
+
+// reflection returns the light reflected by the coat around the mirror direction, from rays spread over the
+// Phong lobe of the roughness by a Hammersley set shifted by a hash of the position, like the environment samples.
+// Past the depth limit only the background is reflected.
+func (c *Coated) reflection(ray *Ray, start, normal, mirror mathutils.Vector, scene *Scene) utils.Color {
+	trace := func(direction mathutils.Vector) utils.Color {
+		reflected := NewRay(start, direction)
+		reflected.depth = ray.depth + 1
+		if reflected.depth > maxTraceDepth {
+			return scene.background(&reflected)
+		}
+		return scene.trace(&reflected)
+	}
+	if c.roughness <= 0 {
+		return trace(mirror)
+	}
+
+	var result utils.Color
+	exponent := phongExponent(c.roughness)
+	tangent, bitangent := mathutils.OrthonormalBasis(mirror)
+	shiftU, shiftV := hashPosition(start)
+	for i := 0; i < c.samples; i++ {
+		u1 := math.Mod((float64(i)+0.5)/float64(c.samples)+shiftU, 1)
+		u2 := math.Mod(radicalInverse(uint(i))+shiftV, 1)
+		cosAlpha := math.Pow(u1, 1/(exponent+1))
+		sinAlpha := math.Sqrt(math.Max(0, 1-cosAlpha*cosAlpha))
+		phi := 2 * math.Pi * u2
+
+		direction := mathutils.VectorMultiply(mirror, cosAlpha)
+		direction.Add(mathutils.VectorMultiply(tangent, sinAlpha*math.Cos(phi)))
+		direction.Add(mathutils.VectorMultiply(bitangent, sinAlpha*math.Sin(phi)))
+		// The directions below the surface are folded back to the mirror direction.
+		if mathutils.DotProduct(direction, normal) <= 0 {
+			direction = mirror
+		}
+		result = utils.ColorAddition(result, trace(direction))
+	}
+
+	return utils.MultiplyColorFloat(result, 1/float64(c.samples))
+}
+
+// schlickFresnel returns the share of the light reflected by a dielectric with the index of refraction
+// at the cosine of the angle of incidence, by the approximation of Schlick.
+func schlickFresnel(cosTheta, ior float64) float64 {
+	f0 := (ior - 1) / (ior + 1)
+	f0 *= f0
+
+	return f0 + (1-f0)*math.Pow(1-cosTheta, 5)
+}
+
+// phongExponent returns the exponent of the Phong lobe which matches the roughness.
+func phongExponent(roughness float64) float64 {
+	return math.Max(0, 2/(roughness*roughness)-2)
+}
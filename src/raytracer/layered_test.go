@@ -0,0 +1,79 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLayeredShaders(t *testing.T) {
+	if math.Abs(schlickFresnel(1, 1.5)-0.04) > 1e-9 || schlickFresnel(0, 1.5) != 1 {
+		t.Errorf("schlickFresnel() failed!")
+	}
+
+	// A sphere lit along the normal facing the camera shows the color of a lambert shader.
+	scene := NewScene()
+	scene.SetAmbientLight(utils.Color{1, 1, 1})
+	scene.AddLight(NewDirectionalLight(mathutils.NewVector(0, 0, -1), utils.Color{1, 1, 1}, 1, 0, 1))
+	red, blue := Lambert{color: utils.Color{1, 0, 0}}, Lambert{color: utils.Color{0, 0, 1}}
+	blend := NewBlend(&red, &blue, 0.25)
+	var sphere Geometry = &Sphere{mathutils.NewVector(0, 0, 0), 10}
+	var shader Shader = &blend
+	scene.SceneNodes = append(scene.SceneNodes, NewNode(&sphere, &shader))
+	scene.Build()
+
+	ray := NewRay(mathutils.NewVector(0, 0, -50), mathutils.NewVector(0, 0, 1))
+	if color := scene.trace(&ray); math.Abs(color[0]-0.75) > 1e-6 || math.Abs(color[2]-0.25) > 1e-6 {
+		t.Errorf("Blend.Shade() failed!")
+	}
+	blend.SetMask(&SimpleColor{utils.Color{1, 1, 1}})
+	if color := scene.trace(&ray); color[0] != 0 || math.Abs(color[2]-1) > 1e-6 {
+		t.Errorf("Blend.Shade() failed!")
+	}
+	// The share of a colored mask is its luminance.
+	blend.SetMask(&SimpleColor{utils.Color{0, 1, 0}})
+	if color := scene.trace(&ray); math.Abs(color[0]-0.2848) > 1e-6 || math.Abs(color[2]-0.7152) > 1e-6 {
+		t.Errorf("Blend.Shade() failed!")
+	}
+
+	// Without lights a coat over black reflects the white background by its Fresnel factor.
+	scene.lights = nil
+	coated := NewCoated(&red, 1.5, 0, 1)
+	shader = &coated
+	if color := scene.trace(&ray); math.Abs(color[0]-0.04) > 1e-6 || math.Abs(color[1]-0.04) > 1e-6 {
+		t.Errorf("Coated.Shade() failed!")
+	}
+	coated.roughness = 0.3
+	coated.samples = 16
+	if color := scene.trace(&ray); math.Abs(color[1]-0.04) > 1e-6 {
+		t.Errorf("Coated.Shade() failed!")
+	}
+
+	reader := SceneReader{fileContent: strings.Fields(`shader Blend {
+		first Coated { base Lambert { color 255 0 0 texture nil } ior 1.8 roughness 0.1 bumpScale 2 }
+		second Phong { color 0 0 255 texture nil specularMultiplier 1 specularExponent 20 }
+		mask SimpleColor { color 128 128 128 } } end`)}
+	parsed, err := reader.readShader()
+	if err != nil || reader.fileContent[reader.position] != "end" {
+		t.Fatalf("SceneReader.readBlend() failed!")
+	}
+	parsedBlend, ok := parsed.(*Blend)
+	if !ok || parsedBlend.mask == nil || parsedBlend.factor != 0.5 {
+		t.Fatalf("SceneReader.readBlend() failed!")
+	}
+	if coat, ok := parsedBlend.first.(*Coated); !ok || coat.ior != 1.8 || coat.roughness != 0.1 || coat.bumpScale != 2 {
+		t.Errorf("SceneReader.readCoated() failed!")
+	} else if _, ok := coat.base.(*Lambert); !ok {
+		t.Errorf("SceneReader.readCoated() failed!")
+	}
+	if _, ok := parsedBlend.second.(*Phong); !ok {
+		t.Errorf("SceneReader.readBlend() failed!")
+	}
+
+	reader = SceneReader{fileContent: strings.Fields(`shader Blend { first Lambert { color 255 0 0 texture nil } } end`)}
+	if _, err := reader.readShader(); err == nil {
+		t.Errorf("SceneReader.readBlend() failed!")
+	}
+}
@@ -4,8 +4,22 @@ package raytracer
 import (
 	"GoRaytracer/src/mathutils"
 	"GoRaytracer/src/utils"
+	"math"
 )
 
+// LightSource defines a light which the shaders sample with shadow rays.
+type LightSource interface {
+	// sampleLight returns the directions from the point to the light with the light arriving along them.
+	sampleLight(point mathutils.Vector) []lightSample
+}
+
+// lightSample holds one direction to a light source.
+type lightSample struct {
+	toLight   mathutils.Vector // The normalized direction from the lit point to the light.
+	distance  float64          // The distance to the light, infinite for lights infinitely far away.
+	intensity utils.Color      // The light arriving along the direction, already divided by the number of samples.
+}
+
 // Light defines a point light.
 type Light struct {
 	position mathutils.Vector
@@ -17,3 +31,100 @@ type Light struct {
 func NewLight(position mathutils.Vector, color utils.Color, power float64) Light {
 	return Light{position, color, power}
 }
+
+// sampleLight implements LightSource for Light, the light falls off with the squared distance.
+func (l Light) sampleLight(point mathutils.Vector) []lightSample {
+	toLight := mathutils.VectorSubstraction(l.position, point)
+	distance := toLight.Length()
+	toLight.Normalize()
+
+	intensity := l.color
+	intensity.Multiply(l.power / (distance * distance))
+
+	return []lightSample{{toLight, distance, intensity}}
+}
+
+// SpotLight defines a point light shining into a cone around its direction. The light fades out
+// between the inner and the outer angle of the cone, measured from the direction.
+type SpotLight struct {
+	Light
+	direction mathutils.Vector // The normalized direction the light shines along.
+	cosInner  float64          // The cosine of the angle up to which the light has full strength.
+	cosOuter  float64          // The cosine of the angle from which there is no light.
+}
+
+// NewSpotLight creates and returns a new spot light shining along the direction.
+// The angles of the cone are given in degrees.
+func NewSpotLight(position, direction mathutils.Vector, color utils.Color, power, innerDegrees, outerDegrees float64) SpotLight {
+	direction.Normalize()
+	outer := mathutils.ToRadians(outerDegrees)
+	inner := math.Min(mathutils.ToRadians(innerDegrees), outer)
+
+	return SpotLight{NewLight(position, color, power), direction, math.Cos(inner), math.Cos(outer)}
+}
+
+// sampleLight implements LightSource for SpotLight, the point light is scaled by the square of the
+// linear falloff of the cosine between the angles of the cone, as in KHR_lights_punctual.
+func (s SpotLight) sampleLight(point mathutils.Vector) []lightSample {
+	samples := s.Light.sampleLight(point)
+	cosAngle := -mathutils.DotProduct(samples[0].toLight, s.direction)
+	falloff := 1.0
+	if s.cosInner > s.cosOuter {
+		falloff = math.Max(0, math.Min((cosAngle-s.cosOuter)/(s.cosInner-s.cosOuter), 1))
+	} else if cosAngle < s.cosOuter {
+		falloff = 0
+	}
+	samples[0].intensity = utils.MultiplyColorFloat(samples[0].intensity, falloff*falloff)
+
+	return samples
+}
+
+// DirectionalLight defines a light infinitely far away, like the sun. The light arrives from a disc
+// with the given angular diameter, which gives soft shadows, and has the same strength everywhere.
+type DirectionalLight struct {
+	direction  mathutils.Vector // The normalized direction towards the light.
+	color      utils.Color
+	irradiance float64 // The strength of the light on a surface facing it.
+	angle      float64 // The angular diameter of the disc in radians, zero for hard shadows.
+	samples    int     // The number of shadow rays for a disc.
+}
+
+// NewDirectionalLight creates and returns a new directional light shining from the direction.
+// The angular diameter is given in degrees and is sampled by the given number of shadow rays.
+func NewDirectionalLight(direction mathutils.Vector, color utils.Color, irradiance, angleDegrees float64, samples int) DirectionalLight {
+	direction.Normalize()
+	if samples < 1 {
+		samples = 1
+	}
+
+	return DirectionalLight{direction, color, irradiance, mathutils.ToRadians(angleDegrees), samples}
+}
+
+// sampleLight implements LightSource for DirectionalLight. The directions are spread uniformly over the cone
+// of the disc by a Hammersley set shifted by a hash of the point, like the environment samples.
+func (d DirectionalLight) sampleLight(point mathutils.Vector) []lightSample {
+	intensity := utils.MultiplyColorFloat(d.color, d.irradiance)
+	if d.angle <= 0 || d.samples == 1 {
+		return []lightSample{{d.direction, math.Inf(1), intensity}}
+	}
+
+	result := make([]lightSample, d.samples)
+	intensity = utils.MultiplyColorFloat(intensity, 1/float64(d.samples))
+	cosMax := math.Cos(d.angle / 2)
+	tangent, bitangent := mathutils.OrthonormalBasis(d.direction)
+	shiftU, shiftV := hashPosition(point)
+	for i := range result {
+		u1 := math.Mod((float64(i)+0.5)/float64(d.samples)+shiftU, 1)
+		u2 := math.Mod(radicalInverse(uint(i))+shiftV, 1)
+		cosTheta := 1 - u1*(1-cosMax)
+		sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+		phi := 2 * math.Pi * u2
+
+		toLight := mathutils.VectorMultiply(d.direction, cosTheta)
+		toLight.Add(mathutils.VectorMultiply(tangent, sinTheta*math.Cos(phi)))
+		toLight.Add(mathutils.VectorMultiply(bitangent, sinTheta*math.Sin(phi)))
+		result[i] = lightSample{toLight, math.Inf(1), intensity}
+	}
+
+	return result
+}
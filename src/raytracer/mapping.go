@@ -0,0 +1,184 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+)
+
+// Projections of the texture mapping.
+const (
+	MappingPlanar      = iota // U and V are X and Z, projected along the Y axis.
+	MappingSpherical          // U and V are the longitude and latitude around the origin.
+	MappingCylindrical        // U goes around the Y axis and V is Y.
+	MappingBox                // Planar along the dominant axis of the normal.
+	MappingTriplanar          // The three planar projections along the axes blended by the normal.
+)
+
+// Mapping defines a projection computing U and V from the object space position of hits,
+// which replaces the coordinates of the primitives so textures are placed the same way on any geometry.
+type Mapping struct {
+	projection       int
+	placement        mathutils.Transform // Mapping space to object space, the placement of the projection.
+	toMapping        mathutils.Transform // Object space to mapping space.
+	normalsToMapping mathutils.Transform // Object space normals to mapping space normals.
+	sharpness        float64             // The exponent of the triplanar weights, higher values give narrower blends.
+}
+
+// NewMapping creates and returns a new mapping with the projection placed in object space by the transformation.
+// Returns false if the transformation cannot be inverted.
+func NewMapping(projection int, placement mathutils.Transform, sharpness float64) (Mapping, bool) {
+	toMapping, ok := placement.Inverse()
+	if !ok {
+		return Mapping{}, false
+	}
+
+	return Mapping{projection, placement, toMapping, placement.Transpose(), sharpness}, true
+}
+
+// triplanarHit holds the three planar projections of a hit with triplanar mapping.
+type triplanarHit struct {
+	weights    [3]float64          // The weights of the projections along X, Y and Z, summing to one.
+	u, v       [3]float64          // The coordinates of the projections.
+	dPdu, dPdv [3]mathutils.Vector // The derivatives of the position for the projections.
+}
+
+// apply replaces the coordinates and derivatives of the object space hit by the ones of the projection.
+func (m *Mapping) apply(info *IntersectionInfo) {
+	info.triplanar = nil
+	q := mathutils.MultiplyPointTransform(info.Position, m.toMapping)
+	normal := mathutils.MultiplyDirectionTransform(info.Normal, m.normalsToMapping)
+
+	// The derivatives are computed in mapping space and moved to object space.
+	var dQdu, dQdv mathutils.Vector
+	switch m.projection {
+	case MappingPlanar:
+		info.U, info.V = q.X, q.Z
+		dQdu, dQdv = mathutils.NewVector(1, 0, 0), mathutils.NewVector(0, 0, 1)
+	case MappingSpherical, MappingCylindrical:
+		info.U = (math.Atan2(q.Z, q.X) + math.Pi) / (2 * math.Pi)
+		dQdu = mathutils.NewVector(-2*math.Pi*q.Z, 0, 2*math.Pi*q.X)
+		if m.projection == MappingCylindrical {
+			info.V = q.Y
+			dQdv = mathutils.NewVector(0, 1, 0)
+			break
+		}
+
+		radius := q.Length()
+		radial := math.Hypot(q.X, q.Z)
+		if radius > 0 {
+			info.V = (math.Asin(q.Y/radius) + math.Pi/2) / math.Pi
+		}
+		if radial > 0 {
+			dQdv = mathutils.NewVector(-q.Y*q.X/radial, radial, -q.Y*q.Z/radial)
+			dQdv.Multiply(math.Pi)
+		}
+	case MappingBox, MappingTriplanar:
+		axis := dominantAxis(normal)
+		info.U, info.V, dQdu, dQdv = planarProjection(q, axis)
+	}
+
+	info.DPdu = mathutils.MultiplyDirectionTransform(dQdu, m.placement)
+	info.DPdv = mathutils.MultiplyDirectionTransform(dQdv, m.placement)
+
+	if m.projection != MappingTriplanar {
+		return
+	}
+
+	triplanar := &triplanarHit{}
+	weights := [3]float64{math.Abs(normal.X), math.Abs(normal.Y), math.Abs(normal.Z)}
+	total := 0.0
+	for axis := range weights {
+		weights[axis] = math.Pow(weights[axis], m.sharpness)
+		total += weights[axis]
+	}
+	for axis := range weights {
+		triplanar.weights[axis] = weights[axis] / total
+		triplanar.u[axis], triplanar.v[axis], dQdu, dQdv = planarProjection(q, axis)
+		triplanar.dPdu[axis] = mathutils.MultiplyDirectionTransform(dQdu, m.placement)
+		triplanar.dPdv[axis] = mathutils.MultiplyDirectionTransform(dQdv, m.placement)
+	}
+	info.triplanar = triplanar
+}
+
+// dominantAxis returns 0, 1 or 2 for the axis along which the vector is the longest.
+func dominantAxis(v mathutils.Vector) int {
+	x, y, z := math.Abs(v.X), math.Abs(v.Y), math.Abs(v.Z)
+	if x >= y && x >= z {
+		return 0
+	}
+	if y >= z {
+		return 1
+	}
+
+	return 2
+}
+
+// planarProjection returns the coordinates of the point projected along the axis and their derivatives.
+func planarProjection(q mathutils.Vector, axis int) (u, v float64, dQdu, dQdv mathutils.Vector) {
+	switch axis {
+	case 0:
+		return q.Z, q.Y, mathutils.NewVector(0, 0, 1), mathutils.NewVector(0, 1, 0)
+	case 1:
+		return q.X, q.Z, mathutils.NewVector(1, 0, 0), mathutils.NewVector(0, 0, 1)
+	default:
+		return q.X, q.Y, mathutils.NewVector(1, 0, 0), mathutils.NewVector(0, 1, 0)
+	}
+}
+
+// projection returns the intersection seen through one of the triplanar projections.
+// The changes of U and V to the next pixels are carried over from the dominant projection.
+func (t *triplanarHit) projection(axis int, info *IntersectionInfo) IntersectionInfo {
+	projected := *info
+	projected.triplanar = nil
+	projected.U, projected.V = t.u[axis], t.v[axis]
+	projected.DPdu, projected.DPdv = t.dPdu[axis], t.dPdv[axis]
+
+	dPdx := mathutils.VectorAddition(mathutils.VectorMultiply(info.DPdu, info.DUdx), mathutils.VectorMultiply(info.DPdv, info.DVdx))
+	dPdy := mathutils.VectorAddition(mathutils.VectorMultiply(info.DPdu, info.DUdy), mathutils.VectorMultiply(info.DPdv, info.DVdy))
+	projected.DUdx, projected.DVdx = uvOffset(projected.DPdu, projected.DPdv, dPdx)
+	projected.DUdy, projected.DVdy = uvOffset(projected.DPdu, projected.DPdv, dPdy)
+
+	return projected
+}
+
+// sampleTexture samples the texture at the intersection. With triplanar mapping the texture is sampled
+// once for each projection and the colors are blended.
+func sampleTexture(texture Texture, info *IntersectionInfo) utils.Color {
+	if info.triplanar == nil {
+		return texture.Sample(info)
+	}
+
+	var result utils.Color
+	for axis, weight := range info.triplanar.weights {
+		if weight > 0 {
+			projected := info.triplanar.projection(axis, info)
+			result = utils.ColorAddition(result, utils.MultiplyColorFloat(texture.Sample(&projected), weight))
+		}
+	}
+
+	return result
+}
+
+// blendNormals returns the normal computed by perturb for the intersection. With triplanar mapping the normals
+// for each projection are blended.
+func blendNormals(info *IntersectionInfo, perturb func(*IntersectionInfo) mathutils.Vector) mathutils.Vector {
+	if info.triplanar == nil {
+		return perturb(info)
+	}
+
+	var result mathutils.Vector
+	for axis, weight := range info.triplanar.weights {
+		if weight > 0 {
+			projected := info.triplanar.projection(axis, info)
+			result.Add(mathutils.VectorMultiply(perturb(&projected), weight))
+		}
+	}
+	if result.LengthSqr() == 0 {
+		return info.Normal
+	}
+	result.Normalize()
+
+	return result
+}
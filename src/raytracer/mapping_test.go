@@ -0,0 +1,64 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMapping(t *testing.T) {
+	info := IntersectionInfo{Position: mathutils.NewVector(2, 5, 3), Normal: mathutils.NewVector(0, 1, 0)}
+	planar, _ := NewMapping(MappingPlanar, mathutils.ScalingTransform(mathutils.NewVector(2, 2, 2)), 4)
+	planar.apply(&info)
+	if info.U != 1 || info.V != 1.5 || !compareVectors(info.DPdu, mathutils.NewVector(2, 0, 0)) {
+		t.Errorf("Mapping.apply() failed!")
+	}
+
+	spherical, _ := NewMapping(MappingSpherical, mathutils.NewTransform(), 4)
+	info = IntersectionInfo{Position: mathutils.NewVector(3, 0, 0), Normal: mathutils.NewVector(1, 0, 0)}
+	spherical.apply(&info)
+	if math.Abs(info.U-0.5) > 1e-9 || math.Abs(info.V-0.5) > 1e-9 {
+		t.Errorf("Mapping.apply() failed!")
+	}
+
+	cylindrical, _ := NewMapping(MappingCylindrical, mathutils.NewTransform(), 4)
+	info = IntersectionInfo{Position: mathutils.NewVector(0, 3, 1), Normal: mathutils.NewVector(0, 0, 1)}
+	cylindrical.apply(&info)
+	if math.Abs(info.U-0.75) > 1e-9 || info.V != 3 {
+		t.Errorf("Mapping.apply() failed!")
+	}
+
+	box, _ := NewMapping(MappingBox, mathutils.NewTransform(), 4)
+	info = IntersectionInfo{Position: mathutils.NewVector(1, 2, 3), Normal: mathutils.NewVector(0, 0, -1)}
+	box.apply(&info)
+	if info.U != 1 || info.V != 2 || info.triplanar != nil {
+		t.Errorf("Mapping.apply() failed!")
+	}
+
+	// Halfway between the X and Y axis the projections along them share the weight.
+	triplanar, _ := NewMapping(MappingTriplanar, mathutils.NewTransform(), 1)
+	info = IntersectionInfo{Position: mathutils.NewVector(1, 2, 3), Normal: mathutils.NewVector(math.Sqrt2/2, math.Sqrt2/2, 0)}
+	triplanar.apply(&info)
+	if info.triplanar == nil || math.Abs(sampleTexture(uGradient{}, &info)[0]-2) > 1e-9 {
+		t.Errorf("Mapping.apply() failed!")
+	}
+
+	// The mapping works in object space, so the coordinates move with the node.
+	sphere := NewSphere(mathutils.NewVector(0, 0, 0), 1)
+	node := NewNode(nil, nil)
+	node.SetGeometry(&sphere)
+	node.SetTransform(mathutils.TranslationTransform(mathutils.NewVector(10, 0, 0)))
+	node.SetMapping(planar)
+	ray := NewRay(mathutils.NewVector(10.5, 5, 0.25), mathutils.NewVector(0, -1, 0))
+	info = IntersectionInfo{Distance: math.Inf(1)}
+	if !node.Intersect(&ray, &info) || math.Abs(info.U-0.25) > 1e-9 || math.Abs(info.V-0.125) > 1e-9 {
+		t.Errorf("Node.Intersect() failed!")
+	}
+
+	reader := SceneReader{fileContent: strings.Fields(`mapping { projection triplanar sharpness 8 transform { scale 2 2 2 } } }`)}
+	if err := reader.readNodeMapping(&node); err != nil || node.mapping.projection != MappingTriplanar ||
+		node.mapping.sharpness != 8 || node.mapping.placement[0][0] != 2 || reader.fileContent[reader.position] != "}" {
+		t.Errorf("SceneReader.readNodeMapping() failed!")
+	}
+}
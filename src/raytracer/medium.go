@@ -0,0 +1,202 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+)
+
+// participatingMedium provides the light transport through the medium filling a volume.
+type participatingMedium interface {
+	// integrate returns the light arriving along the ray through the medium between the distances near and far,
+	// given the light behind arriving at far.
+	integrate(ray *Ray, near, far float64, behind utils.Color, scene *Scene) utils.Color
+	// segmentTransmittance returns the share of the light passing through the medium along the normalized
+	// direction from start over the length.
+	segmentTransmittance(start, direction mathutils.Vector, length float64) utils.Color
+}
+
+// Medium defines a homogeneous participating medium like fog or haze, which absorbs and scatters the light
+// passing through it. The coefficients are given per unit of length for the red, green and blue channels.
+type Medium struct {
+	absorption utils.Color
+	scattering utils.Color
+	anisotropy float64 // The g of the Henyey-Greenstein phase function, positive values scatter forward.
+	samples    int     // The number of distance samples along a ray.
+}
+
+// NewMedium creates and returns a new homogeneous medium.
+func NewMedium(absorption, scattering utils.Color, anisotropy float64, samples int) Medium {
+	if samples < 1 {
+		samples = 1
+	}
+	anisotropy = math.Max(-0.99, math.Min(anisotropy, 0.99))
+
+	return Medium{absorption, scattering, anisotropy, samples}
+}
+
+// extinction returns the share of the light absorbed or scattered away per unit of length.
+func (m *Medium) extinction() utils.Color {
+	return utils.ColorAddition(m.absorption, m.scattering)
+}
+
+// transmittance returns the share of the light passing through the medium over the distance.
+func (m *Medium) transmittance(distance float64) utils.Color {
+	extinction := m.extinction()
+	var result utils.Color
+	for channel := range result {
+		if extinction[channel] == 0 {
+			result[channel] = 1
+		} else {
+			result[channel] = math.Exp(-extinction[channel] * distance)
+		}
+	}
+
+	return result
+}
+
+// segmentTransmittance implements participatingMedium for Medium, which is the same everywhere.
+func (m *Medium) segmentTransmittance(start, direction mathutils.Vector, length float64) utils.Color {
+	return m.transmittance(length)
+}
+
+// henyeyGreenstein returns the density of scattering by the angle with the cosine, measured from the direction
+// of the light, for the anisotropy g.
+func henyeyGreenstein(cosTheta, g float64) float64 {
+	denominator := 1 + g*g - 2*g*cosTheta
+
+	return (1 - g*g) / (4 * math.Pi * denominator * math.Sqrt(denominator))
+}
+
+// integrate implements participatingMedium for Medium. The distances of the scattering are sampled proportionally to the
+// transmittance of a channel chosen at random, a sample beyond far uses the light behind instead.
+func (m *Medium) integrate(ray *Ray, near, far float64, behind utils.Color, scene *Scene) utils.Color {
+	extinction := m.extinction()
+	if extinction == (utils.Color{}) || far <= near {
+		return behind
+	}
+
+	length := far - near
+	shiftU, shiftV := hashPosition(mathutils.VectorAddition(ray.Start, ray.Direction))
+	var result utils.Color
+	for i := 0; i < m.samples; i++ {
+		u := math.Mod((float64(i)+0.5)/float64(m.samples)+shiftU, 1)
+		channel := int(math.Min(math.Mod(radicalInverse(uint(i))+shiftV, 1)*3, 2))
+		t := math.Inf(1)
+		if extinction[channel] > 0 {
+			t = -math.Log(1-u) / extinction[channel]
+		}
+
+		// The probability of passing the segment and the density of stopping are averaged over the channels.
+		if t >= length {
+			transmittance := m.transmittance(length)
+			probability := (transmittance[0] + transmittance[1] + transmittance[2]) / 3
+			result = utils.ColorAddition(result, utils.MultiplyColorFloat(utils.ColorMultiplication(behind, transmittance), 1/probability))
+			continue
+		}
+
+		transmittance := m.transmittance(t)
+		density := utils.ColorMultiplication(extinction, transmittance)
+		pdf := (density[0] + density[1] + density[2]) / 3
+		point := mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, near+t))
+		light := utils.ColorMultiplication(inScattered(ray, point, m.anisotropy, scene), utils.ColorMultiplication(m.scattering, transmittance))
+		result = utils.ColorAddition(result, utils.MultiplyColorFloat(light, 1/pdf))
+	}
+
+	return utils.MultiplyColorFloat(result, 1/float64(m.samples))
+}
+
+// inScattered returns the light of the lights of the scene scattered towards the start of the ray at the point
+// by a medium with the anisotropy. Like the shaders leave out the division by pi of a diffuse reflection,
+// the phase function is multiplied by pi, so a medium is lit as brightly as a surface by the same lights.
+func inScattered(ray *Ray, point mathutils.Vector, anisotropy float64, scene *Scene) utils.Color {
+	var result utils.Color
+	for _, sample := range sceneLightSamples(scene, point) {
+		light := utils.ColorMultiplication(sample.intensity, visibility(point, sample.toLight, sample.distance, scene))
+		phase := math.Pi * henyeyGreenstein(mathutils.DotProduct(ray.Direction, sample.toLight), anisotropy)
+		result = utils.ColorAddition(result, utils.MultiplyColorFloat(light, phase))
+	}
+
+	return utils.ColorMultiplication(result, scene.ambientLight)
+}
+
+// Volume defines a shader filling a closed geometry with a medium, which adds to the global one.
+// The surface of the geometry itself is invisible and casts no shadows, the light is only attenuated inside.
+type Volume struct {
+	medium participatingMedium
+}
+
+// NewVolume creates and returns a new volume shader filled with a homogeneous medium.
+func NewVolume(medium Medium) Volume {
+	return Volume{&medium}
+}
+
+// Shade implements a volume shader. A ray leaving the geometry has started inside and gets the medium
+// up to the hit. A ray entering it continues inside, where the medium ends at the next hit.
+func (v *Volume) Shade(ray *Ray, info *IntersectionInfo, scene *Scene) utils.Color {
+	continued := *ray
+	continued.Start = mathutils.VectorAddition(info.Position, mathutils.VectorMultiply(ray.Direction, 1e-5))
+	if v.leaves(ray.Direction, info) {
+		return v.medium.integrate(ray, 0, info.Distance, scene.trace(&continued), scene)
+	}
+
+	behind, next := scene.traceHit(&continued)
+	if next.shader != nil {
+		if volume, ok := (*next.shader).(*Volume); ok && volume == v && v.leaves(ray.Direction, &next) {
+			return behind
+		}
+	}
+
+	return v.medium.integrate(&continued, 0, next.Distance, behind, scene)
+}
+
+// leaves returns true if the direction leaves the geometry at the hit.
+func (v *Volume) leaves(direction mathutils.Vector, info *IntersectionInfo) bool {
+	return mathutils.DotProduct(direction, info.Normal) > 0
+}
+
+// visibility returns the share of the light passing from start to the distance along the normalized direction,
+// black if an object is in the way. The distance may be infinite for lights infinitely far away.
+// The light passes the surfaces of volumes and is attenuated inside them and by the global medium.
+func visibility(start, direction mathutils.Vector, distance float64, scene *Scene) utils.Color {
+	result := utils.Color{1, 1, 1}
+	if scene.medium != nil {
+		ray := NewRay(start, direction)
+		if near, far, ok := scene.mediumSegment(&ray, distance); ok {
+			result = scene.medium.transmittance(far - near)
+		}
+	}
+
+	travelled := 0.0
+	for {
+		ray := NewRay(start, direction)
+		var info IntersectionInfo
+		if !scene.intersect(&ray, &info) {
+			return result
+		}
+
+		var volume *Volume
+		if info.shader != nil {
+			volume, _ = (*info.shader).(*Volume)
+		}
+
+		// Leaving a volume ends a segment inside it, which started at the last hit or at the first start.
+		// The light may also be inside the volume, before the hit.
+		if travelled+info.Distance >= distance {
+			if volume != nil && volume.leaves(direction, &info) {
+				result = utils.ColorMultiplication(result, volume.medium.segmentTransmittance(start, direction, distance-travelled))
+			}
+			return result
+		}
+		if volume == nil {
+			return utils.Color{}
+		}
+		if volume.leaves(direction, &info) {
+			result = utils.ColorMultiplication(result, volume.medium.segmentTransmittance(start, direction, info.Distance))
+		}
+
+		start = mathutils.VectorAddition(info.Position, mathutils.VectorMultiply(direction, 1e-5))
+		travelled += info.Distance + 1e-5
+	}
+}
@@ -0,0 +1,68 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMedium(t *testing.T) {
+	// The phase function is a density over the sphere of directions.
+	total := 0.0
+	for i := 0; i < 1000; i++ {
+		cosTheta := -1 + (float64(i)+0.5)/500
+		total += henyeyGreenstein(cosTheta, 0.6) * 2 * math.Pi / 500
+	}
+	if math.Abs(total-1) > 1e-3 {
+		t.Errorf("henyeyGreenstein() failed!")
+	}
+
+	// A sphere of absorbing medium in front of the white background lets through the light of the distance inside.
+	scene := NewScene()
+	volume := NewVolume(NewMedium(utils.Color{0.01, 0.02, 0.04}, utils.Color{}, 0, 64))
+	var sphere Geometry = &Sphere{mathutils.NewVector(0, 0, 0), 20}
+	var shader Shader = &volume
+	scene.SceneNodes = append(scene.SceneNodes, NewNode(&sphere, &shader))
+	scene.Build()
+
+	expected := utils.Color{math.Exp(-0.4), math.Exp(-0.8), math.Exp(-1.6)}
+	ray := NewRay(mathutils.NewVector(0, 0, -50), mathutils.NewVector(0, 0, 1))
+	color := scene.trace(&ray)
+	shadow := visibility(ray.Start, ray.Direction, 100, &scene)
+	for channel := range color {
+		if math.Abs(color[channel]-expected[channel]) > 0.03 {
+			t.Errorf("Scene.trace() failed!")
+		}
+		if math.Abs(shadow[channel]-expected[channel]) > 1e-6 {
+			t.Errorf("visibility() failed!")
+		}
+	}
+	if inside := visibility(ray.Start, ray.Direction, 40, &scene); math.Abs(inside[2]-math.Exp(-0.4)) > 1e-6 {
+		t.Errorf("visibility() failed!")
+	}
+
+	// A global medium fills the bounding box of the scene and lights up where it scatters.
+	scene.SetMedium(NewMedium(utils.Color{}, utils.Color{0.01, 0.01, 0.01}, 0.5, 16))
+	scene.AddLight(NewLight(mathutils.NewVector(0, 100, 0), utils.Color{1, 1, 1}, 10000))
+	if shadow := visibility(ray.Start, ray.Direction, 100, &scene); math.Abs(shadow[0]-expected[0]*math.Exp(-0.4)) > 1e-6 {
+		t.Errorf("visibility() failed!")
+	}
+	if missed := NewRay(mathutils.NewVector(0, 0, -50), mathutils.NewVector(0, 1, 0)); scene.trace(&missed) != utils.NewColor(255, 255, 255) {
+		t.Errorf("Scene.trace() failed!")
+	}
+
+	reader := SceneReader{fileContent: strings.Fields(`Medium { absorption 0.1 0.2 0.3 scattering 0.5 0.5 0.5 anisotropy 0.3 }
+		shader Volume { scattering 1 1 1 samples 4 } end`)}
+	medium, err := reader.GetMedium()
+	if err != nil || medium.absorption != (utils.Color{0.1, 0.2, 0.3}) || medium.anisotropy != 0.3 || medium.samples != 8 {
+		t.Errorf("SceneReader.GetMedium() failed!")
+	}
+	parsed, err := reader.readShader()
+	if parsed, ok := parsed.(*Volume); err != nil || !ok {
+		t.Errorf("SceneReader.readShader() failed!")
+	} else if medium, ok := parsed.medium.(*Medium); !ok || medium.scattering != (utils.Color{1, 1, 1}) || medium.samples != 4 {
+		t.Errorf("SceneReader.readShader() failed!")
+	}
+}
@@ -0,0 +1,141 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"fmt"
+	"math"
+)
+
+// MeshData holds the vertices and polygons of a mesh as read from a file.
+// The optional vertex attributes are either empty or have one entry per vertex.
+type MeshData struct {
+	Vertices []mathutils.Vector // The vertex positions.
+	Normals  []mathutils.Vector // The vertex normals.
+	UVs      [][2]float64       // The vertex U and V coordinates.
+	Colors   []utils.Color      // The vertex colors.
+	Polygons [][]int            // The vertex indices of every polygon in counterclockwise order.
+}
+
+// Validate checks that the vertex attributes match the vertices and that the polygons reference existing vertices.
+func (m *MeshData) Validate() error {
+	count := len(m.Vertices)
+	if len(m.Normals) != 0 && len(m.Normals) != count {
+		return fmt.Errorf("Mesh has %d normals for %d vertices", len(m.Normals), count)
+	}
+	if len(m.UVs) != 0 && len(m.UVs) != count {
+		return fmt.Errorf("Mesh has %d UV coordinates for %d vertices", len(m.UVs), count)
+	}
+	if len(m.Colors) != 0 && len(m.Colors) != count {
+		return fmt.Errorf("Mesh has %d colors for %d vertices", len(m.Colors), count)
+	}
+
+	for i, polygon := range m.Polygons {
+		if len(polygon) < 3 {
+			return fmt.Errorf("Polygon %d has only %d vertices", i, len(polygon))
+		}
+		for _, index := range polygon {
+			if index < 0 || index >= count {
+				return fmt.Errorf("Polygon %d references vertex %d out of %d", i, index, count)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Mesh defines a geometry made of triangles.
+type Mesh struct {
+	triangles triangleList // The triangles and their vertex attributes.
+	bvh       bvh          // The acceleration structure over the triangles.
+}
+
+// triangleList implements bvhPrimitives for the triangles of a mesh.
+type triangleList struct {
+	MeshData
+	indices [][3]int // The vertex indices of every triangle.
+}
+
+func (t *triangleList) Len() int {
+	return len(t.indices)
+}
+
+func (t *triangleList) BoundingBox(i int) mathutils.BoundingBox {
+	box := mathutils.EmptyBoundingBox()
+	for _, index := range t.indices[i] {
+		box.AddPoint(t.Vertices[index])
+	}
+	return box
+}
+
+func (t *triangleList) Intersect(i int, ray *Ray, info *IntersectionInfo) bool {
+	a, b, c := t.indices[i][0], t.indices[i][1], t.indices[i][2]
+	distance, beta, gamma, ok := intersectTriangle(ray, t.Vertices[a], t.Vertices[b], t.Vertices[c])
+	if !ok {
+		return false
+	}
+
+	alpha := 1 - beta - gamma
+	info.Distance = distance
+	info.Position = mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, distance))
+
+	if len(t.Normals) > 0 {
+		info.Normal = mathutils.VectorMultiply(t.Normals[a], alpha)
+		info.Normal.Add(mathutils.VectorMultiply(t.Normals[b], beta))
+		info.Normal.Add(mathutils.VectorMultiply(t.Normals[c], gamma))
+	} else {
+		info.Normal = mathutils.CrossProduct(mathutils.VectorSubstraction(t.Vertices[b], t.Vertices[a]), mathutils.VectorSubstraction(t.Vertices[c], t.Vertices[a]))
+	}
+	info.Normal.Normalize()
+
+	// Without U and V coordinates, or with degenerate ones, U and V are the barycentric coordinates of b and c.
+	edge1 := mathutils.VectorSubstraction(t.Vertices[b], t.Vertices[a])
+	edge2 := mathutils.VectorSubstraction(t.Vertices[c], t.Vertices[a])
+	info.U, info.V = beta, gamma
+	info.DPdu, info.DPdv = edge1, edge2
+	if len(t.UVs) > 0 {
+		info.U = alpha*t.UVs[a][0] + beta*t.UVs[b][0] + gamma*t.UVs[c][0]
+		info.V = alpha*t.UVs[a][1] + beta*t.UVs[b][1] + gamma*t.UVs[c][1]
+
+		du1, dv1 := t.UVs[b][0]-t.UVs[a][0], t.UVs[b][1]-t.UVs[a][1]
+		du2, dv2 := t.UVs[c][0]-t.UVs[a][0], t.UVs[c][1]-t.UVs[a][1]
+		if determinant := du1*dv2 - dv1*du2; math.Abs(determinant) > 1e-12 {
+			info.DPdu = mathutils.VectorMultiply(mathutils.VectorSubstraction(mathutils.VectorMultiply(edge1, dv2), mathutils.VectorMultiply(edge2, dv1)), 1/determinant)
+			info.DPdv = mathutils.VectorMultiply(mathutils.VectorSubstraction(mathutils.VectorMultiply(edge2, du1), mathutils.VectorMultiply(edge1, du2)), 1/determinant)
+		}
+	}
+
+	info.HasVertexColor = len(t.Colors) > 0
+	if info.HasVertexColor {
+		info.VertexColor = utils.MultiplyColorFloat(t.Colors[a], alpha)
+		info.VertexColor = utils.ColorAddition(info.VertexColor, utils.MultiplyColorFloat(t.Colors[b], beta))
+		info.VertexColor = utils.ColorAddition(info.VertexColor, utils.MultiplyColorFloat(t.Colors[c], gamma))
+	}
+
+	return true
+}
+
+// NewMesh creates and returns a new mesh from the mesh data.
+// Polygons with more than three vertices are split into triangle fans.
+// Without vertex normals the mesh is flat shaded with normals following the polygon winding.
+func NewMesh(data MeshData) Mesh {
+	triangles := triangleList{MeshData: data}
+	for _, polygon := range data.Polygons {
+		for i := 2; i < len(polygon); i++ {
+			triangles.indices = append(triangles.indices, [3]int{polygon[0], polygon[i-1], polygon[i]})
+		}
+	}
+
+	return Mesh{triangles, newBVH(&triangles)}
+}
+
+// Intersect implements the intersect method of the Geometry interface for Mesh.
+func (m *Mesh) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	return m.bvh.intersect(&m.triangles, ray, info)
+}
+
+// BoundingBox implements the Bounded interface for Mesh.
+func (m *Mesh) BoundingBox() mathutils.BoundingBox {
+	return m.bvh.boundingBox()
+}
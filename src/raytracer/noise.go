@@ -0,0 +1,83 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+)
+
+// Patterns of the noise texture.
+const (
+	NoiseFBm        = iota // Fractional Brownian motion, soft clouds.
+	NoiseTurbulence        // Sum of absolute noise, billowy clouds with creases.
+	NoiseMarble            // Sine bands distorted by turbulence.
+	NoiseWood              // Rings around the Y axis distorted by noise.
+	NoiseWorley            // Cells around scattered feature points.
+)
+
+// Noise defines a procedural texture blending two colors by a noise pattern.
+type Noise struct {
+	pattern int
+	color1  utils.Color // The color where the pattern is 0.
+	color2  utils.Color // The color where the pattern is 1.
+	scale   float64     // The frequency of the pattern, a scale of 1 varies about once per unit.
+	octaves float64     // The number of noise octaves summed by the patterns built on fBm or turbulence.
+	useUV   bool        // Whether the pattern is sampled from U and V instead of the position.
+}
+
+// NewNoise creates and returns a new noise texture with the given pattern.
+func NewNoise(pattern int, color1, color2 utils.Color, scale, octaves float64, useUV bool) Noise {
+	return Noise{pattern, color1, color2, scale, octaves, useUV}
+}
+
+// Sample implements sampling for Noise.
+// With ray differentials the octaves finer than the footprint of the pixel are left out so the pattern
+// does not alias in the distance.
+func (n *Noise) Sample(info *IntersectionInfo) utils.Color {
+	p, footprint := n.point(info)
+	octaves := n.octaves
+	if footprint > 0 {
+		// Octave i has a period of 2^-i, it is dropped once a pixel covers half of its period.
+		octaves = math.Max(0, math.Min(octaves, -math.Log2(footprint)))
+	}
+
+	var t float64
+	switch n.pattern {
+	case NoiseFBm:
+		t = 0.5 + 0.5*mathutils.FBm(p, octaves)
+	case NoiseTurbulence:
+		// The octaves which were left out are replaced by the average of the absolute noise.
+		t = mathutils.Turbulence(p, octaves) + 0.2*2*(math.Pow(0.5, octaves)-math.Pow(0.5, n.octaves))
+	case NoiseMarble:
+		t = 0.5 + 0.5*math.Sin(p.X+3*mathutils.Turbulence(p, octaves))
+	case NoiseWood:
+		rings := math.Hypot(p.X, p.Z) + 0.3*mathutils.FBm(p, octaves)
+		t = 0.5 - 0.5*math.Cos(2*math.Pi*rings)
+		if footprint > 0.5 {
+			// The rings are closer than a pixel, use their average.
+			t = 0.5
+		}
+	case NoiseWorley:
+		t, _ = mathutils.Worley(p)
+	}
+	t = math.Max(0, math.Min(t, 1))
+
+	return utils.ColorAddition(utils.MultiplyColorFloat(n.color1, 1-t), utils.MultiplyColorFloat(n.color2, t))
+}
+
+// point returns the scaled point where the pattern is sampled and the size of the pixel footprint around it,
+// which is zero without ray differentials.
+func (n *Noise) point(info *IntersectionInfo) (mathutils.Vector, float64) {
+	if n.useUV {
+		footprint := math.Max(math.Hypot(info.DUdx, info.DVdx), math.Hypot(info.DUdy, info.DVdy))
+		return mathutils.NewVector(info.U*n.scale, info.V*n.scale, 0), footprint * n.scale
+	}
+
+	// The offsets of the position to the next pixels follow from the changes of U and V.
+	dPdx := mathutils.VectorAddition(mathutils.VectorMultiply(info.DPdu, info.DUdx), mathutils.VectorMultiply(info.DPdv, info.DVdx))
+	dPdy := mathutils.VectorAddition(mathutils.VectorMultiply(info.DPdu, info.DUdy), mathutils.VectorMultiply(info.DPdv, info.DVdy))
+	footprint := math.Max(dPdx.Length(), dPdy.Length())
+
+	return mathutils.VectorMultiply(info.Position, n.scale), footprint * n.scale
+}
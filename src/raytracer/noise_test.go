@@ -0,0 +1,39 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+	"testing"
+)
+
+func TestNoiseSample(t *testing.T) {
+	black, white := utils.Color{0, 0, 0}, utils.Color{1, 1, 1}
+	info := IntersectionInfo{Position: mathutils.NewVector(1.3, 0.7, -2.1), U: 0.4, V: 0.6}
+	for _, pattern := range []int{NoiseFBm, NoiseTurbulence, NoiseMarble, NoiseWood, NoiseWorley} {
+		noise := NewNoise(pattern, black, white, 2, 4, false)
+		color := noise.Sample(&info)
+		if color[0] < 0 || color[0] > 1 || color[0] != color[1] {
+			t.Errorf("Noise.Sample() failed!")
+		}
+	}
+
+	fbm := NewNoise(NoiseFBm, black, white, 2, 4, false)
+	expected := 0.5 + 0.5*mathutils.FBm(mathutils.VectorMultiply(info.Position, 2), 4)
+	if color := fbm.Sample(&info); math.Abs(color[0]-expected) > 1e-9 {
+		t.Errorf("Noise.Sample() failed!")
+	}
+
+	uv := NewNoise(NoiseFBm, black, white, 2, 4, true)
+	expected = 0.5 + 0.5*mathutils.FBm(mathutils.NewVector(0.8, 1.2, 0), 4)
+	if color := uv.Sample(&info); math.Abs(color[0]-expected) > 1e-9 {
+		t.Errorf("Noise.Sample() failed!")
+	}
+
+	// A footprint larger than the coarsest octave leaves only the mean.
+	info.DPdu, info.DPdv = mathutils.NewVector(1, 0, 0), mathutils.NewVector(0, 1, 0)
+	info.DUdx, info.DVdy = 10, 10
+	if color := fbm.Sample(&info); color[0] != 0.5 {
+		t.Errorf("Noise.Sample() failed!")
+	}
+}
@@ -0,0 +1,345 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// plyProperty describes a property of a PLY element.
+type plyProperty struct {
+	name      string
+	valueType string // The type of the value, or of the list items for list properties.
+	countType string // The type of the item count for list properties, empty otherwise.
+}
+
+// plyElement describes an element of a PLY file such as vertex or face.
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+// plyValueReader reads the values of a PLY file body in ASCII or binary format.
+type plyValueReader interface {
+	read(valueType string) (float64, error)
+}
+
+// plyASCIIReader reads whitespace separated values.
+type plyASCIIReader struct {
+	scanner *bufio.Scanner
+}
+
+func (p *plyASCIIReader) read(valueType string) (float64, error) {
+	if !p.scanner.Scan() {
+		if err := p.scanner.Err(); err != nil {
+			return 0, err
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	return strconv.ParseFloat(p.scanner.Text(), 64)
+}
+
+// plyBinaryReader reads little endian binary values.
+type plyBinaryReader struct {
+	reader io.Reader
+}
+
+func (p *plyBinaryReader) read(valueType string) (float64, error) {
+	var err error
+	switch valueType {
+	case "char", "int8":
+		var value int8
+		err = binary.Read(p.reader, binary.LittleEndian, &value)
+		return float64(value), err
+	case "uchar", "uint8":
+		var value uint8
+		err = binary.Read(p.reader, binary.LittleEndian, &value)
+		return float64(value), err
+	case "short", "int16":
+		var value int16
+		err = binary.Read(p.reader, binary.LittleEndian, &value)
+		return float64(value), err
+	case "ushort", "uint16":
+		var value uint16
+		err = binary.Read(p.reader, binary.LittleEndian, &value)
+		return float64(value), err
+	case "int", "int32":
+		var value int32
+		err = binary.Read(p.reader, binary.LittleEndian, &value)
+		return float64(value), err
+	case "uint", "uint32":
+		var value uint32
+		err = binary.Read(p.reader, binary.LittleEndian, &value)
+		return float64(value), err
+	case "float", "float32":
+		var value float32
+		err = binary.Read(p.reader, binary.LittleEndian, &value)
+		return float64(value), err
+	default:
+		var value float64
+		err = binary.Read(p.reader, binary.LittleEndian, &value)
+		return value, err
+	}
+}
+
+// plyTypes holds the value types allowed in PLY files.
+var plyTypes = map[string]bool{
+	"char": true, "uchar": true, "short": true, "ushort": true, "int": true, "uint": true, "float": true, "double": true,
+	"int8": true, "uint8": true, "int16": true, "uint16": true, "int32": true, "uint32": true, "float32": true, "float64": true,
+}
+
+// ReadPLY reads a mesh in the PLY format, either ASCII or binary little endian.
+// It reads the vertex positions, normals (nx, ny, nz), UV coordinates (u, v or s, t),
+// colors (red, green, blue) and the faces. Other elements and properties are skipped.
+func ReadPLY(reader io.Reader) (data MeshData, err error) {
+	buffered := bufio.NewReader(reader)
+	format, elements, err := readPLYHeader(buffered)
+	if err != nil {
+		return
+	}
+
+	var values plyValueReader
+	if format == "ascii" {
+		scanner := bufio.NewScanner(buffered)
+		scanner.Split(bufio.ScanWords)
+		values = &plyASCIIReader{scanner}
+	} else {
+		values = &plyBinaryReader{buffered}
+	}
+
+	for _, element := range elements {
+		switch element.name {
+		case "vertex":
+			err = readPLYVertices(values, element, &data)
+		case "face":
+			err = readPLYFaces(values, element, &data)
+		default:
+			err = skipPLYElement(values, element)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	if len(data.Vertices) == 0 {
+		err = fmt.Errorf("PLY file has no vertices")
+		return
+	}
+
+	err = data.Validate()
+	return
+}
+
+// readPLYHeader reads the header up to end_header and returns the format and the elements.
+func readPLYHeader(reader *bufio.Reader) (format string, elements []plyElement, err error) {
+	line, err := reader.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		err = fmt.Errorf("Not a PLY file")
+		return
+	}
+
+	for lineNumber := 2; ; lineNumber++ {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			err = fmt.Errorf("PLY header ends without end_header")
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "comment", "obj_info":
+		case "format":
+			if len(fields) != 3 {
+				err = fmt.Errorf("PLY header line %d: incorrect format line", lineNumber)
+				return
+			}
+			format = fields[1]
+			if format != "ascii" && format != "binary_little_endian" {
+				err = fmt.Errorf("PLY header line %d: unsupported format %s", lineNumber, format)
+				return
+			}
+		case "element":
+			var count int
+			if len(fields) == 3 {
+				count, err = strconv.Atoi(fields[2])
+			}
+			if len(fields) != 3 || err != nil || count < 0 {
+				err = fmt.Errorf("PLY header line %d: incorrect element line", lineNumber)
+				return
+			}
+			elements = append(elements, plyElement{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				err = fmt.Errorf("PLY header line %d: property outside of an element", lineNumber)
+				return
+			}
+			var property plyProperty
+			switch {
+			case len(fields) == 3 && plyTypes[fields[1]]:
+				property = plyProperty{name: fields[2], valueType: fields[1]}
+			case len(fields) == 5 && fields[1] == "list" && plyTypes[fields[2]] && plyTypes[fields[3]]:
+				property = plyProperty{name: fields[4], valueType: fields[3], countType: fields[2]}
+			default:
+				err = fmt.Errorf("PLY header line %d: incorrect property line", lineNumber)
+				return
+			}
+			last := &elements[len(elements)-1]
+			last.properties = append(last.properties, property)
+		case "end_header":
+			if format == "" {
+				err = fmt.Errorf("PLY header has no format line")
+			}
+			return
+		default:
+			err = fmt.Errorf("PLY header line %d: unknown keyword %s", lineNumber, fields[0])
+			return
+		}
+	}
+}
+
+// readPLYVertices reads the vertex element into the mesh data.
+func readPLYVertices(values plyValueReader, element plyElement, data *MeshData) error {
+	index := make(map[string]int)
+	for i, property := range element.properties {
+		if property.countType != "" {
+			return fmt.Errorf("PLY vertex property %s cannot be a list", property.name)
+		}
+		index[property.name] = i
+	}
+
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := index[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !has("x", "y", "z") {
+		return fmt.Errorf("PLY vertices have no x, y and z properties")
+	}
+	hasNormals := has("nx", "ny", "nz")
+	uName, vName := "u", "v"
+	if !has(uName, vName) {
+		uName, vName = "s", "t"
+	}
+	hasUVs := has(uName, vName)
+	hasColors := has("red", "green", "blue")
+
+	// Integer colors are in [0, 255] and floating point ones in [0, 1].
+	colorScale := 1.0
+	if hasColors && !strings.HasPrefix(element.properties[index["red"]].valueType, "float") && element.properties[index["red"]].valueType != "double" {
+		colorScale = 1.0 / 255
+	}
+
+	row := make([]float64, len(element.properties))
+	for i := 0; i < element.count; i++ {
+		for j, property := range element.properties {
+			value, err := values.read(property.valueType)
+			if err != nil {
+				return fmt.Errorf("PLY vertex %d: %v", i, err)
+			}
+			row[j] = value
+		}
+
+		data.Vertices = append(data.Vertices, vectorOf(row, index["x"], index["y"], index["z"]))
+		if hasNormals {
+			data.Normals = append(data.Normals, vectorOf(row, index["nx"], index["ny"], index["nz"]))
+		}
+		if hasUVs {
+			data.UVs = append(data.UVs, [2]float64{row[index[uName]], row[index[vName]]})
+		}
+		if hasColors {
+			data.Colors = append(data.Colors, utils.Color{row[index["red"]] * colorScale, row[index["green"]] * colorScale, row[index["blue"]] * colorScale})
+		}
+	}
+
+	return nil
+}
+
+// readPLYFaces reads the vertex_indices (or vertex_index) list of the face element into the mesh data.
+func readPLYFaces(values plyValueReader, element plyElement, data *MeshData) error {
+	found := false
+	for _, property := range element.properties {
+		if property.countType != "" && (property.name == "vertex_indices" || property.name == "vertex_index") {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("PLY faces have no vertex_indices list")
+	}
+
+	for i := 0; i < element.count; i++ {
+		for _, property := range element.properties {
+			if property.countType == "" {
+				if _, err := values.read(property.valueType); err != nil {
+					return fmt.Errorf("PLY face %d: %v", i, err)
+				}
+				continue
+			}
+
+			count, err := values.read(property.countType)
+			if err != nil {
+				return fmt.Errorf("PLY face %d: %v", i, err)
+			}
+			if count < 0 || count > math.MaxUint16 {
+				return fmt.Errorf("PLY face %d: incorrect list length %v", i, count)
+			}
+
+			items := make([]int, int(count))
+			for j := range items {
+				value, err := values.read(property.valueType)
+				if err != nil {
+					return fmt.Errorf("PLY face %d: %v", i, err)
+				}
+				items[j] = int(value)
+			}
+
+			if property.name == "vertex_indices" || property.name == "vertex_index" {
+				data.Polygons = append(data.Polygons, items)
+			}
+		}
+	}
+
+	return nil
+}
+
+// skipPLYElement reads and discards all values of an element.
+func skipPLYElement(values plyValueReader, element plyElement) error {
+	for i := 0; i < element.count; i++ {
+		for _, property := range element.properties {
+			count := 1.0
+			var err error
+			if property.countType != "" {
+				count, err = values.read(property.countType)
+			}
+			for j := 0; err == nil && j < int(count); j++ {
+				_, err = values.read(property.valueType)
+			}
+			if err != nil {
+				return fmt.Errorf("PLY %s %d: %v", element.name, i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// vectorOf returns the vector made of the values at the three indices.
+func vectorOf(values []float64, x, y, z int) mathutils.Vector {
+	return mathutils.NewVector(values[x], values[y], values[z])
+}
@@ -0,0 +1,69 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+const testPLYHeader = `ply
+format %s 1.0
+comment a unit quad in the XZ plane
+element vertex 4
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+element face 1
+property list uchar int vertex_indices
+end_header
+`
+
+func TestReadPLY(t *testing.T) {
+	ascii := strings.Replace(testPLYHeader, "%s", "ascii", 1) + `0 0 0 255 0 0
+0 0 1 255 0 0
+1 0 1 0 0 255
+1 0 0 0 0 255
+4 0 1 2 3
+`
+	data, err := ReadPLY(strings.NewReader(ascii))
+	if err != nil || len(data.Vertices) != 4 || len(data.Colors) != 4 || len(data.Polygons) != 1 || len(data.Polygons[0]) != 4 {
+		t.Errorf("ReadPLY() failed!")
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(strings.Replace(testPLYHeader, "%s", "binary_little_endian", 1))
+	for _, vertex := range data.Vertices {
+		binary.Write(&buffer, binary.LittleEndian, [3]float32{float32(vertex.X), float32(vertex.Y), float32(vertex.Z)})
+		binary.Write(&buffer, binary.LittleEndian, [3]uint8{255, 0, 0})
+	}
+	binary.Write(&buffer, binary.LittleEndian, uint8(4))
+	binary.Write(&buffer, binary.LittleEndian, [4]int32{0, 1, 2, 3})
+	binaryData, err := ReadPLY(&buffer)
+	if err != nil || len(binaryData.Vertices) != 4 || !compareVectors(binaryData.Vertices[2], data.Vertices[2]) || binaryData.Colors[3][0] != 1 {
+		t.Errorf("ReadPLY() failed!")
+	}
+
+	mesh := NewMesh(data)
+	var info IntersectionInfo
+	ray := NewRay(mathutils.NewVector(0.75, 5, 0.25), mathutils.NewVector(0, -1, 0))
+	if !mesh.Intersect(&ray, &info) || math.Abs(info.Distance-5) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 1, 0)) {
+		t.Errorf("Mesh.Intersect() failed!")
+	}
+	if !info.HasVertexColor || math.Abs(info.VertexColor[0]-0.25) > 1e-6 || math.Abs(info.VertexColor[2]-0.75) > 1e-6 {
+		t.Errorf("Mesh.Intersect() failed!")
+	}
+
+	broken := strings.Replace(ascii, "4 0 1 2 3", "3 0 1 7", 1)
+	if _, err := ReadPLY(strings.NewReader(broken)); err == nil {
+		t.Errorf("ReadPLY() failed!")
+	}
+	if _, err := ReadPLY(strings.NewReader("ply\nformat ascii 1.0\nelement vertex 2\n")); err == nil {
+		t.Errorf("ReadPLY() failed!")
+	}
+}
@@ -6,11 +6,18 @@ import (
 )
 
 // Ray defines a ray in the 3-dimentional space.
+// Camera rays also carry the differentials: the rays through the next pixels along X and Y of the screen.
 type Ray struct {
 	Start, Direction mathutils.Vector
+
+	HasDifferentials   bool             // Whether the differentials are set.
+	XStart, XDirection mathutils.Vector // The ray through the next pixel along X.
+	YStart, YDirection mathutils.Vector // The ray through the next pixel along Y.
+
+	depth int // The number of reflections before the ray, limited by maxTraceDepth.
 }
 
 // NewRay creates and returns a new ray.
 func NewRay(start, direction mathutils.Vector) Ray {
-	return Ray{start, direction}
+	return Ray{Start: start, Direction: direction}
 }
@@ -2,8 +2,11 @@
 package raytracer
 
 import (
+	"GoRaytracer/src/mathutils"
 	"GoRaytracer/src/utils"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -34,9 +37,14 @@ func NewRenderManager() RenderManager {
 	return RenderManager{0, 0, nil, nil, nil, RenderingNotStarted}
 }
 
-// Setup sets up the current RenderManager from a scene file.
+// Setup sets up the current RenderManager from a scene file or a glTF file (.gltf or .glb).
 func (r *RenderManager) Setup(fileName string) {
-	r.setupScene(fileName)
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gltf", ".glb":
+		r.setupGLTF(fileName)
+	default:
+		r.setupScene(fileName)
+	}
 }
 
 // Render prepares and starts the rendering.
@@ -88,25 +96,7 @@ func (r *RenderManager) render(pixels chan Pixel) {
 }
 
 func (r *RenderManager) raytrace(ray *Ray) utils.Color {
-	var info IntersectionInfo
-	closestDistance := 1e99
-	var closestInfo IntersectionInfo
-	var closestNode Node
-	for _, node := range r.scene.SceneNodes {
-		if (*node.GetGeometry()).Intersect(ray, &info) {
-			if info.Distance < closestDistance {
-				closestDistance = info.Distance
-				closestInfo = info
-				closestNode = node
-			}
-		}
-	}
-
-	if closestDistance < 1e99 {
-		return (*closestNode.GetShader()).Shade(ray, &closestInfo, r.scene)
-	}
-
-	return utils.NewColor(255, 255, 255)
+	return r.scene.trace(ray)
 }
 
 func (r *RenderManager) setupScene(fileName string) {
@@ -126,12 +116,7 @@ func (r *RenderManager) setupScene(fileName string) {
 	r.frameWidth = frameWidth
 	r.frameHeight = frameHeight
 
-	r.frameWidth = 640
-	r.frameHeight = 480
-	r.vfb = make([][]utils.Color, r.frameHeight)
-	for row := range r.vfb {
-		r.vfb[row] = make([]utils.Color, r.frameWidth)
-	}
+	r.setupFrame()
 
 	scene := NewScene()
 	r.scene = &scene
@@ -158,10 +143,90 @@ func (r *RenderManager) setupScene(fileName string) {
 		return
 	}
 
+	// Read the optional environment
+	environment, err := sceneReader.GetEnvironment()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if environment != nil {
+		r.scene.SetEnvironment(*environment)
+		if sun, ok := environment.Sun(); ok {
+			r.scene.AddLight(sun)
+		}
+	}
+
+	// Read the optional global medium
+	medium, err := sceneReader.GetMedium()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if medium != nil {
+		r.scene.SetMedium(*medium)
+	}
+
 	// Read scene nodes
 	r.scene.SceneNodes, err = sceneReader.GetSceneNodes()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
+	r.scene.Build()
+}
+
+// setupFrame creates the display buffer.
+func (r *RenderManager) setupFrame() {
+	r.frameWidth = 640
+	r.frameHeight = 480
+	r.vfb = make([][]utils.Color, r.frameHeight)
+	for row := range r.vfb {
+		r.vfb[row] = make([]utils.Color, r.frameWidth)
+	}
+}
+
+// setupGLTF sets up the scene from a glTF file with a white ambient light.
+// The first camera of the file is used, without cameras the scene is viewed from the front.
+// Without lights a light is placed at the camera.
+func (r *RenderManager) setupGLTF(fileName string) {
+	gltf, err := ReadGLTF(fileName)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	r.setupFrame()
+
+	scene := NewScene()
+	r.scene = &scene
+	r.scene.ambientLight = utils.Color{1, 1, 1}
+	for _, light := range gltf.Lights {
+		r.scene.AddLight(light)
+	}
+	for _, light := range gltf.SpotLights {
+		r.scene.AddLight(light)
+	}
+	for _, light := range gltf.DirectionalLights {
+		r.scene.AddLight(light)
+	}
+	r.scene.SceneNodes = []Node{gltf.Root}
+	r.scene.Build()
+
+	bounds := gltf.Root.BoundingBox()
+	if bounds.IsEmpty() || bounds.IsInfinite() {
+		bounds = mathutils.NewBoundingBox(mathutils.NewVector(-1, -1, -1), mathutils.NewVector(1, 1, 1))
+	}
+	size := mathutils.VectorSubstraction(bounds.Max, bounds.Min)
+	if len(gltf.Cameras) > 0 {
+		r.camera = &gltf.Cameras[0]
+	} else {
+		position := bounds.Center()
+		position.Z = bounds.Min.Z - size.Length()
+		camera := NewTransformedCamera(mathutils.TranslationTransform(position), 45, float64(r.frameWidth)/float64(r.frameHeight))
+		r.camera = &camera
+	}
+
+	if len(r.scene.lights) == 0 {
+		r.scene.AddLight(NewLight(r.camera.position, utils.Color{1, 1, 1}, size.LengthSqr()*2))
+	}
 }
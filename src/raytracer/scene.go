@@ -1,18 +1,25 @@
 // Package raytracer provides the raytracer logic.
 package raytracer
 
-import "GoRaytracer/src/utils"
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+)
 
 // Scene defines a holder for the all the scene elements.
 type Scene struct {
-	SceneNodes   []Node      // Holds all the nodes in the scene.
-	lights       []Light     // Holds all the lights in the scene.
-	ambientLight utils.Color // Holds the ambient light of the scene.
+	SceneNodes   []Node        // Holds all the top level nodes in the scene.
+	lights       []LightSource // Holds all the lights in the scene.
+	ambientLight utils.Color   // Holds the ambient light of the scene.
+	environment  *Environment  // Holds the environment seen by the rays which miss the scene, nil for plain white.
+	medium       *Medium       // Holds the medium filling the bounding box of the scene, nil for none.
+	root         Group         // Holds the acceleration structure over the scene nodes.
 }
 
 // NewScene creates a new empty scene with a default ambient light.
 func NewScene() Scene {
-	return Scene{make([]Node, 0), make([]Light, 0), utils.Color{0.5, 0.5, 0.5}}
+	return Scene{make([]Node, 0), make([]LightSource, 0), utils.Color{0.5, 0.5, 0.5}, nil, nil, NewGroup(nil)}
 }
 
 // SetAmbientLight sets the ambient light of the scene to the specified color.
@@ -20,8 +27,27 @@ func (s *Scene) SetAmbientLight(color utils.Color) {
 	s.ambientLight = color
 }
 
+// SetEnvironment sets the environment which surrounds the scene and lights it.
+func (s *Scene) SetEnvironment(environment Environment) {
+	s.environment = &environment
+}
+
+// SetMedium sets the medium which fills the bounding box of the scene.
+func (s *Scene) SetMedium(medium Medium) {
+	s.medium = &medium
+}
+
+// background returns the color seen by a ray which misses the scene.
+func (s *Scene) background(ray *Ray) utils.Color {
+	if s.environment == nil {
+		return utils.NewColor(255, 255, 255)
+	}
+
+	return s.environment.Radiance(ray.Direction)
+}
+
 // AddLight adds a light to the scene.
-func (s *Scene) AddLight(light Light) {
+func (s *Scene) AddLight(light LightSource) {
 	s.lights = append(s.lights, light)
 }
 
@@ -29,3 +55,54 @@ func (s *Scene) AddLight(light Light) {
 func (s *Scene) AddNode(geometry Geometry, shader Shader) {
 	s.SceneNodes = append(s.SceneNodes, NewNode(&geometry, &shader))
 }
+
+// Build builds the acceleration structure of the scene.
+// It has to be called after the scene nodes are added and before rendering.
+func (s *Scene) Build() {
+	s.root = NewGroup(s.SceneNodes)
+}
+
+// intersect finds the closest intersection of the ray with the scene.
+func (s *Scene) intersect(ray *Ray, info *IntersectionInfo) bool {
+	return s.root.Intersect(ray, info)
+}
+
+// trace returns the light arriving along the ray, from the surface it hits or the background,
+// through the medium of the scene.
+func (s *Scene) trace(ray *Ray) utils.Color {
+	color, _ := s.traceHit(ray)
+	return color
+}
+
+// traceHit returns the light arriving along the ray and its hit, which has no shader and an infinite distance
+// if the ray misses the scene.
+func (s *Scene) traceHit(ray *Ray) (utils.Color, IntersectionInfo) {
+	var info IntersectionInfo
+	var color utils.Color
+	if s.intersect(ray, &info) {
+		info.computeDifferentials(ray)
+		color = (*info.shader).Shade(ray, &info, s)
+	} else {
+		info = IntersectionInfo{Distance: math.Inf(1)}
+		color = s.background(ray)
+	}
+
+	if s.medium != nil {
+		if near, far, ok := s.mediumSegment(ray, info.Distance); ok {
+			color = s.medium.integrate(ray, near, far, color, s)
+		}
+	}
+
+	return color, info
+}
+
+// mediumSegment returns the part of the ray up to the distance inside the bounding box of the scene,
+// which the medium of the scene fills. Returns false if there is none.
+func (s *Scene) mediumSegment(ray *Ray, distance float64) (float64, float64, bool) {
+	bounds := s.root.BoundingBox()
+	inverseDirection := mathutils.NewVector(1/ray.Direction.X, 1/ray.Direction.Y, 1/ray.Direction.Z)
+	near, far, ok := bounds.IntersectRay(ray.Start, inverseDirection)
+	near, far = math.Max(near, 0), math.Min(far, distance)
+
+	return near, far, ok && near < far
+}
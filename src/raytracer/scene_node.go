@@ -1,15 +1,26 @@
 // Package raytracer provides the raytracer logic.
 package raytracer
 
+import "GoRaytracer/src/mathutils"
+
 // Node defines a scene node.
 type Node struct {
-	geometry *Geometry // A pointer to the geometry of the node.
-	shader   *Shader   // A pointer to the shader of the node.
+	geometry  *Geometry      // A pointer to the geometry of the node.
+	shader    *Shader        // A pointer to the shader of the node.
+	transform *nodeTransform // The placement of the node in the world, nil if the geometry is in world space.
+	mapping   *Mapping       // The projection replacing the U and V of the geometry, nil to keep them.
+}
+
+// nodeTransform holds the matrices needed to move rays and hits between world and object space.
+type nodeTransform struct {
+	toWorld        mathutils.Transform // Object space to world space.
+	toObject       mathutils.Transform // World space to object space.
+	normalsToWorld mathutils.Transform // Object space normals to world space normals.
 }
 
 // NewNode creates and return a new scene node.
 func NewNode(geometry *Geometry, shader *Shader) Node {
-	return Node{geometry, shader}
+	return Node{geometry, shader, nil, nil}
 }
 
 // GetGeometry returns the geometry associated with the scene node.
@@ -22,6 +33,15 @@ func (n *Node) GetShader() *Shader {
 	return n.shader
 }
 
+// GetTransform returns the object to world transformation of the scene node.
+func (n *Node) GetTransform() mathutils.Transform {
+	if n.transform == nil {
+		return mathutils.NewTransform()
+	}
+
+	return n.transform.toWorld
+}
+
 // SetGeometry sets the geometry for the current scene node.
 func (n *Node) SetGeometry(geometry Geometry) {
 	n.geometry = &geometry
@@ -31,3 +51,125 @@ func (n *Node) SetGeometry(geometry Geometry) {
 func (n *Node) SetShader(shader Shader) {
 	n.shader = &shader
 }
+
+// SetTransform sets the object to world transformation for the current scene node.
+// Returns false and leaves the node unchanged if the transformation cannot be inverted.
+func (n *Node) SetTransform(transform mathutils.Transform) bool {
+	toObject, ok := transform.Inverse()
+	if !ok {
+		return false
+	}
+
+	n.transform = &nodeTransform{transform, toObject, toObject.Transpose()}
+	return true
+}
+
+// SetMapping sets the projection which replaces the U and V coordinates of the geometry of the node.
+func (n *Node) SetMapping(mapping Mapping) {
+	n.mapping = &mapping
+}
+
+// Intersect intersects the ray with the geometry of the node.
+// The ray is moved to object space and the hit is moved back to world space.
+// The hit gets the shader of the node unless the geometry already provided a more specific one.
+func (n *Node) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	info.reset()
+	if !n.intersectGeometry(ray, info) {
+		return false
+	}
+
+	if info.shader == nil {
+		info.shader = n.shader
+	}
+	return true
+}
+
+// IntersectAll returns all the hits of the ray with the geometry of the node in world space.
+// It implements the Solid interface for nodes whose geometry is a Solid and returns nil otherwise.
+func (n *Node) IntersectAll(ray *Ray) []IntersectionInfo {
+	solid, ok := (*n.geometry).(Solid)
+	if !ok {
+		return nil
+	}
+
+	if n.transform == nil {
+		hits := solid.IntersectAll(ray)
+		for i := range hits {
+			n.applyMapping(&hits[i])
+		}
+		return hits
+	}
+
+	objectRay, scale := n.toObject(ray)
+	hits := solid.IntersectAll(&objectRay)
+	for i := range hits {
+		n.applyMapping(&hits[i])
+		n.toWorld(&hits[i], scale)
+	}
+
+	return hits
+}
+
+// BoundingBox returns the world space bounding box of the node.
+func (n *Node) BoundingBox() mathutils.BoundingBox {
+	bounded, ok := (*n.geometry).(Bounded)
+	if !ok {
+		return mathutils.InfiniteBoundingBox()
+	}
+
+	if n.transform == nil {
+		return bounded.BoundingBox()
+	}
+	return mathutils.TransformBoundingBox(bounded.BoundingBox(), n.transform.toWorld)
+}
+
+func (n *Node) intersectGeometry(ray *Ray, info *IntersectionInfo) bool {
+	if n.transform == nil {
+		if !(*n.geometry).Intersect(ray, info) {
+			return false
+		}
+		n.applyMapping(info)
+		return true
+	}
+
+	objectRay, scale := n.toObject(ray)
+	if !(*n.geometry).Intersect(&objectRay, info) {
+		return false
+	}
+
+	n.applyMapping(info)
+	n.toWorld(info, scale)
+	return true
+}
+
+// applyMapping replaces the coordinates of the object space hit by the ones of the mapping of the node, if any.
+func (n *Node) applyMapping(info *IntersectionInfo) {
+	if n.mapping != nil {
+		n.mapping.apply(info)
+	}
+}
+
+// toObject returns the ray moved to object space with a normalized direction,
+// and the factor by which object space distances have to be divided.
+func (n *Node) toObject(ray *Ray) (Ray, float64) {
+	direction := mathutils.MultiplyDirectionTransform(ray.Direction, n.transform.toObject)
+	scale := direction.Length()
+	direction.Multiply(1 / scale)
+	return NewRay(mathutils.MultiplyPointTransform(ray.Start, n.transform.toObject), direction), scale
+}
+
+// toWorld moves a hit found in object space to world space.
+func (n *Node) toWorld(info *IntersectionInfo, scale float64) {
+	info.Position = mathutils.MultiplyPointTransform(info.Position, n.transform.toWorld)
+	info.Normal = mathutils.MultiplyDirectionTransform(info.Normal, n.transform.normalsToWorld)
+	info.Normal.Normalize()
+	info.DPdu = mathutils.MultiplyDirectionTransform(info.DPdu, n.transform.toWorld)
+	info.DPdv = mathutils.MultiplyDirectionTransform(info.DPdv, n.transform.toWorld)
+	if info.triplanar != nil {
+		for axis := range info.triplanar.dPdu {
+			info.triplanar.dPdu[axis] = mathutils.MultiplyDirectionTransform(info.triplanar.dPdu[axis], n.transform.toWorld)
+			info.triplanar.dPdv[axis] = mathutils.MultiplyDirectionTransform(info.triplanar.dPdv[axis], n.transform.toWorld)
+		}
+	}
+	info.Distance /= scale
+}
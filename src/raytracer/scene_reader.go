@@ -7,13 +7,21 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 // SceneReader provides a way to parse a scene file.
 type SceneReader struct {
-	fileContent []string // Holds all the words of the scene.
-	position    int      // Holds the current position.
+	fileContent []string              // Holds all the words of the scene.
+	position    int                   // Holds the current position.
+	prototypes  map[string]*Prototype // Holds the prototypes declared so far by name.
+	directory   string                // Holds the directory of the scene file, relative paths start from it.
+	models      map[string]*Prototype // Holds the glTF models loaded so far by path, so they are shared.
+	materials   map[string]Shader     // Holds the materials declared so far by name.
+	textures    map[string]Texture    // Holds the textures declared so far by name.
+	includes    map[string]bool       // Holds the paths of the files being included, to catch files including themselves.
 }
 
 // NewSceneReader creates and returns a new SceneReader
@@ -23,7 +31,8 @@ func NewSceneReader(filePath string) (*SceneReader, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &SceneReader{content, 0}, nil
+	return &SceneReader{content, 0, make(map[string]*Prototype), filepath.Dir(filePath), make(map[string]*Prototype),
+		make(map[string]Shader), make(map[string]Texture), make(map[string]bool)}, nil
 }
 
 //GetFrameSettings parses and returns the frame width and height.
@@ -137,9 +146,19 @@ func (s *SceneReader) GetAmbientLight() (ambientLight utils.Color, err error) {
 	return
 }
 
-// GetLights parses and returns all the lights from the scene file.
-func (s *SceneReader) GetLights() (lights []Light, err error) {
+// GetLights parses and returns all the point and directional lights from the scene file.
+func (s *SceneReader) GetLights() (lights []LightSource, err error) {
 	for {
+		if s.fileContent[s.position] == "DirectionalLight" {
+			var light DirectionalLight
+			light, err = s.readDirectionalLight()
+			if err != nil {
+				return
+			}
+			lights = append(lights, light)
+			continue
+		}
+
 		if s.fileContent[s.position] != "Light" {
 			break
 		}
@@ -191,207 +210,2721 @@ func (s *SceneReader) GetLights() (lights []Light, err error) {
 	return
 }
 
-// GetSceneNodes parses and returns all the scene nodes from the scene file.
-func (s *SceneReader) GetSceneNodes() (nodes []Node, err error) {
-	for {
-		if s.fileContent[s.position] != "Node" {
-			break
+// readDirectionalLight reads a directional light block with the direction towards the light and the optional
+// color, irradiance, angular diameter in degrees and number of shadow rays.
+func (s *SceneReader) readDirectionalLight() (light DirectionalLight, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	var direction mathutils.Vector
+	color, irradiance, angle, samples := utils.Color{1, 1, 1}, 1.0, 0.0, 1
+	for s.position++; s.fileContent[s.position] != "}"; s.position++ {
+		setting := s.fileContent[s.position]
+		s.position++
+		switch setting {
+		case "direction":
+			direction, err = s.readVector()
+		case "color":
+			color, err = s.readColor()
+		case "irradiance":
+			irradiance, err = s.readFloat()
+		case "angle":
+			angle, err = s.readFloat()
+		case "samples":
+			samples, err = s.readInt()
+		default:
+			err = fmt.Errorf("Unknown directional light setting %s", setting)
+		}
+		if err != nil {
+			return
 		}
+	}
+	s.position++
+
+	if direction.LengthSqr() == 0 {
+		err = fmt.Errorf("A directional light needs a direction")
+		return
+	}
+
+	return NewDirectionalLight(direction, color, irradiance, angle, samples), nil
+}
+
+// GetEnvironment parses and returns the optional environment block from the scene file, nil if there is none.
+// The block starts with the type (constant, gradient or map) and its settings: the color for constant,
+// the top and bottom colors for gradient, the file and optional rotation for map. The sky takes the sun direction,
+// or the latitude, longitude, timeZone, date (month and day) and time, and optionally the turbidity,
+// the groundAlbedo color and the sunSize, sunSamples and sunIntensity of its sun. Every type takes
+// the optional intensity and the number of samples used for lighting.
+func (s *SceneReader) GetEnvironment() (environment *Environment, err error) {
+	if s.fileContent[s.position] != "Environment" {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "type")
+	if err != nil {
+		return
+	}
 
+	s.position++
+	kind := s.fileContent[s.position]
+	var color, top, bottom utils.Color
+	var path string
+	rotation, intensity, samples := 0.0, 1.0, 16
+	sky := NewSky(mathutils.NewVector(0, 1, 0))
+	var latitude, longitude, timeZone, time float64
+	month, day, placed := 6, 21, false
+	for s.position++; s.fileContent[s.position] != "}"; s.position++ {
+		setting := s.fileContent[s.position]
 		s.position++
-		err = check(s.fileContent[s.position], "{")
+		switch setting {
+		case "color":
+			color, err = s.readColor()
+		case "top":
+			top, err = s.readColor()
+		case "bottom":
+			bottom, err = s.readColor()
+		case "file":
+			path = s.readPath()
+		case "rotation":
+			rotation, err = s.readFloat()
+		case "intensity":
+			intensity, err = s.readFloat()
+		case "samples":
+			samples, err = s.readInt()
+		case "sunDirection":
+			sky.SunDirection, err = s.readVector()
+		case "latitude":
+			latitude, err = s.readFloat()
+			placed = true
+		case "longitude":
+			longitude, err = s.readFloat()
+			placed = true
+		case "timeZone":
+			timeZone, err = s.readFloat()
+		case "date":
+			month, err = s.readInt()
+			if err == nil {
+				s.position++
+				day, err = s.readInt()
+			}
+		case "time":
+			time, err = s.readFloat()
+			placed = true
+		case "turbidity":
+			sky.Turbidity, err = s.readFloat()
+		case "groundAlbedo":
+			sky.GroundAlbedo, err = s.readColor()
+		case "sunSize":
+			sky.SunSize, err = s.readFloat()
+		case "sunSamples":
+			sky.SunSamples, err = s.readInt()
+		case "sunIntensity":
+			sky.SunIntensity, err = s.readFloat()
+		default:
+			err = fmt.Errorf("Unknown environment setting %s", setting)
+		}
 		if err != nil {
-			break
+			return
 		}
-		var node Node
+	}
+	s.position++
+
+	var result Environment
+	switch kind {
+	case "constant":
+		result = NewConstantEnvironment(color, intensity, samples)
+	case "gradient":
+		result = NewGradientEnvironment(top, bottom, intensity, samples)
+	case "map":
+		result, err = NewMapEnvironment(path, rotation, intensity, samples)
+		if err != nil {
+			return
+		}
+	case "sky":
+		if placed {
+			sky.SunDirection = SunPosition(latitude, longitude, timeZone, DayOfYear(month, day), time)
+		}
+		if sky.SunDirection.LengthSqr() == 0 {
+			err = fmt.Errorf("The sun direction of the sky is zero")
+			return
+		}
+		result = NewSkyEnvironment(sky, intensity, samples)
+	default:
+		err = fmt.Errorf("Unknown environment type %s", kind)
+		return
+	}
+
+	return &result, nil
+}
+
+// GetMedium parses and returns the optional global medium block from the scene file, nil if there is none.
+func (s *SceneReader) GetMedium() (medium *Medium, err error) {
+	if s.fileContent[s.position] != "Medium" {
+		return
+	}
+
+	result, err := s.readMedium()
+	if err != nil {
+		return
+	}
+
+	return &result, nil
+}
+
+// readMedium reads a medium block with the absorption and scattering coefficients per unit of length for the red,
+// green and blue channels and the optional anisotropy of the scattering and number of distance samples.
+func (s *SceneReader) readMedium() (medium Medium, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
 
-		// Read the geometry
+	var absorption, scattering mathutils.Vector
+	anisotropy, samples := 0.0, 8
+	for s.position++; s.fileContent[s.position] != "}"; s.position++ {
+		setting := s.fileContent[s.position]
 		s.position++
-		err = check(s.fileContent[s.position], "geometry")
+		switch setting {
+		case "absorption":
+			absorption, err = s.readVector()
+		case "scattering":
+			scattering, err = s.readVector()
+		case "anisotropy":
+			anisotropy, err = s.readFloat()
+		case "samples":
+			samples, err = s.readInt()
+		default:
+			err = fmt.Errorf("Unknown medium setting %s", setting)
+		}
 		if err != nil {
-			break
+			return
+		}
+	}
+	s.position++
+
+	return NewMedium(utils.Color{absorption.X, absorption.Y, absorption.Z}, utils.Color{scattering.X, scattering.Y, scattering.Z},
+		anisotropy, samples), nil
+}
+
+// readGridMedium reads a grid volume block with the density grid, either a file or a noise cloud with its resolution,
+// scale and octaves, and the optional density, albedo, emission, anisotropy and number of tracked paths.
+// The bounds of the grid are set from the node.
+func (s *SceneReader) readGridMedium() (medium GridMedium, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "grid")
+	if err != nil {
+		return
+	}
+
+	var grid DensityGrid
+	s.position++
+	switch s.fileContent[s.position] {
+	case "file":
+		s.position++
+		grid, err = s.readDensityGridFile()
+	case "noise":
+		grid, err = s.readNoiseDensityGrid()
+	default:
+		err = fmt.Errorf("Unknown density grid %s", s.fileContent[s.position])
+	}
+	if err != nil {
+		return
+	}
+
+	var emission mathutils.Vector
+	density, albedo, anisotropy, samples := 1.0, utils.Color{1, 1, 1}, 0.0, 8
+	for s.position++; s.fileContent[s.position] != "}"; s.position++ {
+		setting := s.fileContent[s.position]
+		s.position++
+		switch setting {
+		case "density":
+			density, err = s.readFloat()
+		case "albedo":
+			albedo, err = s.readColor()
+		case "emission":
+			emission, err = s.readVector()
+		case "anisotropy":
+			anisotropy, err = s.readFloat()
+		case "samples":
+			samples, err = s.readInt()
+		default:
+			err = fmt.Errorf("Unknown grid volume setting %s", setting)
+		}
+		if err != nil {
+			return
 		}
+	}
+	s.position++
+
+	return NewGridMedium(grid, mathutils.EmptyBoundingBox(), density, albedo, utils.Color{emission.X, emission.Y, emission.Z},
+		anisotropy, samples), nil
+}
+
+// readDensityGridFile reads a binary density grid from the file at the current position.
+func (s *SceneReader) readDensityGridFile() (DensityGrid, error) {
+	path := s.readPath()
+	file, err := os.Open(path)
+	if err != nil {
+		return DensityGrid{}, err
+	}
+	defer file.Close()
+
+	grid, err := ReadDensityGrid(file)
+	if err != nil {
+		return DensityGrid{}, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return grid, nil
+}
+
+// readNoiseDensityGrid reads a noise block with the resolution, scale and octaves of a noise cloud,
+// leaving the position at its closing brace.
+func (s *SceneReader) readNoiseDensityGrid() (grid DensityGrid, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
 
+	resolution, scale, octaves := 32, 4.0, 4.0
+	for s.position++; s.fileContent[s.position] != "}"; s.position++ {
+		setting := s.fileContent[s.position]
 		s.position++
+		switch setting {
+		case "resolution":
+			resolution, err = s.readInt()
+		case "scale":
+			scale, err = s.readFloat()
+		case "octaves":
+			octaves, err = s.readFloat()
+		default:
+			err = fmt.Errorf("Unknown noise grid setting %s", setting)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return NewNoiseDensityGrid(resolution, scale, octaves), nil
+}
+
+// GetSceneNodes parses and returns all the scene nodes and groups from the scene file.
+func (s *SceneReader) GetSceneNodes() (nodes []Node, err error) {
+	return s.readNodes(false)
+}
+
+// readNodes reads consecutive Node, Group, Instance and Model blocks, the Prototype, Material and Texture
+// declarations between them and the Include directives, whose nodes are added in their place.
+// inheritsShader tells if there is an enclosing group with a shader the nodes can use.
+func (s *SceneReader) readNodes(inheritsShader bool) (nodes []Node, err error) {
+	for {
+		var node Node
 		name := s.fileContent[s.position]
 		switch {
-		case name == "Sphere":
-			var sphere Sphere
-			sphere, err = s.readSphere()
+		case name == "Node":
+			node, err = s.readNode(inheritsShader)
+		case name == "Group":
+			node, err = s.readGroup(inheritsShader)
+		case name == "Instance":
+			node, err = s.readInstance()
+		case name == "Model":
+			node, err = s.readModel()
+		case name == "Prototype":
+			err = s.readPrototype()
 			if err != nil {
 				return
 			}
-			node.SetGeometry(&sphere)
-		case name == "Plane":
-			var plane Plane
-			plane, err = s.readPlane()
+			continue
+		case name == "Material":
+			err = s.readMaterial()
 			if err != nil {
 				return
 			}
-			node.SetGeometry(&plane)
-
-		case name == "Cube":
-			var cube Cube
-			cube, err = s.readCube()
+			continue
+		case name == "Texture":
+			err = s.readNamedTexture()
+			if err != nil {
+				return
+			}
+			continue
+		case name == "Include":
+			var included []Node
+			included, err = s.readInclude(inheritsShader)
 			if err != nil {
 				return
 			}
-			node.SetGeometry(&cube)
+			nodes = append(nodes, included...)
+			continue
+		default:
+			return
 		}
 
-		// Read the shader
-		err = check(s.fileContent[s.position], "shader")
 		if err != nil {
-			break
+			return
 		}
+		nodes = append(nodes, node)
+	}
+}
 
-		s.position++
-		name = s.fileContent[s.position]
-		switch {
-		case name == "Lambert":
-			var lambert Lambert
-			lambert, err = s.readLambert()
-			if err != nil {
-				return
-			}
-			node.SetShader(&lambert)
+func (s *SceneReader) readNode(inheritsShader bool) (node Node, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
 
-		case name == "Phong":
-			var phong Phong
-			phong, err = s.readPhong()
-			if err != nil {
-				return
-			}
-			node.SetShader(&phong)
-		}
+	// Read the geometry
+	s.position++
+	err = check(s.fileContent[s.position], "geometry")
+	if err != nil {
+		return
+	}
 
-		nodes = append(nodes, node)
-		err = check(s.fileContent[s.position], "}")
+	var geometry Geometry
+	geometry, err = s.readGeometry()
+	if err != nil {
+		return
+	}
+	node.SetGeometry(geometry)
+
+	// Read the shader, which can be omitted if the node inherits one
+	if s.fileContent[s.position] == "shader" {
+		var shader Shader
+		shader, err = s.readShader()
 		if err != nil {
-			break
+			return
 		}
-		s.position++
+		node.SetShader(shader)
+	} else if !inheritsShader {
+		err = fmt.Errorf("Node without a shader")
+		return
 	}
 
-	return
-}
+	err = s.readNodeMapping(&node)
+	if err != nil {
+		return
+	}
 
-func scanWords(path string) ([]string, error) {
+	err = s.readNodeTransform(&node)
+	if err != nil {
+		return
+	}
 
-	file, err := os.Open(path)
+	// A density grid is stretched over the box bounding the node. The medium is copied,
+	// as a material may share it between nodes.
+	if shader := node.GetShader(); shader != nil {
+		if volume, ok := (*shader).(*Volume); ok {
+			if grid, ok := volume.medium.(*GridMedium); ok {
+				medium := *grid
+				medium.SetBounds(node.BoundingBox())
+				node.SetShader(&Volume{&medium})
+			}
+		}
+	}
+
+	err = check(s.fileContent[s.position], "}")
 	if err != nil {
-		return nil, err
+		return
 	}
+	s.position++
+	return
+}
 
-	defer file.Close()
+// readGeometry reads a geometry definition starting at the geometry keyword.
+func (s *SceneReader) readGeometry() (geometry Geometry, err error) {
+	s.position++
+	name := s.fileContent[s.position]
+	switch {
+	case name == "Sphere":
+		var sphere Sphere
+		sphere, err = s.readSphere()
+		if err != nil {
+			return
+		}
+		geometry = &sphere
+	case name == "Plane":
+		var plane Plane
+		plane, err = s.readPlane()
+		if err != nil {
+			return
+		}
+		geometry = &plane
 
-	scanner := bufio.NewScanner(file)
+	case name == "Disc":
+		var disc Disc
+		disc, err = s.readDisc()
+		if err != nil {
+			return
+		}
+		geometry = &disc
 
-	scanner.Split(bufio.ScanWords)
+	case name == "Quad":
+		var quad Quad
+		quad, err = s.readQuad()
+		if err != nil {
+			return
+		}
+		geometry = &quad
 
-	var words []string
+	case name == "Cube":
+		var cube Cube
+		cube, err = s.readCube()
+		if err != nil {
+			return
+		}
+		geometry = &cube
 
-	for scanner.Scan() {
-		words = append(words, scanner.Text())
+	case name == "Cylinder":
+		var cylinder Cylinder
+		cylinder, err = s.readCylinder()
+		if err != nil {
+			return
+		}
+		geometry = &cylinder
+
+	case name == "Cone":
+		var cone Cone
+		cone, err = s.readCone()
+		if err != nil {
+			return
+		}
+		geometry = &cone
+
+	case name == "Capsule":
+		var capsule Capsule
+		capsule, err = s.readCapsule()
+		if err != nil {
+			return
+		}
+		geometry = &capsule
+
+	case name == "Torus":
+		var torus Torus
+		torus, err = s.readTorus()
+		if err != nil {
+			return
+		}
+		geometry = &torus
+
+	case name == "SDF":
+		var sdf SDF
+		sdf, err = s.readSDF()
+		if err != nil {
+			return
+		}
+		geometry = &sdf
+
+	case name == "Heightfield":
+		var heightfield Heightfield
+		heightfield, err = s.readHeightfield()
+		if err != nil {
+			return
+		}
+		geometry = &heightfield
+
+	case name == "Mesh":
+		var mesh Mesh
+		mesh, err = s.readMesh()
+		if err != nil {
+			return
+		}
+		geometry = &mesh
+
+	case name == "Curves":
+		var curves Curves
+		curves, err = s.readCurves()
+		if err != nil {
+			return
+		}
+		geometry = &curves
+
+	case name == "BezierPatches":
+		var mesh Mesh
+		mesh, err = s.readBezierPatches()
+		if err != nil {
+			return
+		}
+		geometry = &mesh
+
+	case name == "Union" || name == "Intersection" || name == "Difference":
+		var csg CSG
+		csg, err = s.readCSG(name)
+		if err != nil {
+			return
+		}
+		geometry = &csg
+
+	default:
+		err = fmt.Errorf("Unknown geometry %s", name)
+	}
+
+	return
+
+}
+
+// readCSG reads the children of a CSG block. Each child is a solid geometry with an optional transformation,
+// cylinders and cones have to be closed at both ends to enclose a volume.
+func (s *SceneReader) readCSG(name string) (csg CSG, err error) {
+	operations := map[string]uint8{"Union": Union, "Intersection": Intersection, "Difference": Difference}
+
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	var children []Solid
+	s.position++
+	for s.fileContent[s.position] == "geometry" {
+		childName := s.fileContent[s.position+1]
+		var geometry Geometry
+		geometry, err = s.readGeometry()
+		if err != nil {
+			return
+		}
+
+		solid, ok := geometry.(Solid)
+		if !ok {
+			err = fmt.Errorf("%s cannot be used in %s", childName, name)
+			return
+		}
+
+		closed := true
+		switch g := geometry.(type) {
+		case *Cylinder:
+			closed = g.bottomCap && g.topCap
+		case *Cone:
+			closed = (g.bottomCap || g.radius == 0) && (g.topCap || g.topRadius == 0)
+		}
+		if !closed {
+			err = fmt.Errorf("%s needs caps to be used in %s", childName, name)
+			return
+		}
+
+		if s.fileContent[s.position] == "transform" {
+			node := NewNode(nil, nil)
+			node.SetGeometry(geometry)
+			err = s.readNodeTransform(&node)
+			if err != nil {
+				return
+			}
+			solid = &node
+		}
+		children = append(children, solid)
+	}
+
+	if len(children) < 2 {
+		err = fmt.Errorf("%s needs at least two children", name)
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	csg = NewCSG(operations[name], children)
+	s.position++
+	return
+}
+
+// readGroup reads a group block with an optional shader and transformation followed by its child nodes.
+// The child nodes without a shader inherit the shader of the closest group which has one.
+func (s *SceneReader) readGroup(inheritsShader bool) (node Node, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	if s.fileContent[s.position] == "shader" {
+		var shader Shader
+		shader, err = s.readShader()
+		if err != nil {
+			return
+		}
+		node.SetShader(shader)
+		inheritsShader = true
+	}
+
+	err = s.readNodeMapping(&node)
+	if err != nil {
+		return
+	}
+
+	err = s.readNodeTransform(&node)
+	if err != nil {
+		return
+	}
+
+	children, err := s.readNodes(inheritsShader)
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	group := NewGroup(children)
+	node.SetGeometry(&group)
+	s.position++
+	return
+}
+
+// readPrototype reads a named prototype made of an optional shared shader and the nodes of the shared geometry.
+// The nodes without a shader use the shader of the prototype, or the one of the instance if it has its own.
+func (s *SceneReader) readPrototype() (err error) {
+	s.position++
+	name := s.fileContent[s.position]
+
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	var shader Shader
+	s.position++
+	if s.fileContent[s.position] == "shader" {
+		shader, err = s.readShader()
+		if err != nil {
+			return
+		}
+	}
+
+	nodes, err := s.readNodes(shader != nil)
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	group := NewGroup(nodes)
+	s.prototypes[name] = NewPrototype(&group, shader)
+	s.position++
+	return
+}
+
+// readMaterial reads a named material holding a shader, which the nodes and the shaders nesting shaders
+// use in place of a shader definition by its name. A later material with the same name replaces it.
+func (s *SceneReader) readMaterial() (err error) {
+	s.position++
+	name := s.fileContent[s.position]
+
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "shader")
+	if err != nil {
+		return
+	}
+
+	shader, err := s.readShader()
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	s.materials[name] = shader
+	s.position++
+	return
+}
+
+// readNamedTexture reads a named texture, which is used in place of a texture definition by its name.
+// A later texture with the same name replaces it.
+func (s *SceneReader) readNamedTexture() (err error) {
+	s.position++
+	name := s.fileContent[s.position]
+
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	texture, err := s.readTextureProperty("texture")
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	s.textures[name] = texture
+	s.position++
+	return
+}
+
+// readInclude reads the scene file of an Include directive, which holds declarations and nodes and may end with End.
+// The declarations are shared with the including file, the relative paths start from the directory of the included one.
+func (s *SceneReader) readInclude(inheritsShader bool) (nodes []Node, err error) {
+	s.position++
+	path := s.readPath()
+	if s.includes[path] {
+		return nil, fmt.Errorf("%s includes itself", path)
+	}
+
+	words, err := scanWords(path)
+	if err != nil {
+		return
+	}
+	if len(words) == 0 || words[len(words)-1] != "End" {
+		words = append(words, "End")
+	}
+
+	s.includes[path] = true
+	defer delete(s.includes, path)
+	included := SceneReader{words, 0, s.prototypes, filepath.Dir(path), s.models, s.materials, s.textures, s.includes}
+	nodes, err = included.readNodes(inheritsShader)
+	if err == nil && included.position != len(words)-1 {
+		err = fmt.Errorf("Unexpected %s", words[included.position])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	s.position++
+	return
+}
+
+// readInstance reads an instance of a prototype with an optional shader and transformation.
+func (s *SceneReader) readInstance() (node Node, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "prototype")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	prototype, ok := s.prototypes[s.fileContent[s.position]]
+	if !ok {
+		err = fmt.Errorf("Unknown prototype %s", s.fileContent[s.position])
+		return
+	}
+
+	var shader *Shader
+	s.position++
+	if s.fileContent[s.position] == "shader" {
+		var instanceShader Shader
+		instanceShader, err = s.readShader()
+		if err != nil {
+			return
+		}
+		shader = &instanceShader
+	}
+
+	transform := mathutils.NewTransform()
+	if s.fileContent[s.position] == "transform" {
+		transform, err = s.readTransform()
+		if err != nil {
+			return
+		}
+	}
+
+	node, ok = prototype.NewInstance(shader, transform)
+	if !ok {
+		err = fmt.Errorf("Transformation cannot be inverted")
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+	s.position++
+	return
+}
+
+// readModel reads a model block placing the meshes of a glTF file with an optional transformation.
+// The cameras and lights of the file are not used.
+func (s *SceneReader) readModel() (node Node, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "file")
+	if err != nil {
+		return
+	}
+	s.position++
+	path := s.readPath()
+	model, ok := s.models[path]
+	if !ok {
+		var gltf GLTFScene
+		gltf, err = ReadGLTF(path)
+		if err != nil {
+			err = fmt.Errorf("%s: %v", path, err)
+			return
+		}
+		group := NewGroup([]Node{gltf.Root})
+		model = NewPrototype(&group, nil)
+		s.models[path] = model
+	}
+
+	transform := mathutils.NewTransform()
+	s.position++
+	if s.fileContent[s.position] == "transform" {
+		transform, err = s.readTransform()
+		if err != nil {
+			return
+		}
+	}
+
+	node, ok = model.NewInstance(nil, transform)
+	if !ok {
+		err = fmt.Errorf("Transformation cannot be inverted")
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+	s.position++
+	return
+}
+
+// readShader reads a shader definition starting at the shader keyword.
+func (s *SceneReader) readShader() (shader Shader, err error) {
+	s.position++
+	name := s.fileContent[s.position]
+	switch {
+	case name == "Lambert":
+		var lambert Lambert
+		lambert, err = s.readLambert()
+		if err != nil {
+			return
+		}
+		shader = &lambert
+
+	case name == "Phong":
+		var phong Phong
+		phong, err = s.readPhong()
+		if err != nil {
+			return
+		}
+		shader = &phong
+
+	case name == "Hair":
+		var hair Hair
+		hair, err = s.readHair()
+		if err != nil {
+			return
+		}
+		shader = &hair
+
+	case name == "Subsurface":
+		var subsurface Subsurface
+		subsurface, err = s.readSubsurface()
+		if err != nil {
+			return
+		}
+		shader = &subsurface
+
+	case name == "Blend":
+		var blend Blend
+		blend, err = s.readBlend()
+		if err != nil {
+			return
+		}
+		shader = &blend
+
+	case name == "Coated":
+		var coated Coated
+		coated, err = s.readCoated()
+		if err != nil {
+			return
+		}
+		shader = &coated
+
+	case name == "Volume":
+		var medium Medium
+		medium, err = s.readMedium()
+		if err != nil {
+			return
+		}
+		volume := NewVolume(medium)
+		shader = &volume
+
+	case name == "GridVolume":
+		var medium GridMedium
+		medium, err = s.readGridMedium()
+		if err != nil {
+			return
+		}
+		shader = &Volume{&medium}
+
+	default:
+		material, ok := s.materials[name]
+		if !ok {
+			err = fmt.Errorf("Unknown shader %s", name)
+			return
+		}
+		shader = material
+		s.position++
+	}
+
+	return
+}
+
+// readNodeTransform reads the optional transformation of a node.
+func (s *SceneReader) readNodeTransform(node *Node) error {
+	if s.fileContent[s.position] != "transform" {
+		return nil
+	}
+
+	transform, err := s.readTransform()
+	if err != nil {
+		return err
+	}
+
+	if !node.SetTransform(transform) {
+		return fmt.Errorf("Transformation cannot be inverted")
+	}
+
+	return nil
+}
+
+// readNodeMapping reads the optional mapping block of a node with the projection (planar, spherical, cylindrical,
+// box or triplanar) followed by the optional sharpness of the triplanar blend and the transformation of the projection.
+func (s *SceneReader) readNodeMapping(node *Node) (err error) {
+	if s.fileContent[s.position] != "mapping" {
+		return nil
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "projection")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	projections := map[string]int{
+		"planar": MappingPlanar, "spherical": MappingSpherical, "cylindrical": MappingCylindrical,
+		"box": MappingBox, "triplanar": MappingTriplanar,
+	}
+	projection, ok := projections[s.fileContent[s.position]]
+	if !ok {
+		return fmt.Errorf("Unknown projection %s", s.fileContent[s.position])
+	}
+
+	sharpness := 4.0
+	placement := mathutils.NewTransform()
+	s.position++
+	if s.fileContent[s.position] == "sharpness" {
+		s.position++
+		sharpness, err = s.readFloat()
+		if err != nil {
+			return
+		}
+		s.position++
+	}
+	if s.fileContent[s.position] == "transform" {
+		placement, err = s.readTransform()
+		if err != nil {
+			return
+		}
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+	s.position++
+
+	mapping, ok := NewMapping(projection, placement, sharpness)
+	if !ok {
+		return fmt.Errorf("Mapping transformation cannot be inverted")
+	}
+	node.SetMapping(mapping)
+
+	return nil
+}
+
+func scanWords(path string) ([]string, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	scanner.Split(bufio.ScanWords)
+
+	var words []string
+
+	for scanner.Scan() {
+		words = append(words, scanner.Text())
+	}
+
+	return words, nil
+}
+
+func check(found, expected string) error {
+	if found != expected {
+		return fmt.Errorf("Incorrect format")
+	}
+
+	return nil
+}
+
+func (s *SceneReader) readVector() (vector mathutils.Vector, err error) {
+
+	vector.X, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	if err != nil {
+		return
+	}
+
+	s.position++
+	vector.Y, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	if err != nil {
+		return
+	}
+
+	s.position++
+	vector.Z, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *SceneReader) readFloat() (float64, error) {
+	return strconv.ParseFloat(s.fileContent[s.position], 64)
+}
+
+// readPath reads a file path, optionally in double quotes, relative to the directory of the scene file.
+func (s *SceneReader) readPath() string {
+	path := strings.Trim(s.fileContent[s.position], "\"")
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(s.directory, path)
+}
+
+func (s *SceneReader) readInt() (int, error) {
+	return strconv.Atoi(s.fileContent[s.position])
+}
+
+func (s *SceneReader) readBool() (bool, error) {
+	return strconv.ParseBool(s.fileContent[s.position])
+}
+
+func (s *SceneReader) readColor() (color utils.Color, err error) {
+	var r, g, b int
+
+	r, err = strconv.Atoi(s.fileContent[s.position])
+	if err != nil {
+		return
+	}
+
+	s.position++
+	g, err = strconv.Atoi(s.fileContent[s.position])
+	if err != nil {
+		return
+	}
+
+	s.position++
+	b, err = strconv.Atoi(s.fileContent[s.position])
+	if err != nil {
+		return
+	}
+	color = utils.NewColor(uint8(r), uint8(g), uint8(b))
+	return
+}
+
+func (s *SceneReader) readSphere() (sphere Sphere, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "center")
+	if err != nil {
+		return
+	}
+	s.position++
+	sphere.center, err = s.readVector()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "radius")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	sphere.radius, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	return
+}
+
+func (s *SceneReader) readPlane() (plane Plane, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "center")
+	if err != nil {
+		return
+	}
+	s.position++
+	center, err := s.readVector()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	if s.fileContent[s.position] == "normal" {
+		plane, err = s.readOrientedPlane(center)
+		return
+	}
+
+	err = check(s.fileContent[s.position], "limit")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	limit, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "orientation")
+	if err != nil {
+		return
+	}
+
+	var orientation uint8
+	s.position++
+	value := s.fileContent[s.position]
+	switch {
+	case value == "XY":
+		orientation = XY
+	case value == "XZ":
+		orientation = XZ
+	case value == "YZ":
+		orientation = YZ
+	}
+	plane = NewPlane(center, limit, orientation)
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	return
+}
+
+// readOrientedPlane reads the rest of a plane given by a normal and optional width and height.
+func (s *SceneReader) readOrientedPlane(center mathutils.Vector) (plane Plane, err error) {
+	s.position++
+	normal, err := s.readVector()
+	if err != nil {
+		return
+	}
+
+	var width, height float64
+	s.position++
+	if s.fileContent[s.position] == "width" {
+		s.position++
+		width, err = s.readFloat()
+		if err != nil {
+			return
+		}
+		s.position++
+	}
+
+	if s.fileContent[s.position] == "height" {
+		s.position++
+		height, err = s.readFloat()
+		if err != nil {
+			return
+		}
+		s.position++
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	plane = NewOrientedPlane(center, normal, width, height)
+	s.position++
+	return
+}
+
+func (s *SceneReader) readDisc() (disc Disc, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "center")
+	if err != nil {
+		return
+	}
+	s.position++
+	center, err := s.readVector()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "normal")
+	if err != nil {
+		return
+	}
+	s.position++
+	normal, err := s.readVector()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "radius")
+	if err != nil {
+		return
+	}
+	s.position++
+	radius, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	var innerRadius float64
+	s.position++
+	if s.fileContent[s.position] == "innerRadius" {
+		s.position++
+		innerRadius, err = s.readFloat()
+		if err != nil {
+			return
+		}
+		s.position++
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	disc, err = NewDisc(center, normal, radius, innerRadius)
+	if err != nil {
+		return
+	}
+	s.position++
+	return
+}
+
+func (s *SceneReader) readQuad() (quad Quad, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "corner")
+	if err != nil {
+		return
+	}
+	s.position++
+	corner, err := s.readVector()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "edge1")
+	if err != nil {
+		return
+	}
+	s.position++
+	edge1, err := s.readVector()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "edge2")
+	if err != nil {
+		return
+	}
+	s.position++
+	edge2, err := s.readVector()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	quad, err = NewQuad(corner, edge1, edge2)
+	if err != nil {
+		return
+	}
+	s.position++
+	return
+}
+
+func (s *SceneReader) readCube() (cube Cube, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "center")
+	if err != nil {
+		return
+	}
+	s.position++
+	cube.center, err = s.readVector()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "edge")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	cube.edge, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	return
+}
+
+// readCenterAndAxis reads the center and the axis which start the blocks of the primitives with an axis.
+func (s *SceneReader) readCenterAndAxis() (center, axis mathutils.Vector, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "center")
+	if err != nil {
+		return
+	}
+	s.position++
+	center, err = s.readVector()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "axis")
+	if err != nil {
+		return
+	}
+	s.position++
+	axis, err = s.readVector()
+	return
+}
+
+// readCaps reads either the caps setting, which closes both ends, or the bottomCap and topCap settings.
+func (s *SceneReader) readCaps() (bottomCap, topCap bool, err error) {
+	s.position++
+	if s.fileContent[s.position] == "caps" {
+		s.position++
+		bottomCap, err = s.readBool()
+		return bottomCap, bottomCap, err
+	}
+
+	err = check(s.fileContent[s.position], "bottomCap")
+	if err != nil {
+		return
+	}
+	s.position++
+	bottomCap, err = s.readBool()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "topCap")
+	if err != nil {
+		return
+	}
+	s.position++
+	topCap, err = s.readBool()
+	return
+}
+
+func (s *SceneReader) readCylinder() (cylinder Cylinder, err error) {
+	center, axis, err := s.readCenterAndAxis()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "radius")
+	if err != nil {
+		return
+	}
+	s.position++
+	radius, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "height")
+	if err != nil {
+		return
+	}
+	s.position++
+	height, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	bottomCap, topCap, err := s.readCaps()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	cylinder = NewCylinder(center, axis, radius, height, bottomCap, topCap)
+	s.position++
+	return
+}
+
+func (s *SceneReader) readCone() (cone Cone, err error) {
+	center, axis, err := s.readCenterAndAxis()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "radius")
+	if err != nil {
+		return
+	}
+	s.position++
+	radius, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	var topRadius float64
+	s.position++
+	if s.fileContent[s.position] == "topRadius" {
+		s.position++
+		topRadius, err = s.readFloat()
+		if err != nil {
+			return
+		}
+		s.position++
+	}
+
+	err = check(s.fileContent[s.position], "height")
+	if err != nil {
+		return
+	}
+	s.position++
+	height, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	bottomCap, topCap, err := s.readCaps()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	cone = NewCone(center, axis, radius, topRadius, height, bottomCap, topCap)
+	s.position++
+	return
+}
+
+func (s *SceneReader) readCapsule() (capsule Capsule, err error) {
+	center, axis, err := s.readCenterAndAxis()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "radius")
+	if err != nil {
+		return
+	}
+	s.position++
+	radius, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "height")
+	if err != nil {
+		return
+	}
+	s.position++
+	height, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	capsule = NewCapsule(center, axis, radius, height)
+	s.position++
+	return
+}
+
+func (s *SceneReader) readTorus() (torus Torus, err error) {
+	center, axis, err := s.readCenterAndAxis()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "majorRadius")
+	if err != nil {
+		return
+	}
+	s.position++
+	majorRadius, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "minorRadius")
+	if err != nil {
+		return
+	}
+	s.position++
+	minorRadius, err := s.readFloat()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	torus = NewTorus(center, axis, majorRadius, minorRadius)
+	s.position++
+	return
+}
+
+// readSDF reads a signed distance field block made of optional marching settings followed by a distance function.
+// The bounds setting gives the box containing the surface for the functions which cannot compute it, like Repeat.
+func (s *SceneReader) readSDF() (sdf SDF, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	maxSteps, epsilon, maxDistance := 256, 0.001, 1000.0
+	var bounds mathutils.BoundingBox
+	hasBounds := false
+	s.position++
+	for s.fileContent[s.position] != "function" {
+		switch s.fileContent[s.position] {
+		case "maxSteps":
+			s.position++
+			maxSteps, err = s.readInt()
+		case "epsilon":
+			s.position++
+			epsilon, err = s.readFloat()
+		case "maxDistance":
+			s.position++
+			maxDistance, err = s.readFloat()
+		case "bounds":
+			var min, max mathutils.Vector
+			s.position++
+			min, err = s.readVector()
+			if err != nil {
+				return
+			}
+			s.position++
+			max, err = s.readVector()
+			bounds = mathutils.NewBoundingBox(min, max)
+			hasBounds = true
+		default:
+			err = fmt.Errorf("Unknown SDF setting %s", s.fileContent[s.position])
+		}
+		if err != nil {
+			return
+		}
+		s.position++
+	}
+
+	distanceFunction, err := s.readDistanceFunction()
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	sdf = NewSDF(distanceFunction, maxSteps, epsilon, maxDistance)
+	if hasBounds {
+		sdf.SetBounds(bounds)
+	}
+	s.position++
+	return
+}
+
+// readDistanceFunction reads a function block and the nested functions of the combining ones.
+func (s *SceneReader) readDistanceFunction() (function DistanceFunction, err error) {
+	s.position++
+	name := s.fileContent[s.position]
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	switch name {
+	case "Sphere":
+		var center mathutils.Vector
+		var radius float64
+		center, err = s.readVectorProperty("center")
+		if err != nil {
+			return
+		}
+		radius, err = s.readFloatProperty("radius")
+		if err != nil {
+			return
+		}
+		sphere := NewSphereDistance(center, radius)
+		function = &sphere
+
+	case "Box":
+		var center, size mathutils.Vector
+		var rounding float64
+		center, err = s.readVectorProperty("center")
+		if err != nil {
+			return
+		}
+		size, err = s.readVectorProperty("size")
+		if err != nil {
+			return
+		}
+		if s.fileContent[s.position+1] == "rounding" {
+			rounding, err = s.readFloatProperty("rounding")
+			if err != nil {
+				return
+			}
+		}
+		box := NewBoxDistance(center, size, rounding)
+		function = &box
+
+	case "Torus":
+		var center mathutils.Vector
+		var majorRadius, minorRadius float64
+		center, err = s.readVectorProperty("center")
+		if err != nil {
+			return
+		}
+		majorRadius, err = s.readFloatProperty("majorRadius")
+		if err != nil {
+			return
+		}
+		minorRadius, err = s.readFloatProperty("minorRadius")
+		if err != nil {
+			return
+		}
+		torus := NewTorusDistance(center, majorRadius, minorRadius)
+		function = &torus
+
+	case "SmoothUnion":
+		var smoothness float64
+		if s.fileContent[s.position+1] == "smoothness" {
+			smoothness, err = s.readFloatProperty("smoothness")
+			if err != nil {
+				return
+			}
+		}
+
+		var children []DistanceFunction
+		for s.fileContent[s.position+1] == "function" {
+			s.position++
+			var child DistanceFunction
+			child, err = s.readDistanceFunction()
+			if err != nil {
+				return
+			}
+			children = append(children, child)
+			s.position--
+		}
+		if len(children) == 0 {
+			err = fmt.Errorf("SmoothUnion needs at least one function")
+			return
+		}
+		union := NewSmoothUnionDistance(children, smoothness)
+		function = &union
+
+	case "Repeat":
+		var period mathutils.Vector
+		period, err = s.readVectorProperty("period")
+		if err != nil {
+			return
+		}
+
+		s.position++
+		err = check(s.fileContent[s.position], "function")
+		if err != nil {
+			return
+		}
+		var child DistanceFunction
+		child, err = s.readDistanceFunction()
+		if err != nil {
+			return
+		}
+		s.position--
+		repetition := NewRepetitionDistance(child, period)
+		function = &repetition
+
+	case "Mandelbulb":
+		var center mathutils.Vector
+		var scale, power float64
+		var iterations int
+		center, err = s.readVectorProperty("center")
+		if err != nil {
+			return
+		}
+		scale, err = s.readFloatProperty("scale")
+		if err != nil {
+			return
+		}
+		power, err = s.readFloatProperty("power")
+		if err != nil {
+			return
+		}
+		s.position++
+		err = check(s.fileContent[s.position], "iterations")
+		if err != nil {
+			return
+		}
+		s.position++
+		iterations, err = s.readInt()
+		if err != nil {
+			return
+		}
+		mandelbulb := NewMandelbulbDistance(center, scale, power, iterations)
+		function = &mandelbulb
+
+	default:
+		err = fmt.Errorf("Unknown distance function %s", name)
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	return
+}
+
+// readVectorProperty reads the property name at the next position followed by a vector.
+func (s *SceneReader) readVectorProperty(name string) (vector mathutils.Vector, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], name)
+	if err != nil {
+		return
+	}
+	s.position++
+	return s.readVector()
+}
+
+// readFloatProperty reads the property name at the next position followed by a float.
+func (s *SceneReader) readFloatProperty(name string) (value float64, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], name)
+	if err != nil {
+		return
+	}
+	s.position++
+	return s.readFloat()
+}
+
+// readHeightfield reads a heightfield block with the height map file and the size of the terrain.
+func (s *SceneReader) readHeightfield() (heightfield Heightfield, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "file")
+	if err != nil {
+		return
+	}
+	s.position++
+	path := s.readPath()
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	heights, err := ReadHeightmap(file)
+	if err != nil {
+		err = fmt.Errorf("%s: %v", path, err)
+		return
+	}
+
+	center, err := s.readVectorProperty("center")
+	if err != nil {
+		return
+	}
+
+	width, err := s.readFloatProperty("width")
+	if err != nil {
+		return
+	}
+
+	depth, err := s.readFloatProperty("depth")
+	if err != nil {
+		return
+	}
+
+	height, err := s.readFloatProperty("height")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	heightfield = NewHeightfield(heights, center, width, depth, height)
+	s.position++
+	return
+}
+
+// readMesh reads a mesh block with a PLY or STL file and an optional number of Catmull-Clark subdivision steps.
+func (s *SceneReader) readMesh() (mesh Mesh, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "file")
+	if err != nil {
+		return
+	}
+	s.position++
+	path := s.readPath()
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	var data MeshData
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ply":
+		data, err = ReadPLY(file)
+	case ".stl":
+		data, err = ReadSTL(file)
+	default:
+		err = fmt.Errorf("Unknown mesh format %s", filepath.Ext(path))
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %v", path, err)
+		return
+	}
+
+	s.position++
+	if s.fileContent[s.position] == "subdivision" {
+		s.position++
+		var levels int
+		levels, err = s.readInt()
+		if err != nil {
+			return
+		}
+		data, err = SubdivideCatmullClark(data, levels)
+		if err != nil {
+			err = fmt.Errorf("%s: %v", path, err)
+			return
+		}
+		s.position++
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	mesh = NewMesh(data)
+	s.position++
+	return
+}
+
+// readBezierPatches reads a Bezier patches block with the patch file and the tessellation tolerance.
+func (s *SceneReader) readBezierPatches() (mesh Mesh, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "file")
+	if err != nil {
+		return
+	}
+	s.position++
+	path := s.readPath()
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	patches, err := ReadBezierPatches(file)
+	if err != nil {
+		err = fmt.Errorf("%s: %v", path, err)
+		return
+	}
+
+	tolerance, err := s.readFloatProperty("tolerance")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	mesh = NewMesh(TessellateBezierPatches(patches, tolerance))
+	s.position++
+	return
+}
+
+// readCurves reads a curves block with the curve file and the optional shape, ribbon or cylinder.
+func (s *SceneReader) readCurves() (curves Curves, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "file")
+	if err != nil {
+		return
+	}
+	s.position++
+	path := s.readPath()
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	data, err := ReadCurves(file)
+	if err != nil {
+		err = fmt.Errorf("%s: %v", path, err)
+		return
+	}
+
+	shape := CurveCylinder
+	s.position++
+	if s.fileContent[s.position] == "shape" {
+		s.position++
+		switch s.fileContent[s.position] {
+		case "ribbon":
+			shape = CurveRibbon
+		case "cylinder":
+			shape = CurveCylinder
+		default:
+			err = fmt.Errorf("Unknown curve shape %s", s.fileContent[s.position])
+			return
+		}
+		s.position++
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+
+	curves = NewCurves(data, shape)
+	s.position++
+	return
+}
+
+// readTransform reads a transform block made of translate, rotate(in degrees) and scale operations.
+// As in the usual matrix notation, the last operation is applied to the object first.
+func (s *SceneReader) readTransform() (transform mathutils.Transform, err error) {
+	transform = mathutils.NewTransform()
+
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	for {
+		s.position++
+		name := s.fileContent[s.position]
+		if name == "}" {
+			break
+		}
+
+		s.position++
+		var value mathutils.Vector
+		value, err = s.readVector()
+		if err != nil {
+			return
+		}
+
+		var operation mathutils.Transform
+		switch {
+		case name == "translate":
+			operation = mathutils.TranslationTransform(value)
+		case name == "rotate":
+			operation = mathutils.RotationTransform(mathutils.NewVector(mathutils.ToRadians(value.X), mathutils.ToRadians(value.Y), mathutils.ToRadians(value.Z)))
+		case name == "scale":
+			operation = mathutils.ScalingTransform(value)
+		default:
+			err = fmt.Errorf("Incorrect format")
+			return
+		}
+		transform = mathutils.TransformMultiplication(operation, transform)
+	}
+
+	s.position++
+	return
+}
+
+func (s *SceneReader) readLambert() (lambert Lambert, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "color")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	lambert.color, err = s.readColor()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "texture")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	var texture Texture
+	texture, err = s.readTexture()
+	if err != nil {
+		return
+	}
+	if texture != nil {
+		lambert.SetTexture(texture)
+	}
+
+	err = s.readSurfaceDetail(&lambert.surfaceDetail)
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+	s.position++
+
+	return
+}
+
+func (s *SceneReader) readPhong() (phong Phong, err error) {
+
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "color")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	phong.color, err = s.readColor()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "texture")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	var texture Texture
+	texture, err = s.readTexture()
+	if err != nil {
+		return
+	}
+	if texture != nil {
+		phong.SetTexture(texture)
+	}
+
+	err = check(s.fileContent[s.position], "specularMultiplier")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	phong.specularMultiplier, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "specularExponent")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	phong.specularExponent, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = s.readSurfaceDetail(&phong.surfaceDetail)
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+	s.position++
+
+	return
+}
+
+func (s *SceneReader) readHair() (hair Hair, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "color")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	hair.color, err = s.readColor()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "texture")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	var texture Texture
+	texture, err = s.readTexture()
+	if err != nil {
+		return
+	}
+	if texture != nil {
+		hair.SetTexture(texture)
+	}
+
+	err = check(s.fileContent[s.position], "specularColor")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	hair.specularColor, err = s.readColor()
+	if err != nil {
+		return
+	}
+
+	hair.specularExponent, err = s.readFloatProperty("specularExponent")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = s.readSurfaceDetail(&hair.surfaceDetail)
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+	s.position++
+
+	return
+}
+
+// readSubsurface reads a subsurface block with the color, the texture, the scattering radius per channel
+// and the optional number of random walks and surface detail.
+func (s *SceneReader) readSubsurface() (subsurface Subsurface, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "color")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	var color utils.Color
+	color, err = s.readColor()
+	if err != nil {
+		return
+	}
+
+	s.position++
+	err = check(s.fileContent[s.position], "texture")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	var texture Texture
+	texture, err = s.readTexture()
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "radius")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	var radius mathutils.Vector
+	radius, err = s.readVector()
+	if err != nil {
+		return
+	}
+
+	samples := 8
+	s.position++
+	if s.fileContent[s.position] == "samples" {
+		s.position++
+		samples, err = s.readInt()
+		if err != nil {
+			return
+		}
+		s.position++
+	}
+
+	subsurface = NewSubsurface(color, texture, utils.Color{radius.X, radius.Y, radius.Z}, samples)
+	err = s.readSurfaceDetail(&subsurface.surfaceDetail)
+	if err != nil {
+		return
+	}
+
+	err = check(s.fileContent[s.position], "}")
+	if err != nil {
+		return
+	}
+	s.position++
+
+	return
+}
+
+// readBlend reads a blend block with the first and second shaders, nested like the shader of a node,
+// and the optional factor, which defaults to 0.5, and mask texture.
+func (s *SceneReader) readBlend() (blend Blend, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
+	}
+
+	blend.factor = 0.5
+	for s.position++; s.fileContent[s.position] != "}"; {
+		setting := s.fileContent[s.position]
+		switch setting {
+		case "first":
+			blend.first, err = s.readShader()
+		case "second":
+			blend.second, err = s.readShader()
+		case "factor":
+			s.position++
+			blend.factor, err = s.readFloat()
+			s.position++
+		case "mask":
+			var mask Texture
+			mask, err = s.readTextureProperty("mask")
+			if err == nil {
+				blend.SetMask(mask)
+			}
+		default:
+			err = fmt.Errorf("Unknown blend setting %s", setting)
+		}
+		if err != nil {
+			return
+		}
 	}
+	s.position++
 
-	return words, nil
-}
-
-func check(found, expected string) error {
-	if found != expected {
-		return fmt.Errorf("Incorrect format")
+	if blend.first == nil || blend.second == nil {
+		err = fmt.Errorf("A blend needs a first and a second shader")
 	}
 
-	return nil
+	return
 }
 
-func (s *SceneReader) readVector() (vector mathutils.Vector, err error) {
-
-	vector.X, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+// readCoated reads a coated block with the base shader, nested like the shader of a node, and the optional
+// color, ior, roughness, samples and surface detail of the coat.
+func (s *SceneReader) readCoated() (coated Coated, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
 	if err != nil {
 		return
 	}
 
 	s.position++
-	vector.Y, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	err = check(s.fileContent[s.position], "base")
 	if err != nil {
 		return
 	}
 
-	s.position++
-	vector.Z, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	var base Shader
+	base, err = s.readShader()
 	if err != nil {
 		return
 	}
 
-	return
-}
-
-func (s *SceneReader) readColor() (color utils.Color, err error) {
-	var r, g, b int
+	coated = NewCoated(base, 1.5, 0, 8)
+	// The settings of the coat come before the surface detail.
+	for err == nil {
+		setting := s.fileContent[s.position]
+		if setting != "color" && setting != "ior" && setting != "roughness" && setting != "samples" {
+			break
+		}
 
-	r, err = strconv.Atoi(s.fileContent[s.position])
+		s.position++
+		switch setting {
+		case "color":
+			coated.color, err = s.readColor()
+		case "ior":
+			coated.ior, err = s.readFloat()
+		case "roughness":
+			coated.roughness, err = s.readFloat()
+		case "samples":
+			coated.samples, err = s.readInt()
+		}
+		s.position++
+	}
 	if err != nil {
 		return
 	}
+	if coated.samples < 1 {
+		coated.samples = 1
+	}
 
-	s.position++
-	g, err = strconv.Atoi(s.fileContent[s.position])
+	err = s.readSurfaceDetail(&coated.surfaceDetail)
 	if err != nil {
 		return
 	}
 
-	s.position++
-	b, err = strconv.Atoi(s.fileContent[s.position])
+	err = check(s.fileContent[s.position], "}")
 	if err != nil {
 		return
 	}
-	color = utils.NewColor(uint8(r), uint8(g), uint8(b))
+	s.position++
+
 	return
 }
 
-func (s *SceneReader) readSphere() (sphere Sphere, err error) {
-	s.position++
-	err = check(s.fileContent[s.position], "{")
+// readSurfaceDetail reads the optional normalMap, bumpMap and bumpScale settings at the end of a shader block
+// and leaves the position at the closing brace.
+func (s *SceneReader) readSurfaceDetail(detail *surfaceDetail) (err error) {
+	detail.bumpScale = 1
+	for s.fileContent[s.position] != "}" {
+		setting := s.fileContent[s.position]
+		switch setting {
+		case "normalMap":
+			detail.normalMap, err = s.readTextureProperty("normalMap")
+		case "bumpMap":
+			detail.bumpMap, err = s.readTextureProperty("bumpMap")
+		case "bumpScale":
+			s.position++
+			detail.bumpScale, err = s.readFloat()
+			s.position++
+		default:
+			err = fmt.Errorf("Unknown shader setting %s", setting)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// readTexture reads the texture block starting at the current position, including the textures nested in it,
+// and leaves the position after the block. The texture is nil for "nil".
+func (s *SceneReader) readTexture() (texture Texture, err error) {
+	name := s.fileContent[s.position]
+	switch {
+	case name == "SimpleColor":
+		var simpleColor SimpleColor
+		simpleColor, err = s.readSimpleColor()
+		texture = &simpleColor
+	case name == "Checker":
+		var checker Checker
+		checker, err = s.readChecker()
+		texture = &checker
+	case name == "VertexColor":
+		var vertexColor VertexColor
+		vertexColor, err = s.readVertexColor()
+		texture = &vertexColor
+	case name == "Bitmap":
+		var bitmap Bitmap
+		bitmap, err = s.readBitmap()
+		texture = &bitmap
+	case name == "FBm" || name == "Turbulence" || name == "Marble" || name == "Wood" || name == "Worley":
+		var noise Noise
+		noise, err = s.readNoise(name)
+		texture = &noise
+	case name == "Mix":
+		var mix Mix
+		mix, err = s.readMix()
+		texture = &mix
+	case name == "Multiply":
+		var multiply Multiply
+		multiply.texture1, multiply.texture2, err = s.readTexturePair()
+		texture = &multiply
+	case name == "Add":
+		var add Add
+		add.texture1, add.texture2, err = s.readTexturePair()
+		texture = &add
+	case name == "ColorRamp":
+		var colorRamp ColorRamp
+		colorRamp, err = s.readColorRamp()
+		texture = &colorRamp
+	case name == "Invert":
+		var invert Invert
+		invert, err = s.readInvert()
+		texture = &invert
+	case name == "Remap":
+		var remap Remap
+		remap, err = s.readRemap()
+		texture = &remap
+	case name == "nil":
+		s.position++
+	default:
+		var ok bool
+		texture, ok = s.textures[name]
+		if !ok {
+			err = fmt.Errorf("Unknown texture %s", name)
+		}
+		s.position++
+	}
+
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	s.position++
-	err = check(s.fileContent[s.position], "center")
+	return
+}
+
+// readTextureProperty reads the property name at the current position followed by a texture, which must not be nil.
+func (s *SceneReader) readTextureProperty(name string) (texture Texture, err error) {
+	err = check(s.fileContent[s.position], name)
 	if err != nil {
 		return
 	}
+
 	s.position++
-	sphere.center, err = s.readVector()
+	texture, err = s.readTexture()
+	if err == nil && texture == nil {
+		err = fmt.Errorf("The %s texture is missing", name)
+	}
+
+	return
+}
+
+// readTexturePair reads a block with texture1 and texture2, which is the body of the Multiply and Add textures.
+func (s *SceneReader) readTexturePair() (texture1, texture2 Texture, err error) {
+	s.position++
+	err = check(s.fileContent[s.position], "{")
 	if err != nil {
 		return
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "radius")
+	texture1, err = s.readTextureProperty("texture1")
 	if err != nil {
 		return
 	}
 
-	s.position++
-	sphere.radius, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	texture2, err = s.readTextureProperty("texture2")
 	if err != nil {
 		return
 	}
 
-	s.position++
 	err = check(s.fileContent[s.position], "}")
 	if err != nil {
 		return
 	}
-
 	s.position++
 	return
 }
 
-func (s *SceneReader) readPlane() (plane Plane, err error) {
+// readMix reads a mix block with texture1, texture2 and the mask texture.
+func (s *SceneReader) readMix() (mix Mix, err error) {
 	s.position++
 	err = check(s.fileContent[s.position], "{")
 	if err != nil {
@@ -399,47 +2932,70 @@ func (s *SceneReader) readPlane() (plane Plane, err error) {
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "center")
+	mix.texture1, err = s.readTextureProperty("texture1")
 	if err != nil {
 		return
 	}
-	s.position++
-	plane.center, err = s.readVector()
+
+	mix.texture2, err = s.readTextureProperty("texture2")
 	if err != nil {
 		return
 	}
 
-	s.position++
-	err = check(s.fileContent[s.position], "limit")
+	mix.mask, err = s.readTextureProperty("mask")
 	if err != nil {
 		return
 	}
 
-	s.position++
-	plane.limit, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	err = check(s.fileContent[s.position], "}")
 	if err != nil {
 		return
 	}
+	s.position++
+	return
+}
 
+// readColorRamp reads a color ramp block with the input texture followed by stops, each with a position and a color.
+func (s *SceneReader) readColorRamp() (colorRamp ColorRamp, err error) {
 	s.position++
-	err = check(s.fileContent[s.position], "orientation")
+	err = check(s.fileContent[s.position], "{")
 	if err != nil {
 		return
 	}
 
 	s.position++
-	value := s.fileContent[s.position]
-	switch {
-	case value == "XY":
-		plane.orientation = 0
-	case value == "XZ":
-		plane.orientation = 1
-	case value == "YZ":
-		plane.orientation = 2
+	input, err := s.readTextureProperty("input")
+	if err != nil {
+		return
 	}
 
-	s.position++
-	err = check(s.fileContent[s.position], "}")
+	var positions []float64
+	var colors []utils.Color
+	for ; s.fileContent[s.position] != "}"; s.position++ {
+		err = check(s.fileContent[s.position], "stop")
+		if err != nil {
+			return
+		}
+
+		s.position++
+		var position float64
+		position, err = s.readFloat()
+		if err != nil {
+			return
+		}
+
+		s.position++
+		var color utils.Color
+		color, err = s.readColor()
+		if err != nil {
+			return
+		}
+
+		positions = append(positions, position)
+		colors = append(colors, color)
+	}
+
+	colorRamp, err = NewColorRamp(input, positions, colors)
 	if err != nil {
 		return
 	}
@@ -448,7 +3004,8 @@ func (s *SceneReader) readPlane() (plane Plane, err error) {
 	return
 }
 
-func (s *SceneReader) readCube() (cube Cube, err error) {
+// readInvert reads an invert block with the inverted texture.
+func (s *SceneReader) readInvert() (invert Invert, err error) {
 	s.position++
 	err = check(s.fileContent[s.position], "{")
 	if err != nil {
@@ -456,95 +3013,78 @@ func (s *SceneReader) readCube() (cube Cube, err error) {
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "center")
+	invert.texture, err = s.readTextureProperty("texture")
 	if err != nil {
 		return
 	}
-	s.position++
-	cube.center, err = s.readVector()
+
+	err = check(s.fileContent[s.position], "}")
 	if err != nil {
 		return
 	}
+	s.position++
+	return
+}
 
+// readRemap reads a remap block with the texture followed by the from and to ranges.
+func (s *SceneReader) readRemap() (remap Remap, err error) {
 	s.position++
-	err = check(s.fileContent[s.position], "edge")
+	err = check(s.fileContent[s.position], "{")
 	if err != nil {
 		return
 	}
 
 	s.position++
-	cube.edge, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	remap.texture, err = s.readTextureProperty("texture")
 	if err != nil {
 		return
 	}
 
-	s.position++
-	err = check(s.fileContent[s.position], "}")
+	err = check(s.fileContent[s.position], "from")
 	if err != nil {
 		return
 	}
 
 	s.position++
-	return
-}
-
-func (s *SceneReader) readLambert() (lambert Lambert, err error) {
-	s.position++
-	err = check(s.fileContent[s.position], "{")
+	remap.fromMin, err = s.readFloat()
 	if err != nil {
 		return
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "color")
+	remap.fromMax, err = s.readFloat()
 	if err != nil {
 		return
 	}
 
 	s.position++
-	lambert.color, err = s.readColor()
+	err = check(s.fileContent[s.position], "to")
 	if err != nil {
 		return
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "texture")
+	remap.toMin, err = s.readFloat()
 	if err != nil {
 		return
 	}
 
 	s.position++
-	name := s.fileContent[s.position]
-	switch {
-	case name == "SimpleColor":
-		var simpleColor SimpleColor
-		simpleColor, err = s.readSimpleColor()
-		if err != nil {
-			return
-		}
-		lambert.SetTexture(&simpleColor)
-	case name == "Checker":
-		var checker Checker
-		checker, err = s.readChecker()
-		if err != nil {
-			return
-		}
-		lambert.SetTexture(&checker)
-	case name == "nil":
-		s.position++
+	remap.toMax, err = s.readFloat()
+	if err != nil {
+		return
 	}
 
+	s.position++
 	err = check(s.fileContent[s.position], "}")
 	if err != nil {
 		return
 	}
 	s.position++
-
 	return
 }
 
-func (s *SceneReader) readPhong() (phong Phong, err error) {
-
+func (s *SceneReader) readSimpleColor() (simpleColor SimpleColor, err error) {
 	s.position++
 	err = check(s.fileContent[s.position], "{")
 	if err != nil {
@@ -558,73 +3098,140 @@ func (s *SceneReader) readPhong() (phong Phong, err error) {
 	}
 
 	s.position++
-	phong.color, err = s.readColor()
+	simpleColor.color, err = s.readColor()
 	if err != nil {
 		return
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "texture")
+	err = check(s.fileContent[s.position], "}")
 	if err != nil {
 		return
 	}
+	s.position++
+	return
+}
 
+func (s *SceneReader) readVertexColor() (vertexColor VertexColor, err error) {
 	s.position++
-	name := s.fileContent[s.position]
-	switch {
-	case name == "SimpleColor":
-		var simpleColor SimpleColor
-		simpleColor, err = s.readSimpleColor()
-		if err != nil {
-			return
-		}
-		phong.SetTexture(&simpleColor)
-	case name == "Checker":
-		var checker Checker
-		checker, err = s.readChecker()
-		if err != nil {
-			return
-		}
-		phong.SetTexture(&checker)
-	case name == "nil":
-		s.position++
+	err = check(s.fileContent[s.position], "{")
+	if err != nil {
+		return
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "specularMultiplier")
+	err = check(s.fileContent[s.position], "color")
 	if err != nil {
 		return
 	}
 
 	s.position++
-	phong.specularMultiplier, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	vertexColor.color, err = s.readColor()
 	if err != nil {
 		return
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "specularExponent")
+	err = check(s.fileContent[s.position], "}")
 	if err != nil {
 		return
 	}
+	s.position++
+	return
+}
 
+// readBitmap reads a bitmap block with the image file followed by the optional colorSpace (srgb or linear),
+// wrap (repeat, clamp or mirror), filter (nearest, bilinear, trilinear or ewa), scale, offset and rotation settings.
+func (s *SceneReader) readBitmap() (bitmap Bitmap, err error) {
 	s.position++
-	phong.specularExponent, err = strconv.ParseFloat(s.fileContent[s.position], 64)
+	err = check(s.fileContent[s.position], "{")
 	if err != nil {
 		return
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "}")
+	err = check(s.fileContent[s.position], "file")
 	if err != nil {
 		return
 	}
 	s.position++
+	path := s.readPath()
+
+	linear := false
+	wrap, filter := WrapRepeat, FilterTrilinear
+	scale, offset := [2]float64{1, 1}, [2]float64{0, 0}
+	rotation := 0.0
+	options := map[string]map[string]int{
+		"wrap":   {"repeat": WrapRepeat, "clamp": WrapClamp, "mirror": WrapMirror},
+		"filter": {"nearest": FilterNearest, "bilinear": FilterBilinear, "trilinear": FilterTrilinear, "ewa": FilterEWA},
+	}
+	for s.position++; s.fileContent[s.position] != "}"; s.position++ {
+		setting := s.fileContent[s.position]
+		s.position++
+		value := s.fileContent[s.position]
+		switch setting {
+		case "colorSpace":
+			if value != "srgb" && value != "linear" {
+				err = fmt.Errorf("Unknown color space %s", value)
+				return
+			}
+			linear = value == "linear"
+		case "wrap", "filter":
+			option, ok := options[setting][value]
+			if !ok {
+				err = fmt.Errorf("Unknown bitmap %s %s", setting, value)
+				return
+			}
+			if setting == "wrap" {
+				wrap = option
+			} else {
+				filter = option
+			}
+		case "scale", "offset":
+			var pair [2]float64
+			for i := range pair {
+				pair[i], err = s.readFloat()
+				if err != nil {
+					return
+				}
+				if i == 0 {
+					s.position++
+				}
+			}
+			if setting == "scale" {
+				scale = pair
+			} else {
+				offset = pair
+			}
+		case "rotation":
+			rotation, err = s.readFloat()
+			if err != nil {
+				return
+			}
+		default:
+			err = fmt.Errorf("Unknown bitmap setting %s", setting)
+			return
+		}
+	}
+
+	bitmap, err = NewBitmap(path, linear, wrap, filter, scale, offset, rotation)
+	if err != nil {
+		return
+	}
 
+	s.position++
 	return
 }
 
-func (s *SceneReader) readSimpleColor() (simpleColor SimpleColor, err error) {
+// readNoise reads a noise texture block with the two colors followed by the optional scale, octaves
+// and space (position or uv) settings. The name of the block selects the pattern.
+func (s *SceneReader) readNoise(name string) (noise Noise, err error) {
+	patterns := map[string]int{
+		"FBm": NoiseFBm, "Turbulence": NoiseTurbulence, "Marble": NoiseMarble, "Wood": NoiseWood, "Worley": NoiseWorley,
+	}
+	noise.pattern = patterns[name]
+	noise.scale, noise.octaves = 1, 4
+
 	s.position++
 	err = check(s.fileContent[s.position], "{")
 	if err != nil {
@@ -632,22 +3239,52 @@ func (s *SceneReader) readSimpleColor() (simpleColor SimpleColor, err error) {
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "color")
+	err = check(s.fileContent[s.position], "color1")
 	if err != nil {
 		return
 	}
 
 	s.position++
-	simpleColor.color, err = s.readColor()
+	noise.color1, err = s.readColor()
 	if err != nil {
 		return
 	}
 
 	s.position++
-	err = check(s.fileContent[s.position], "}")
+	err = check(s.fileContent[s.position], "color2")
+	if err != nil {
+		return
+	}
+
+	s.position++
+	noise.color2, err = s.readColor()
 	if err != nil {
 		return
 	}
+
+	for s.position++; s.fileContent[s.position] != "}"; s.position++ {
+		setting := s.fileContent[s.position]
+		s.position++
+		switch setting {
+		case "scale":
+			noise.scale, err = s.readFloat()
+		case "octaves":
+			noise.octaves, err = s.readFloat()
+		case "space":
+			value := s.fileContent[s.position]
+			if value != "position" && value != "uv" {
+				err = fmt.Errorf("Unknown noise space %s", value)
+				return
+			}
+			noise.useUV = value == "uv"
+		default:
+			err = fmt.Errorf("Unknown noise setting %s", setting)
+		}
+		if err != nil {
+			return
+		}
+	}
+
 	s.position++
 	return
 }
@@ -0,0 +1,77 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSceneLibraries(t *testing.T) {
+	// The library includes a file next to it and declares a texture, materials using it and a node.
+	directory := t.TempDir()
+	library := filepath.Join(directory, "library")
+	os.Mkdir(library, 0755)
+	os.WriteFile(filepath.Join(library, "textures.scene"), []byte(`Texture red { texture SimpleColor { color 255 0 0 } }`), 0644)
+	os.WriteFile(filepath.Join(library, "materials.scene"), []byte(`Include textures.scene
+		Material paint { shader Lambert { color 255 255 255 texture red } }
+		Material coat { shader Coated { base paint } }
+		Material smoke { shader GridVolume { grid noise { resolution 2 } } }
+		Node { geometry Sphere { center 0 0 0 radius 1 } shader paint }
+		End`), 0644)
+	os.WriteFile(filepath.Join(directory, "main.scene"), []byte(`Include "library/materials.scene"
+		Node { geometry Sphere { center 0 0 0 radius 1 } shader coat }
+		Node { geometry Cube { center 0 0 0 edge 1 } shader smoke }
+		Node { geometry Cube { center 5 0 0 edge 2 } shader smoke }
+		End`), 0644)
+
+	reader, err := NewSceneReader(filepath.Join(directory, "main.scene"))
+	if err != nil {
+		t.Fatalf("NewSceneReader() failed!")
+	}
+	nodes, err := reader.GetSceneNodes()
+	if err != nil || len(nodes) != 4 || reader.fileContent[reader.position] != "End" {
+		t.Fatalf("SceneReader.GetSceneNodes() failed!")
+	}
+
+	paint, ok := (*nodes[0].GetShader()).(*Lambert)
+	if !ok || paint.texture == nil || (*paint.texture).Sample(&IntersectionInfo{}) != (utils.Color{1, 0, 0}) {
+		t.Errorf("SceneReader.readMaterial() failed!")
+	}
+	if coat, ok := (*nodes[1].GetShader()).(*Coated); !ok || coat.base != Shader(paint) {
+		t.Errorf("SceneReader.readMaterial() failed!")
+	}
+
+	// Every node of a shared grid volume gets its own bounds.
+	first := (*nodes[2].GetShader()).(*Volume).medium.(*GridMedium)
+	second := (*nodes[3].GetShader()).(*Volume).medium.(*GridMedium)
+	if first.bounds.Max.X != 0.5 || second.bounds.Max.X != 6 || first.grid != second.grid {
+		t.Errorf("SceneReader.readNode() failed!")
+	}
+
+	os.WriteFile(filepath.Join(directory, "loop.scene"), []byte(`Include loop.scene`), 0644)
+	if reader, err := NewSceneReader(filepath.Join(directory, "loop.scene")); err != nil {
+		t.Errorf("NewSceneReader() failed!")
+	} else if _, err := reader.GetSceneNodes(); err == nil {
+		t.Errorf("SceneReader.readInclude() failed!")
+	}
+
+	reader.fileContent, reader.position = strings.Fields(`Node { geometry Sphere { center 0 0 0 radius 1 } shader missing } End`), 0
+	if _, err := reader.GetSceneNodes(); err == nil {
+		t.Errorf("SceneReader.readShader() failed!")
+	}
+}
+
+func TestReadCaps(t *testing.T) {
+	reader := SceneReader{fileContent: strings.Fields(`Cylinder { center 0 0 0 axis 0 1 0 radius 1 height 2 caps true }
+		Cone { center 0 0 0 axis 0 1 0 radius 1 topRadius 2 height 2 bottomCap true topCap false } end`)}
+	cylinder, err := reader.readCylinder()
+	if err != nil || !cylinder.bottomCap || !cylinder.topCap {
+		t.Errorf("SceneReader.readCylinder() failed!")
+	}
+	cone, err := reader.readCone()
+	if err != nil || !cone.bottomCap || cone.topCap || reader.fileContent[reader.position] != "end" {
+		t.Errorf("SceneReader.readCone() failed!")
+	}
+}
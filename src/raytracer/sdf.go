@@ -0,0 +1,311 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+)
+
+// DistanceFunction provides an interface for signed distance functions.
+// Distance returns the distance from the point to the surface, negative inside.
+type DistanceFunction interface {
+	Distance(point mathutils.Vector) float64
+}
+
+// SDF defines a geometry whose surface is the zero set of a signed distance function.
+// It is intersected by sphere tracing inside its bounding box.
+type SDF struct {
+	function    DistanceFunction      // The distance function of the surface.
+	maxSteps    int                   // The maximal number of steps along a ray.
+	epsilon     float64               // The distance at which the surface counts as hit.
+	maxDistance float64               // The distance after which the ray counts as missing.
+	bounds      mathutils.BoundingBox // The box containing the surface.
+}
+
+// NewSDF creates and returns a new signed distance field geometry. The bounding box is the one
+// of the distance function if it is Bounded, and infinite otherwise.
+func NewSDF(function DistanceFunction, maxSteps int, epsilon, maxDistance float64) SDF {
+	return SDF{function, maxSteps, epsilon, maxDistance, distanceBounds(function)}
+}
+
+// SetBounds sets the box containing the surface, for distance functions which cannot compute it.
+func (s *SDF) SetBounds(bounds mathutils.BoundingBox) {
+	s.bounds = bounds
+}
+
+// BoundingBox implements the Bounded interface for SDF.
+func (s *SDF) BoundingBox() mathutils.BoundingBox {
+	return s.bounds
+}
+
+// Intersect implements the intersect method of the Geometry interface for SDF.
+func (s *SDF) Intersect(ray *Ray, info *IntersectionInfo) bool {
+	// Rays starting inside march on the negated distance so they can leave the surface.
+	sign := 1.0
+	if s.function.Distance(ray.Start) < 0 {
+		sign = -1
+	}
+
+	// The march starts where the ray enters the bounding box and ends where it leaves it.
+	inverseDirection := mathutils.NewVector(1/ray.Direction.X, 1/ray.Direction.Y, 1/ray.Direction.Z)
+	near, far, ok := s.bounds.IntersectRay(ray.Start, inverseDirection)
+	if !ok {
+		return false
+	}
+	far = math.Min(far+s.epsilon, s.maxDistance)
+
+	// Rays starting on the surface, like shadow rays, only hit after moving away from it first.
+	// Rays starting outside the box are away from it already, even if the surface touches the box.
+	distance := math.Max(near, 0)
+	away := near > 0
+	for step := 0; step < s.maxSteps && distance < far; step++ {
+		position := mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(ray.Direction, distance))
+		value := sign * s.function.Distance(position)
+		if value >= s.epsilon {
+			away = true
+		} else if away {
+			info.Distance = distance
+			info.Position = position
+			info.Normal = s.gradient(position)
+			info.U, info.V = boxUV(position, info.Normal)
+			info.DPdu, info.DPdv = boxDerivatives(info.Normal)
+			return true
+		}
+
+		distance += math.Max(value, s.epsilon)
+	}
+
+	return false
+}
+
+// gradient returns the normalized gradient of the distance function at the point.
+func (s *SDF) gradient(point mathutils.Vector) mathutils.Vector {
+	h := s.epsilon
+	difference := func(offset mathutils.Vector) float64 {
+		return s.function.Distance(mathutils.VectorAddition(point, offset)) - s.function.Distance(mathutils.VectorSubstraction(point, offset))
+	}
+
+	result := mathutils.NewVector(difference(mathutils.NewVector(h, 0, 0)), difference(mathutils.NewVector(0, h, 0)), difference(mathutils.NewVector(0, 0, h)))
+	result.Normalize()
+	return result
+}
+
+// distanceBounds returns the bounding box of the distance function, infinite if it is not Bounded.
+func distanceBounds(function DistanceFunction) mathutils.BoundingBox {
+	if bounded, ok := function.(Bounded); ok {
+		return bounded.BoundingBox()
+	}
+
+	return mathutils.InfiniteBoundingBox()
+}
+
+// aroundPoint returns the box reaching from the center by the half size along every axis.
+func aroundPoint(center, halfSize mathutils.Vector) mathutils.BoundingBox {
+	return mathutils.NewBoundingBox(mathutils.VectorSubstraction(center, halfSize), mathutils.VectorAddition(center, halfSize))
+}
+
+// boxUV returns U and V coordinates by projecting the point along the dominant axis of the normal.
+func boxUV(point, normal mathutils.Vector) (float64, float64) {
+	x, y, z := math.Abs(normal.X), math.Abs(normal.Y), math.Abs(normal.Z)
+	if y >= x && y >= z {
+		return point.X, point.Z
+	}
+
+	return point.X + point.Z, point.Y
+}
+
+// SphereDistance defines the distance to a sphere.
+type SphereDistance struct {
+	center mathutils.Vector
+	radius float64
+}
+
+// NewSphereDistance creates and returns the distance function of a sphere.
+func NewSphereDistance(center mathutils.Vector, radius float64) SphereDistance {
+	return SphereDistance{center, radius}
+}
+
+// Distance implements the DistanceFunction interface for SphereDistance.
+func (s *SphereDistance) Distance(point mathutils.Vector) float64 {
+	relative := mathutils.VectorSubstraction(point, s.center)
+	return relative.Length() - s.radius
+}
+
+// BoundingBox implements the Bounded interface for SphereDistance.
+func (s *SphereDistance) BoundingBox() mathutils.BoundingBox {
+	return aroundPoint(s.center, mathutils.NewVector(s.radius, s.radius, s.radius))
+}
+
+// BoxDistance defines the distance to an axis-aligned box with optionally rounded edges.
+type BoxDistance struct {
+	center   mathutils.Vector
+	halfSize mathutils.Vector
+	rounding float64
+}
+
+// NewBoxDistance creates and returns the distance function of a box with the given size and edge rounding radius.
+func NewBoxDistance(center, size mathutils.Vector, rounding float64) BoxDistance {
+	halfSize := mathutils.VectorMultiply(size, 0.5)
+	halfSize = mathutils.VectorSubstraction(halfSize, mathutils.NewVector(rounding, rounding, rounding))
+	return BoxDistance{center, halfSize, rounding}
+}
+
+// Distance implements the DistanceFunction interface for BoxDistance.
+func (b *BoxDistance) Distance(point mathutils.Vector) float64 {
+	relative := mathutils.VectorSubstraction(point, b.center)
+	q := mathutils.NewVector(math.Abs(relative.X)-b.halfSize.X, math.Abs(relative.Y)-b.halfSize.Y, math.Abs(relative.Z)-b.halfSize.Z)
+	outside := mathutils.NewVector(math.Max(q.X, 0), math.Max(q.Y, 0), math.Max(q.Z, 0))
+	inside := math.Min(math.Max(q.X, math.Max(q.Y, q.Z)), 0)
+	return outside.Length() + inside - b.rounding
+}
+
+// BoundingBox implements the Bounded interface for BoxDistance.
+func (b *BoxDistance) BoundingBox() mathutils.BoundingBox {
+	return aroundPoint(b.center, mathutils.VectorAddition(b.halfSize, mathutils.NewVector(b.rounding, b.rounding, b.rounding)))
+}
+
+// TorusDistance defines the distance to a torus lying around the Y axis.
+type TorusDistance struct {
+	center      mathutils.Vector
+	majorRadius float64
+	minorRadius float64
+}
+
+// NewTorusDistance creates and returns the distance function of a torus.
+func NewTorusDistance(center mathutils.Vector, majorRadius, minorRadius float64) TorusDistance {
+	return TorusDistance{center, majorRadius, minorRadius}
+}
+
+// Distance implements the DistanceFunction interface for TorusDistance.
+func (t *TorusDistance) Distance(point mathutils.Vector) float64 {
+	relative := mathutils.VectorSubstraction(point, t.center)
+	ring := math.Sqrt(relative.X*relative.X+relative.Z*relative.Z) - t.majorRadius
+	return math.Sqrt(ring*ring+relative.Y*relative.Y) - t.minorRadius
+}
+
+// BoundingBox implements the Bounded interface for TorusDistance.
+func (t *TorusDistance) BoundingBox() mathutils.BoundingBox {
+	outer := t.majorRadius + t.minorRadius
+	return aroundPoint(t.center, mathutils.NewVector(outer, t.minorRadius, outer))
+}
+
+// SmoothUnionDistance defines the union of distance functions blended together with a smooth minimum.
+type SmoothUnionDistance struct {
+	children   []DistanceFunction
+	smoothness float64
+}
+
+// NewSmoothUnionDistance creates and returns a smooth union. A zero smoothness gives a sharp union.
+func NewSmoothUnionDistance(children []DistanceFunction, smoothness float64) SmoothUnionDistance {
+	return SmoothUnionDistance{children, smoothness}
+}
+
+// Distance implements the DistanceFunction interface for SmoothUnionDistance.
+func (s *SmoothUnionDistance) Distance(point mathutils.Vector) float64 {
+	result := s.children[0].Distance(point)
+	for _, child := range s.children[1:] {
+		result = smoothMin(result, child.Distance(point), s.smoothness)
+	}
+
+	return result
+}
+
+// BoundingBox implements the Bounded interface for SmoothUnionDistance. The smooth minimum lies at most
+// a quarter of the smoothness below the sharp one, so the blend stays that close to the children.
+func (s *SmoothUnionDistance) BoundingBox() mathutils.BoundingBox {
+	result := mathutils.EmptyBoundingBox()
+	for _, child := range s.children {
+		result = mathutils.BoundingBoxUnion(result, distanceBounds(child))
+	}
+	if result.IsInfinite() {
+		return result
+	}
+
+	margin := math.Max(s.smoothness, 0) / 4
+	grow := mathutils.NewVector(margin, margin, margin)
+	return mathutils.NewBoundingBox(mathutils.VectorSubstraction(result.Min, grow), mathutils.VectorAddition(result.Max, grow))
+}
+
+// smoothMin returns the polynomial smooth minimum of a and b.
+func smoothMin(a, b, smoothness float64) float64 {
+	if smoothness <= 0 {
+		return math.Min(a, b)
+	}
+
+	h := math.Max(smoothness-math.Abs(a-b), 0) / smoothness
+	return math.Min(a, b) - h*h*smoothness/4
+}
+
+// RepetitionDistance defines infinitely many copies of a distance function placed on a grid.
+type RepetitionDistance struct {
+	child  DistanceFunction
+	period mathutils.Vector // The grid spacing, a zero component disables the repetition along that axis.
+}
+
+// NewRepetitionDistance creates and returns a repetition of child with the given period.
+func NewRepetitionDistance(child DistanceFunction, period mathutils.Vector) RepetitionDistance {
+	return RepetitionDistance{child, period}
+}
+
+// Distance implements the DistanceFunction interface for RepetitionDistance.
+func (r *RepetitionDistance) Distance(point mathutils.Vector) float64 {
+	repeat := func(value, period float64) float64 {
+		if period <= 0 {
+			return value
+		}
+		return value - period*math.Floor(value/period+0.5)
+	}
+
+	return r.child.Distance(mathutils.NewVector(repeat(point.X, r.period.X), repeat(point.Y, r.period.Y), repeat(point.Z, r.period.Z)))
+}
+
+// MandelbulbDistance defines the distance estimate of the Mandelbulb fractal.
+type MandelbulbDistance struct {
+	center     mathutils.Vector
+	scale      float64 // The size of the fractal, which is about 2.4 units across at scale 1.
+	power      float64 // The power of the iteration, 8 for the classic shape.
+	iterations int     // The number of iterations, more give finer details.
+}
+
+// NewMandelbulbDistance creates and returns the distance estimate of a Mandelbulb.
+func NewMandelbulbDistance(center mathutils.Vector, scale, power float64, iterations int) MandelbulbDistance {
+	return MandelbulbDistance{center, scale, power, iterations}
+}
+
+// Distance implements the DistanceFunction interface for MandelbulbDistance.
+func (m *MandelbulbDistance) Distance(point mathutils.Vector) float64 {
+	c := mathutils.VectorMultiply(mathutils.VectorSubstraction(point, m.center), 1/m.scale)
+	z := c
+	derivative := 1.0
+	radius := 0.0
+	for i := 0; i < m.iterations; i++ {
+		radius = z.Length()
+		if radius > 2 {
+			break
+		}
+
+		// The center of the fractal is a fixed point of the iteration, where the angles are undefined.
+		if radius == 0 {
+			break
+		}
+
+		theta := math.Acos(z.Z/radius) * m.power
+		phi := math.Atan2(z.Y, z.X) * m.power
+		derivative = math.Pow(radius, m.power-1)*m.power*derivative + 1
+
+		zr := math.Pow(radius, m.power)
+		z = mathutils.NewVector(zr*math.Sin(theta)*math.Cos(phi), zr*math.Sin(phi)*math.Sin(theta), zr*math.Cos(theta))
+		z.Add(c)
+	}
+
+	if radius == 0 {
+		return 0
+	}
+	return 0.5 * math.Log(radius) * radius / derivative * m.scale
+}
+
+// BoundingBox implements the Bounded interface for MandelbulbDistance. The points farther than 2 from
+// the center escape at the first iteration, so the fractal stays within that radius.
+func (m *MandelbulbDistance) BoundingBox() mathutils.BoundingBox {
+	return aroundPoint(m.center, mathutils.NewVector(2*m.scale, 2*m.scale, 2*m.scale))
+}
@@ -0,0 +1,86 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSDFIntersect(t *testing.T) {
+	sphere := NewSphereDistance(mathutils.NewVector(0, 0, 0), 1)
+	box := NewBoxDistance(mathutils.NewVector(3, 0, 0), mathutils.NewVector(2, 2, 2), 0)
+	union := NewSmoothUnionDistance([]DistanceFunction{&sphere, &box}, 0)
+	sdf := NewSDF(&union, 256, 1e-6, 100)
+	var info IntersectionInfo
+
+	ray := NewRay(mathutils.NewVector(-10, 0, 0), mathutils.NewVector(1, 0, 0))
+	if !sdf.Intersect(&ray, &info) || math.Abs(info.Distance-9) > 1e-4 || mathutils.DotProduct(info.Normal, mathutils.NewVector(-1, 0, 0)) < 0.999 {
+		t.Errorf("SDF.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(3, 10, 0), mathutils.NewVector(0, -1, 0))
+	if !sdf.Intersect(&ray, &info) || math.Abs(info.Distance-9) > 1e-4 || mathutils.DotProduct(info.Normal, mathutils.NewVector(0, 1, 0)) < 0.999 {
+		t.Errorf("SDF.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(0, 0, 0), mathutils.NewVector(0, 0, 1))
+	if !sdf.Intersect(&ray, &info) || math.Abs(info.Distance-1) > 1e-4 {
+		t.Errorf("SDF.Intersect() failed!")
+	}
+
+	ray = NewRay(mathutils.NewVector(-10, 5, 0), mathutils.NewVector(1, 0, 0))
+	if sdf.Intersect(&ray, &info) {
+		t.Errorf("SDF.Intersect() failed!")
+	}
+
+	repetition := NewRepetitionDistance(&sphere, mathutils.NewVector(4, 0, 0))
+	sdf = NewSDF(&repetition, 256, 1e-6, 100)
+	ray = NewRay(mathutils.NewVector(-8, 0, 5), mathutils.NewVector(0, 0, -1))
+	if !sdf.Intersect(&ray, &info) || math.Abs(info.Distance-4) > 1e-4 {
+		t.Errorf("SDF.Intersect() failed!")
+	}
+}
+
+func TestSDFBoundingBox(t *testing.T) {
+	sphere := NewSphereDistance(mathutils.NewVector(0, 0, 0), 1)
+	torus := NewTorusDistance(mathutils.NewVector(5, 0, 0), 2, 0.5)
+	union := NewSmoothUnionDistance([]DistanceFunction{&sphere, &torus}, 2)
+	sdf := NewSDF(&union, 256, 1e-6, 100)
+	if sdf.BoundingBox() != mathutils.NewBoundingBox(mathutils.NewVector(-1.5, -1.5, -3), mathutils.NewVector(8, 1.5, 3)) {
+		t.Errorf("SDF.BoundingBox() failed!")
+	}
+
+	repetition := NewRepetitionDistance(&sphere, mathutils.NewVector(4, 0, 0))
+	sdf = NewSDF(&repetition, 256, 1e-6, 100)
+	if bounds := sdf.BoundingBox(); !bounds.IsInfinite() {
+		t.Errorf("SDF.BoundingBox() failed!")
+	}
+
+	// The bounds of the scene block stop the march of the rays passing beside the surface.
+	reader := SceneReader{fileContent: strings.Fields(`SDF { bounds -10 -1 -1 10 1 1
+		function Repeat { period 4 0 0 function Sphere { center 0 0 0 radius 1 } } } end`)}
+	sdf, err := reader.readSDF()
+	if err != nil || sdf.BoundingBox() != mathutils.NewBoundingBox(mathutils.NewVector(-10, -1, -1), mathutils.NewVector(10, 1, 1)) {
+		t.Errorf("SceneReader.readSDF() failed!")
+	}
+	var info IntersectionInfo
+	ray := NewRay(mathutils.NewVector(-8, 0, 5), mathutils.NewVector(0, 0, -1))
+	if !sdf.Intersect(&ray, &info) || math.Abs(info.Distance-4) > 1e-2 {
+		t.Errorf("SDF.Intersect() failed!")
+	}
+	ray = NewRay(mathutils.NewVector(12, 0, 5), mathutils.NewVector(0, 0, -1))
+	if sdf.Intersect(&ray, &info) {
+		t.Errorf("SDF.Intersect() failed!")
+	}
+}
+
+func TestMandelbulbDistance(t *testing.T) {
+	mandelbulb := NewMandelbulbDistance(mathutils.NewVector(1, 2, 3), 2, 8, 10)
+	if distance := mandelbulb.Distance(mathutils.NewVector(1, 2, 3)); math.IsNaN(distance) || distance > 0 {
+		t.Errorf("MandelbulbDistance.Distance() failed!")
+	}
+	if distance := mandelbulb.Distance(mathutils.NewVector(1, 2, 10)); distance <= 0 {
+		t.Errorf("MandelbulbDistance.Distance() failed!")
+	}
+}
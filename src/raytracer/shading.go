@@ -45,25 +45,69 @@ func NewChecker(color1, color2 utils.Color, scale float64) Checker {
 }
 
 // Sample implements sampling for Checker.
+// With ray differentials the checks are averaged over the footprint of the pixel, so they fade to the mean color
+// in the distance instead of aliasing.
 func (c *Checker) Sample(info *IntersectionInfo) utils.Color {
-	x := int(math.Floor(info.U * c.scale / 5.0))
-	y := int(math.Floor(info.V * c.scale / 5.0))
-	if (x+y)%2 == 0 {
-		return c.color1
+	s := info.U * c.scale / 5.0
+	t := info.V * c.scale / 5.0
+	ds := math.Max(math.Abs(info.DUdx), math.Abs(info.DUdy)) * c.scale / 5.0
+	dt := math.Max(math.Abs(info.DVdx), math.Abs(info.DVdy)) * c.scale / 5.0
+	if ds == 0 || dt == 0 {
+		x := int(math.Floor(s))
+		y := int(math.Floor(t))
+		if (x+y)%2 == 0 {
+			return c.color1
+		}
+
+		return c.color2
+	}
+
+	// The share of odd checks along each axis is the average over [s - ds, s + ds] of a square wave,
+	// the second color covers the area where exactly one of the axes is odd.
+	oddShare := func(value, width float64) float64 {
+		if width >= 1 {
+			return 0.5
+		}
+		integral := func(x float64) float64 {
+			return math.Floor(x/2) + 2*math.Max(x/2-math.Floor(x/2)-0.5, 0)
+		}
+		return (integral(value+width) - integral(value-width)) / (2 * width)
+	}
+	sOdd, tOdd := oddShare(s, ds), oddShare(t, dt)
+	share := sOdd + tOdd - 2*sOdd*tOdd
+
+	return utils.ColorAddition(utils.MultiplyColorFloat(c.color1, 1-share), utils.MultiplyColorFloat(c.color2, share))
+}
+
+// VertexColor defines a texture returning the color interpolated from the mesh vertices.
+type VertexColor struct {
+	color utils.Color // The color used for geometry without vertex colors.
+}
+
+// NewVertexColor creates and returns a VertexColor texture.
+func NewVertexColor(color utils.Color) VertexColor {
+	return VertexColor{color}
+}
+
+// Sample implements sampling for VertexColor.
+func (v *VertexColor) Sample(info *IntersectionInfo) utils.Color {
+	if info.HasVertexColor {
+		return info.VertexColor
 	}
 
-	return c.color2
+	return v.color
 }
 
 // Lambert defines a lambert shader.
 type Lambert struct {
 	color   utils.Color
 	texture *Texture
+	surfaceDetail
 }
 
 // NewLambert creates and returns a new lambert shader.
 func NewLambert(color utils.Color, texture Texture) Lambert {
-	return Lambert{color, &texture}
+	return Lambert{color: color, texture: &texture}
 }
 
 // SetTexture sets the texture for the current lambert shader.
@@ -74,22 +118,33 @@ func (l *Lambert) SetTexture(texture Texture) {
 // Shade implements a lambert shader.
 func (l *Lambert) Shade(ray *Ray, info *IntersectionInfo, scene *Scene) utils.Color {
 	var result utils.Color
+	shading := l.shadingInfo(info)
 	diffuse := l.color
 	if l.texture != nil {
-		diffuse = (*l.texture).Sample(info)
+		diffuse = sampleTexture(*l.texture, info)
 	}
 
 	diffuse = utils.ColorMultiplication(diffuse, scene.ambientLight)
 
+	displacedRay := mathutils.VectorAddition(info.Position, mathutils.VectorMultiply(info.Normal, 1e-5))
 	for _, light := range scene.lights {
-		displacedRay := mathutils.VectorAddition(info.Position, mathutils.VectorMultiply(info.Normal, 1e-5))
-		if visibilityCheck(displacedRay, light.position, scene) {
-			lightContribution := getLightContribution(ray, info, &light)
-			result = utils.ColorMultiplication(diffuse, lightContribution)
-		} else {
-			result = utils.ColorMultiplication(diffuse, utils.Color{0.05, 0.05, 0.05})
+		samples := light.sampleLight(info.Position)
+		result = utils.Color{}
+		for _, sample := range samples {
+			if visible := visibility(displacedRay, sample.toLight, sample.distance, scene); visible != (utils.Color{}) {
+				lightContribution := utils.ColorMultiplication(getLightContribution(ray, shading, &sample), visible)
+				result = utils.ColorAddition(result, utils.ColorMultiplication(diffuse, lightContribution))
+			} else {
+				shadow := 0.05 / float64(len(samples))
+				result = utils.ColorAddition(result, utils.ColorMultiplication(diffuse, utils.Color{shadow, shadow, shadow}))
+			}
 		}
 	}
+
+	normal := mathutils.Faceforward(ray.Direction, shading.Normal)
+	result = utils.ColorAddition(result, environmentLighting(ray, shading, scene, func(toLight mathutils.Vector) utils.Color {
+		return utils.MultiplyColorFloat(diffuse, math.Max(0, mathutils.DotProduct(normal, toLight))/math.Pi)
+	}))
 	return result
 }
 
@@ -99,11 +154,12 @@ type Phong struct {
 	texture            *Texture
 	specularMultiplier float64
 	specularExponent   float64
+	surfaceDetail
 }
 
 // NewPhong creates and returns a new phong shader.
 func NewPhong(color utils.Color, texture Texture, specularMultiplier, specularExponent float64) Phong {
-	return Phong{color, &texture, specularMultiplier, specularExponent}
+	return Phong{color: color, texture: &texture, specularMultiplier: specularMultiplier, specularExponent: specularExponent}
 }
 
 // SetTexture sets the texture for the current phong shader.
@@ -114,62 +170,152 @@ func (p *Phong) SetTexture(texture Texture) {
 // Shade implements a phong shader.
 func (p *Phong) Shade(ray *Ray, info *IntersectionInfo, scene *Scene) utils.Color {
 	var result utils.Color
+	shading := p.shadingInfo(info)
 	diffuse := p.color
 	if p.texture != nil {
-		diffuse = (*p.texture).Sample(info)
+		diffuse = sampleTexture(*p.texture, info)
 	}
 
+	toCamera := ray.Direction
+	toCamera.UnaryMinus()
 	for _, light := range scene.lights {
-		reflected := mathutils.Reflect(mathutils.VectorSubstraction(info.Position, light.position), info.Normal)
-		toCamera := ray.Direction
-		toCamera.UnaryMinus()
-		cosGamma := mathutils.DotProduct(toCamera, reflected)
-		phongCoeff := 0.0
-		if cosGamma > 0 {
-			phongCoeff = math.Pow(cosGamma, p.specularExponent)
-		}
+		var specular utils.Color
+		for _, sample := range light.sampleLight(info.Position) {
+			// The specular term has always used the unnormalized vector from a point light, keep it for them.
+			fromLight := mathutils.VectorMultiply(sample.toLight, -1)
+			if !math.IsInf(sample.distance, 1) {
+				fromLight.Multiply(sample.distance)
+			}
+			reflected := mathutils.Reflect(fromLight, shading.Normal)
+			cosGamma := mathutils.DotProduct(toCamera, reflected)
+			phongCoeff := 0.0
+			if cosGamma > 0 {
+				phongCoeff = math.Pow(cosGamma, p.specularExponent)
+			}
 
-		lightContribution := utils.ColorMultiplication(scene.ambientLight, getLightContribution(ray, info, &light))
-		diffuse = utils.ColorAddition(diffuse, lightContribution)
-		specular := utils.MultiplyColorFloat(lightContribution, phongCoeff*p.specularMultiplier)
+			lightContribution := utils.ColorMultiplication(scene.ambientLight, getLightContribution(ray, shading, &sample))
+			diffuse = utils.ColorAddition(diffuse, lightContribution)
+			specular = utils.ColorAddition(specular, utils.MultiplyColorFloat(lightContribution, phongCoeff*p.specularMultiplier))
+		}
 		result = utils.ColorAddition(diffuse, specular)
 	}
 
+	// The environment is reflected with the same diffuse color and specular lobe as the lights.
+	normal := mathutils.Faceforward(ray.Direction, shading.Normal)
+	surfaceColor := p.color
+	if p.texture != nil {
+		surfaceColor = sampleTexture(*p.texture, info)
+	}
+	result = utils.ColorAddition(result, environmentLighting(ray, shading, scene, func(toLight mathutils.Vector) utils.Color {
+		cosTheta := math.Max(0, mathutils.DotProduct(normal, toLight))
+		reflected := mathutils.Reflect(mathutils.VectorMultiply(toLight, -1), normal)
+		cosGamma := -mathutils.DotProduct(ray.Direction, reflected)
+		phongCoeff := 0.0
+		if cosGamma > 0 {
+			phongCoeff = math.Pow(cosGamma, p.specularExponent) * p.specularMultiplier
+		}
+		share := utils.ColorAddition(surfaceColor, utils.Color{phongCoeff, phongCoeff, phongCoeff})
+		return utils.MultiplyColorFloat(share, cosTheta/math.Pi)
+	}))
+
 	result = utils.ColorMultiplication(result, scene.ambientLight)
 	return result
 }
 
-// visibilityCheck checks if there is an object between start and end.
-func visibilityCheck(start, end mathutils.Vector, scene *Scene) bool {
-	direction := mathutils.VectorSubstraction(end, start)
-	targetDistance := direction.Length()
-	direction.Normalize()
-	ray := NewRay(start, direction)
+// Hair defines a Kajiya-Kay hair shader for thin fibers like hair, fur or grass.
+// The lighting follows the fiber tangent given by DPdu instead of the normal.
+type Hair struct {
+	color            utils.Color
+	texture          *Texture
+	specularColor    utils.Color
+	specularExponent float64
+	surfaceDetail
+}
+
+// NewHair creates and returns a new hair shader.
+func NewHair(color utils.Color, texture Texture, specularColor utils.Color, specularExponent float64) Hair {
+	hair := Hair{color: color, specularColor: specularColor, specularExponent: specularExponent}
+	if texture != nil {
+		hair.texture = &texture
+	}
+	return hair
+}
+
+// SetTexture sets the texture for the current hair shader.
+func (h *Hair) SetTexture(texture Texture) {
+	h.texture = &texture
+}
+
+// Shade implements a hair shader. The diffuse term is the sine between the tangent and the light
+// and the specular term peaks on the cone of directions reflecting the light around the tangent.
+// Geometry without a tangent is shaded with a diffuse term from the normal.
+func (h *Hair) Shade(ray *Ray, info *IntersectionInfo, scene *Scene) utils.Color {
+	var result utils.Color
+	shading := h.shadingInfo(info)
+	diffuse := h.color
+	if h.texture != nil {
+		diffuse = sampleTexture(*h.texture, info)
+	}
 
-	for _, node := range scene.SceneNodes {
-		var info IntersectionInfo
-		if !(*node.geometry).Intersect(&ray, &info) {
+	tangent := info.DPdu
+	hasTangent := tangent.LengthSqr() > 0
+	if hasTangent {
+		tangent.Normalize()
+	}
+	toCamera := ray.Direction
+	toCamera.UnaryMinus()
+
+	displacedRay := mathutils.VectorAddition(info.Position, mathutils.VectorMultiply(info.Normal, 1e-5))
+	for _, sample := range sceneLightSamples(scene, info.Position) {
+		visible := visibility(displacedRay, sample.toLight, sample.distance, scene)
+		if visible == (utils.Color{}) {
 			continue
 		}
 
-		if info.Distance < targetDistance {
-			return false
+		toLight, intensity := sample.toLight, utils.ColorMultiplication(sample.intensity, visible)
+
+		var diffuseCoeff, specularCoeff float64
+		if hasTangent {
+			cosLight := mathutils.DotProduct(tangent, toLight)
+			cosCamera := mathutils.DotProduct(tangent, toCamera)
+			sinLight := math.Sqrt(math.Max(0, 1-cosLight*cosLight))
+			sinCamera := math.Sqrt(math.Max(0, 1-cosCamera*cosCamera))
+			diffuseCoeff = sinLight
+			specularCoeff = math.Pow(math.Max(0, sinLight*sinCamera-cosLight*cosCamera), h.specularExponent)
+		} else {
+			diffuseCoeff = math.Max(0, mathutils.DotProduct(mathutils.Faceforward(ray.Direction, shading.Normal), toLight))
 		}
+
+		result = utils.ColorAddition(result, utils.MultiplyColorFloat(utils.ColorMultiplication(diffuse, intensity), diffuseCoeff))
+		result = utils.ColorAddition(result, utils.MultiplyColorFloat(utils.ColorMultiplication(h.specularColor, intensity), specularCoeff))
 	}
 
-	return true
+	// The environment lights the diffuse term of the fibers.
+	result = utils.ColorAddition(result, environmentLighting(ray, shading, scene, func(toLight mathutils.Vector) utils.Color {
+		coeff := math.Max(0, mathutils.DotProduct(mathutils.Faceforward(ray.Direction, shading.Normal), toLight))
+		if hasTangent {
+			cosLight := mathutils.DotProduct(tangent, toLight)
+			coeff = math.Sqrt(math.Max(0, 1-cosLight*cosLight))
+		}
+		return utils.MultiplyColorFloat(diffuse, coeff/math.Pi)
+	}))
+
+	return utils.ColorMultiplication(result, scene.ambientLight)
 }
 
-// getLightContribution return the light contribution for the current point.
-func getLightContribution(ray *Ray, info *IntersectionInfo, light *Light) utils.Color {
-	vectorToLight := mathutils.VectorSubstraction(light.position, info.Position)
-	distanceToLightSqr := vectorToLight.LengthSqr()
+// sceneLightSamples returns the samples of all the lights of the scene for the point.
+func sceneLightSamples(scene *Scene, point mathutils.Vector) []lightSample {
+	var result []lightSample
+	for _, light := range scene.lights {
+		result = append(result, light.sampleLight(point)...)
+	}
 
-	vectorToLight.Normalize()
-	cosTheta := mathutils.DotProduct(vectorToLight, mathutils.Faceforward(ray.Direction, info.Normal))
+	return result
+}
 
-	result := light.color
-	result.Multiply(light.power / distanceToLightSqr * cosTheta)
+// getLightContribution return the light contribution of the light sample for the current point.
+func getLightContribution(ray *Ray, info *IntersectionInfo, sample *lightSample) utils.Color {
+	cosTheta := mathutils.DotProduct(sample.toLight, mathutils.Faceforward(ray.Direction, info.Normal))
 
-	return result
+	return utils.MultiplyColorFloat(sample.intensity, cosTheta)
 }
@@ -0,0 +1,186 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+)
+
+// skyUnit converts luminances in kcd/m² of the sky model to the colors of the raytracer,
+// so a clear sky at noon is a little brighter than half white.
+const skyUnit = 0.05
+
+// Sky defines the settings of a daylight sky following the Preetham model, with +X pointing east,
+// +Y up and +Z north. The sun disc itself is not part of the sky, it is the matching directional light.
+type Sky struct {
+	SunDirection mathutils.Vector // The direction towards the sun, it does not have to be normalized.
+	Turbidity    float64          // The haziness of the air, 2 for a very clear sky to 10 for a hazy one.
+	GroundAlbedo utils.Color      // The color of the ground below the horizon.
+	SunSize      float64          // The angular diameter of the sun in degrees.
+	SunSamples   int              // The number of shadow rays for the sun.
+	SunIntensity float64          // The multiplier of the sun light, zero for no sun.
+}
+
+// NewSky creates and returns the settings of a sky with the sun in the direction,
+// the default turbidity of 3, a gray ground and a sun of the real size.
+func NewSky(sunDirection mathutils.Vector) Sky {
+	return Sky{sunDirection, 3, utils.Color{0.3, 0.3, 0.3}, 0.53, 8, 1}
+}
+
+// SunPosition returns the direction towards the sun for the place given by the latitude and longitude in degrees,
+// with east longitudes positive, the day of the year starting at 1 and the local standard time in hours
+// of the time zone given in hours from UTC.
+func SunPosition(latitude, longitude, timeZone float64, day int, time float64) mathutils.Vector {
+	latitude, longitude = mathutils.ToRadians(latitude), mathutils.ToRadians(longitude)
+	meridian := mathutils.ToRadians(15 * timeZone)
+	j := float64(day)
+
+	// The solar time and declination from the appendix of Preetham et al.
+	solarTime := time + 0.170*math.Sin(4*math.Pi*(j-80)/373) - 0.129*math.Sin(2*math.Pi*(j-8)/355) + 12*(longitude-meridian)/math.Pi
+	declination := 0.4093 * math.Sin(2*math.Pi*(j-81)/368)
+	hourAngle := math.Pi * (solarTime - 12) / 12
+
+	east := -math.Cos(declination) * math.Sin(hourAngle)
+	up := math.Sin(latitude)*math.Sin(declination) + math.Cos(latitude)*math.Cos(declination)*math.Cos(hourAngle)
+	north := math.Cos(latitude)*math.Sin(declination) - math.Sin(latitude)*math.Cos(declination)*math.Cos(hourAngle)
+
+	return mathutils.NewVector(east, up, north)
+}
+
+// DayOfYear returns the day of the year starting at 1 for the month and the day of the month in a common year.
+func DayOfYear(month, day int) int {
+	daysBefore := [12]int{0, 31, 59, 90, 120, 151, 181, 212, 243, 273, 304, 334}
+	month = int(math.Max(1, math.Min(float64(month), 12)))
+
+	return daysBefore[month-1] + day
+}
+
+// preethamSky holds the precomputed model of a sky.
+type preethamSky struct {
+	sunDirection mathutils.Vector
+	sunTheta     float64       // The angle of the sun from the zenith, at most at the horizon.
+	zenith       [3]float64    // The luminance and the x and y chromaticities at the zenith.
+	perez        [3][5]float64 // The coefficients of the Perez distribution for the luminance and the chromaticities.
+	ground       utils.Color   // The light reflected by the ground.
+}
+
+// newPreethamSky creates the model of the sky and the sun light matching it, which shines only above the horizon.
+func newPreethamSky(settings Sky) (preethamSky, *DirectionalLight) {
+	direction := settings.SunDirection
+	direction.Normalize()
+	t := settings.Turbidity
+	sky := preethamSky{sunDirection: direction, sunTheta: math.Acos(math.Max(0, math.Min(direction.Y, 1)))}
+
+	theta := sky.sunTheta
+	theta2, theta3 := theta*theta, theta*theta*theta
+	chi := (4.0/9.0 - t/120) * (math.Pi - 2*theta)
+	sky.zenith[0] = (4.0453*t-4.9710)*math.Tan(chi) - 0.2155*t + 2.4192
+	sky.zenith[1] = t*t*(0.00166*theta3-0.00375*theta2+0.00209*theta) +
+		t*(-0.02903*theta3+0.06377*theta2-0.03202*theta+0.00394) +
+		(0.11693*theta3 - 0.21196*theta2 + 0.06052*theta + 0.25886)
+	sky.zenith[2] = t*t*(0.00275*theta3-0.00610*theta2+0.00317*theta) +
+		t*(-0.04214*theta3+0.08970*theta2-0.04153*theta+0.00516) +
+		(0.15346*theta3 - 0.26756*theta2 + 0.06670*theta + 0.26688)
+	sky.perez = [3][5]float64{
+		{0.1787*t - 1.4630, -0.3554*t + 0.4275, -0.0227*t + 5.3251, 0.1206*t - 2.5771, -0.0670*t + 0.3703},
+		{-0.0193*t - 0.2592, -0.0665*t + 0.0008, -0.0004*t + 0.2125, -0.0641*t - 0.8989, -0.0033*t + 0.0452},
+		{-0.0167*t - 0.2608, -0.0950*t + 0.0092, -0.0079*t + 0.2102, -0.0441*t - 1.6537, -0.0109*t + 0.0529},
+	}
+
+	var sun *DirectionalLight
+	sunIrradiance := 0.0
+	if direction.Y > 0 && settings.SunIntensity > 0 {
+		light := NewDirectionalLight(direction, sunTransmittance(theta, t), settings.SunIntensity*sunIlluminance*skyUnit/math.Pi,
+			settings.SunSize, settings.SunSamples)
+		sun = &light
+		sunIrradiance = light.irradiance * direction.Y
+	}
+
+	// The ground reflects the sky and the sun diffusely, the sky is integrated over the upper hemisphere.
+	var irradiance utils.Color
+	const steps = 32
+	for i := 0; i < steps; i++ {
+		for j := 0; j < 2*steps; j++ {
+			zenithAngle := math.Pi / 2 * (float64(i) + 0.5) / steps
+			phi := math.Pi * (float64(j) + 0.5) / steps
+			dir := mathutils.NewVector(math.Sin(zenithAngle)*math.Cos(phi), math.Cos(zenithAngle), math.Sin(zenithAngle)*math.Sin(phi))
+			weight := math.Cos(zenithAngle) * math.Sin(zenithAngle) * (math.Pi / 2 / steps) * (math.Pi / steps)
+			irradiance = utils.ColorAddition(irradiance, utils.MultiplyColorFloat(sky.radiance(dir), weight))
+		}
+	}
+	if sun != nil {
+		irradiance = utils.ColorAddition(irradiance, utils.MultiplyColorFloat(sun.color, sunIrradiance*math.Pi))
+	}
+	sky.ground = utils.MultiplyColorFloat(utils.ColorMultiplication(settings.GroundAlbedo, irradiance), 1/math.Pi)
+
+	return sky, sun
+}
+
+// sunIlluminance is the illuminance of the sun above the atmosphere in klx.
+const sunIlluminance = 128.0
+
+// radiance returns the color of the sky in the normalized direction, or the ground below the horizon.
+func (s *preethamSky) radiance(direction mathutils.Vector) utils.Color {
+	if direction.Y < 0 {
+		return s.ground
+	}
+
+	// The Perez distribution breaks down at the horizon, where the cosine is kept from reaching zero.
+	cosTheta := math.Max(direction.Y, 0.01)
+	cosGamma := math.Max(-1, math.Min(mathutils.DotProduct(direction, s.sunDirection), 1))
+	gamma := math.Acos(cosGamma)
+
+	var values [3]float64
+	for i, coefficients := range s.perez {
+		values[i] = s.zenith[i] * perezDistribution(coefficients, cosTheta, gamma, cosGamma) /
+			perezDistribution(coefficients, 1, s.sunTheta, math.Cos(s.sunTheta))
+	}
+
+	return xyYToRGB(values[1], values[2], values[0]*skyUnit)
+}
+
+// perezDistribution returns the Perez sky distribution for the cosine of the angle from the zenith
+// and the angle from the sun.
+func perezDistribution(coefficients [5]float64, cosTheta, gamma, cosGamma float64) float64 {
+	a, b, c, d, e := coefficients[0], coefficients[1], coefficients[2], coefficients[3], coefficients[4]
+
+	return (1 + a*math.Exp(b/cosTheta)) * (1 + c*math.Exp(d*gamma) + e*cosGamma*cosGamma)
+}
+
+// xyYToRGB converts a color given by its chromaticities and luminance to linear sRGB, clamping negative channels.
+func xyYToRGB(x, y, luminance float64) utils.Color {
+	if y <= 0 {
+		return utils.Color{}
+	}
+
+	X := x / y * luminance
+	Z := (1 - x - y) / y * luminance
+	result := utils.Color{
+		3.2406*X - 1.5372*luminance - 0.4986*Z,
+		-0.9689*X + 1.8758*luminance + 0.0415*Z,
+		0.0557*X - 0.2040*luminance + 1.0570*Z,
+	}
+	for channel := range result {
+		result[channel] = math.Max(0, result[channel])
+	}
+
+	return result
+}
+
+// sunTransmittance returns the share of the sunlight passing through the atmosphere for the red, green and blue
+// wavelengths, from the Rayleigh scattering of the air and the Ångström turbidity of the aerosols.
+func sunTransmittance(theta, turbidity float64) utils.Color {
+	degrees := math.Min(theta*180/math.Pi, 93.885-1e-3)
+	airMass := 1 / (math.Cos(theta) + 0.15*math.Pow(93.885-degrees, -1.253))
+	beta := 0.04608*turbidity - 0.04586
+
+	var result utils.Color
+	for channel, wavelength := range [3]float64{0.68, 0.55, 0.44} {
+		rayleigh := 0.008735 * math.Pow(wavelength, -4.08)
+		aerosol := beta * math.Pow(wavelength, -1.3)
+		result[channel] = math.Exp(-airMass * (rayleigh + aerosol))
+	}
+
+	return result
+}
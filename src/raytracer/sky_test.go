@@ -0,0 +1,81 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSky(t *testing.T) {
+	// At noon on the equinox the sun stands at the zenith of the equator and in the south at 60 degrees north.
+	if sun := SunPosition(0, 0, 0, DayOfYear(3, 21), 12); sun.Y < 0.99 {
+		t.Errorf("SunPosition() failed!")
+	}
+	if sun := SunPosition(60, 15, 1, DayOfYear(3, 21), 12); math.Abs(sun.Y-0.5) > 0.03 || sun.Z > -0.8 {
+		t.Errorf("SunPosition() failed!")
+	}
+	if sun := SunPosition(45, 0, 0, DayOfYear(6, 21), 8); sun.X <= 0 || sun.Y <= 0 {
+		t.Errorf("SunPosition() failed!")
+	}
+
+	// The sky is brighter around the sun than opposite to it and the setting sun is redder than the high one.
+	settings := NewSky(mathutils.NewVector(1, 1, 0))
+	environment := NewSkyEnvironment(settings, 1, 16)
+	toSun, away := mathutils.NewVector(0.8, 0.6, 0), mathutils.NewVector(-0.8, 0.6, 0)
+	if luminance(environment.Radiance(toSun)) <= luminance(environment.Radiance(away)) {
+		t.Errorf("Environment.Radiance() failed!")
+	}
+	high, low := sunTransmittance(0, 3), sunTransmittance(math.Pi/2*0.98, 3)
+	if high[0] <= high[2] || low[0]/low[2] <= high[0]/high[2] {
+		t.Errorf("sunTransmittance() failed!")
+	}
+
+	// The sun matches the sky and its shadow rays stay within its disc.
+	sun, ok := environment.Sun()
+	if !ok || mathutils.DotProduct(sun.direction, mathutils.NewVector(math.Sqrt2/2, math.Sqrt2/2, 0)) < 0.9999 {
+		t.Errorf("Environment.Sun() failed!")
+	}
+	samples := sun.sampleLight(mathutils.NewVector(0.5, 0, 0.5))
+	if len(samples) != 8 {
+		t.Errorf("DirectionalLight.sampleLight() failed!")
+	}
+	for _, sample := range samples {
+		if mathutils.DotProduct(sample.toLight, sun.direction) < math.Cos(sun.angle/2)-1e-9 || !math.IsInf(sample.distance, 1) {
+			t.Errorf("DirectionalLight.sampleLight() failed!")
+		}
+	}
+	night := NewSkyEnvironment(NewSky(mathutils.NewVector(0, -1, 1)), 1, 16)
+	if _, ok := night.Sun(); ok {
+		t.Errorf("Environment.Sun() failed!")
+	}
+
+	// The samples of the sky prefer the directions around the sun.
+	nearSun := 0
+	for i := 0; i < 64; i++ {
+		direction, density := environment.sample(mathutils.NewVector(0, 1, 0), (float64(i)+0.5)/64, radicalInverse(uint(i)))
+		if density <= 0 {
+			t.Errorf("Environment.sample() failed!")
+		}
+		if direction.X > 0 {
+			nearSun++
+		}
+	}
+	if nearSun <= 32 {
+		t.Errorf("Environment.sample() failed!")
+	}
+
+	reader := SceneReader{fileContent: strings.Fields(`Environment { type sky latitude 48 longitude 11 timeZone 1 date 6 21 time 15
+		turbidity 4 groundAlbedo 50 60 40 } DirectionalLight { direction 0 1 1 angle 2 samples 4 } end`)}
+	parsed, err := reader.GetEnvironment()
+	if err != nil || parsed.kind != EnvironmentSky || parsed.sun == nil || parsed.sun.direction.X >= 0 {
+		t.Errorf("SceneReader.GetEnvironment() failed!")
+	}
+	lights, err := reader.GetLights()
+	if err != nil || len(lights) != 1 {
+		t.Fatalf("SceneReader.GetLights() failed!")
+	}
+	if directional, ok := lights[0].(DirectionalLight); !ok || directional.samples != 4 {
+		t.Errorf("SceneReader.GetLights() failed!")
+	}
+}
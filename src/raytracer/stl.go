@@ -0,0 +1,112 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// ReadSTL reads a mesh in the STL format, either ASCII or binary.
+// STL files store every triangle with its own vertices, equal vertices are merged.
+// The stored facet normals are ignored, the triangles are flat shaded following their winding.
+func ReadSTL(reader io.Reader) (data MeshData, err error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return
+	}
+
+	// Binary files may start with "solid" too, so the size given by the triangle count decides.
+	binarySize := -1
+	if len(content) >= 84 {
+		binarySize = 84 + 50*int(binary.LittleEndian.Uint32(content[80:84]))
+	}
+
+	merged := make(map[mathutils.Vector]int)
+	addVertex := func(vertex mathutils.Vector) int {
+		index, ok := merged[vertex]
+		if !ok {
+			index = len(data.Vertices)
+			merged[vertex] = index
+			data.Vertices = append(data.Vertices, vertex)
+		}
+		return index
+	}
+
+	switch {
+	case len(content) == binarySize:
+		err = readBinarySTL(content, addVertex, &data)
+	case bytes.HasPrefix(bytes.TrimSpace(content), []byte("solid")):
+		err = readASCIISTL(content, addVertex, &data)
+	default:
+		err = fmt.Errorf("Not an STL file")
+	}
+	if err != nil {
+		return
+	}
+
+	if len(data.Polygons) == 0 {
+		err = fmt.Errorf("STL file has no facets")
+	}
+	return
+}
+
+// readBinarySTL reads the triangles of a binary STL file.
+func readBinarySTL(content []byte, addVertex func(mathutils.Vector) int, data *MeshData) error {
+	count := int(binary.LittleEndian.Uint32(content[80:84]))
+	for i := 0; i < count; i++ {
+		// Every record holds the normal, three vertices and a 16 bit attribute.
+		record := content[84+50*i:]
+		polygon := make([]int, 3)
+		for j := range polygon {
+			var vertex [3]float64
+			for k := range vertex {
+				offset := 12 + 12*j + 4*k
+				vertex[k] = float64(math.Float32frombits(binary.LittleEndian.Uint32(record[offset : offset+4])))
+			}
+			polygon[j] = addVertex(mathutils.NewVector(vertex[0], vertex[1], vertex[2]))
+		}
+		data.Polygons = append(data.Polygons, polygon)
+	}
+
+	return nil
+}
+
+// readASCIISTL reads the triangles of an ASCII STL file.
+func readASCIISTL(content []byte, addVertex func(mathutils.Vector) int, data *MeshData) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Split(bufio.ScanWords)
+
+	var polygon []int
+	for scanner.Scan() {
+		switch scanner.Text() {
+		case "outer":
+			polygon = nil
+		case "vertex":
+			var vertex [3]float64
+			for k := range vertex {
+				if !scanner.Scan() {
+					return fmt.Errorf("STL facet %d: unexpected end of file", len(data.Polygons))
+				}
+				value, err := strconv.ParseFloat(scanner.Text(), 64)
+				if err != nil {
+					return fmt.Errorf("STL facet %d: %v", len(data.Polygons), err)
+				}
+				vertex[k] = value
+			}
+			polygon = append(polygon, addVertex(mathutils.NewVector(vertex[0], vertex[1], vertex[2])))
+		case "endloop":
+			if len(polygon) < 3 {
+				return fmt.Errorf("STL facet %d has only %d vertices", len(data.Polygons), len(polygon))
+			}
+			data.Polygons = append(data.Polygons, polygon)
+		}
+	}
+
+	return scanner.Err()
+}
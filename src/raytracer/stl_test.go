@@ -0,0 +1,55 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestReadSTL(t *testing.T) {
+	ascii := `solid test
+facet normal 0 0 -1
+  outer loop
+    vertex 0 0 0
+    vertex 0 1 0
+    vertex 1 0 0
+  endloop
+endfacet
+facet normal 0 0 -1
+  outer loop
+    vertex 1 0 0
+    vertex 0 1 0
+    vertex 1 1 0
+  endloop
+endfacet
+endsolid test
+`
+	data, err := ReadSTL(strings.NewReader(ascii))
+	if err != nil || len(data.Vertices) != 4 || len(data.Polygons) != 2 {
+		t.Errorf("ReadSTL() failed!")
+	}
+
+	var buffer bytes.Buffer
+	buffer.Write(make([]byte, 80))
+	binary.Write(&buffer, binary.LittleEndian, uint32(1))
+	binary.Write(&buffer, binary.LittleEndian, [12]float32{0, 0, -1, 0, 0, 0, 0, 1, 0, 1, 0, 0})
+	binary.Write(&buffer, binary.LittleEndian, uint16(0))
+	binaryData, err := ReadSTL(&buffer)
+	if err != nil || len(binaryData.Vertices) != 3 || !compareVectors(binaryData.Vertices[1], mathutils.NewVector(0, 1, 0)) {
+		t.Errorf("ReadSTL() failed!")
+	}
+
+	mesh := NewMesh(data)
+	var info IntersectionInfo
+	ray := NewRay(mathutils.NewVector(0.9, 0.9, -5), mathutils.NewVector(0, 0, 1))
+	if !mesh.Intersect(&ray, &info) || math.Abs(info.Distance-5) > 1e-6 || !compareVectors(info.Normal, mathutils.NewVector(0, 0, -1)) || info.HasVertexColor {
+		t.Errorf("Mesh.Intersect() failed!")
+	}
+
+	if _, err := ReadSTL(strings.NewReader("solid broken\nfacet normal 0 0 1\nouter loop\nvertex 0 0 x\n")); err == nil {
+		t.Errorf("ReadSTL() failed!")
+	}
+}
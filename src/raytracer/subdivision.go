@@ -0,0 +1,201 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+)
+
+// subdivisionVertex holds the vertex attributes which are refined by the subdivision.
+// The U and V coordinates and the color are averaged with the same weights as the position.
+type subdivisionVertex struct {
+	position mathutils.Vector
+	uv       [2]float64
+	color    utils.Color
+}
+
+func (v *subdivisionVertex) add(other subdivisionVertex, weight float64) {
+	v.position.Add(mathutils.VectorMultiply(other.position, weight))
+	v.uv[0] += other.uv[0] * weight
+	v.uv[1] += other.uv[1] * weight
+	v.color = utils.ColorAddition(v.color, utils.MultiplyColorFloat(other.color, weight))
+}
+
+// subdivisionEdge is an edge given by its vertex indices, the smaller one first.
+type subdivisionEdge [2]int
+
+func newSubdivisionEdge(a, b int) subdivisionEdge {
+	if a > b {
+		a, b = b, a
+	}
+	return subdivisionEdge{a, b}
+}
+
+// SubdivideCatmullClark refines the mesh data with the given number of Catmull-Clark subdivision steps.
+// Every step replaces each polygon with n vertices by n quads. Border edges, and edges shared by more
+// than two polygons, are kept as creases following the cubic B-spline of the border.
+// The vertex U and V coordinates and colors are refined with the positions,
+// the normals are replaced by smooth normals of the refined mesh.
+func SubdivideCatmullClark(data MeshData, levels int) (MeshData, error) {
+	if err := data.Validate(); err != nil {
+		return data, err
+	}
+
+	vertices := make([]subdivisionVertex, len(data.Vertices))
+	for i := range vertices {
+		vertices[i].position = data.Vertices[i]
+		if len(data.UVs) > 0 {
+			vertices[i].uv = data.UVs[i]
+		}
+		if len(data.Colors) > 0 {
+			vertices[i].color = data.Colors[i]
+		}
+	}
+
+	polygons := data.Polygons
+	for level := 0; level < levels; level++ {
+		vertices, polygons = subdivisionStep(vertices, polygons)
+	}
+
+	result := MeshData{Polygons: polygons}
+	for _, vertex := range vertices {
+		result.Vertices = append(result.Vertices, vertex.position)
+		if len(data.UVs) > 0 {
+			result.UVs = append(result.UVs, vertex.uv)
+		}
+		if len(data.Colors) > 0 {
+			result.Colors = append(result.Colors, vertex.color)
+		}
+	}
+	result.ComputeNormals()
+
+	return result, nil
+}
+
+// subdivisionStep performs one Catmull-Clark subdivision step.
+// The new vertices are the old vertices, followed by the face points and the edge points.
+func subdivisionStep(vertices []subdivisionVertex, polygons [][]int) ([]subdivisionVertex, [][]int) {
+	// Face points are the centroids of the polygons.
+	facePoints := make([]subdivisionVertex, len(polygons))
+	edgeFaces := make(map[subdivisionEdge][]int)
+	var edges []subdivisionEdge
+	for i, polygon := range polygons {
+		for j, index := range polygon {
+			facePoints[i].add(vertices[index], 1/float64(len(polygon)))
+
+			edge := newSubdivisionEdge(index, polygon[(j+1)%len(polygon)])
+			if _, ok := edgeFaces[edge]; !ok {
+				edges = append(edges, edge)
+			}
+			edgeFaces[edge] = append(edgeFaces[edge], i)
+		}
+	}
+
+	// Edge points average the edge ends and the face points of the two polygons, border edge points are the midpoints.
+	edgePoints := make(map[subdivisionEdge]int, len(edges))
+	edgePointValues := make([]subdivisionVertex, len(edges))
+	for i, edge := range edges {
+		faces := edgeFaces[edge]
+		if len(faces) == 2 {
+			edgePointValues[i].add(vertices[edge[0]], 0.25)
+			edgePointValues[i].add(vertices[edge[1]], 0.25)
+			edgePointValues[i].add(facePoints[faces[0]], 0.25)
+			edgePointValues[i].add(facePoints[faces[1]], 0.25)
+		} else {
+			edgePointValues[i].add(vertices[edge[0]], 0.5)
+			edgePointValues[i].add(vertices[edge[1]], 0.5)
+		}
+		edgePoints[edge] = len(vertices) + len(facePoints) + i
+	}
+
+	// The old vertices move to (F + 2R + (n - 3)P) / n, where F is the average of the adjacent face points,
+	// R the average of the adjacent edge midpoints and n the number of adjacent polygons.
+	// Border vertices follow the border curve with (R0 + 6P + R1) / 8.
+	faceSums := make([]subdivisionVertex, len(vertices))
+	faceCounts := make([]int, len(vertices))
+	for i, polygon := range polygons {
+		for _, index := range polygon {
+			faceSums[index].add(facePoints[i], 1)
+			faceCounts[index]++
+		}
+	}
+
+	edgeSums := make([]subdivisionVertex, len(vertices))
+	edgeCounts := make([]int, len(vertices))
+	borderSums := make([]subdivisionVertex, len(vertices))
+	borderCounts := make([]int, len(vertices))
+	for _, edge := range edges {
+		for k := 0; k < 2; k++ {
+			index, other := edge[k], edge[1-k]
+			edgeSums[index].add(vertices[index], 0.5)
+			edgeSums[index].add(vertices[other], 0.5)
+			edgeCounts[index]++
+			if len(edgeFaces[edge]) != 2 {
+				borderSums[index].add(vertices[other], 1)
+				borderCounts[index]++
+			}
+		}
+	}
+
+	result := make([]subdivisionVertex, 0, len(vertices)+len(facePoints)+len(edges))
+	for i, vertex := range vertices {
+		var moved subdivisionVertex
+		switch {
+		case faceCounts[i] == 0:
+			moved = vertex
+		case borderCounts[i] == 2 && faceCounts[i] > 1:
+			moved.add(vertex, 0.75)
+			moved.add(borderSums[i], 0.125)
+		case borderCounts[i] > 0:
+			// Corners of a single polygon and vertices joining several borders stay in place.
+			moved = vertex
+		default:
+			n := float64(faceCounts[i])
+			moved.add(faceSums[i], 1/(n*n))
+			moved.add(edgeSums[i], 2/(float64(edgeCounts[i])*n))
+			moved.add(vertex, (n-3)/n)
+		}
+		result = append(result, moved)
+	}
+	result = append(result, facePoints...)
+	result = append(result, edgePointValues...)
+
+	// Every polygon corner becomes a quad made of the corner, its next edge point, the face point and its previous edge point.
+	newPolygons := make([][]int, 0, 4*len(polygons))
+	for i, polygon := range polygons {
+		facePoint := len(vertices) + i
+		for j, index := range polygon {
+			next := polygon[(j+1)%len(polygon)]
+			previous := polygon[(j+len(polygon)-1)%len(polygon)]
+			newPolygons = append(newPolygons, []int{
+				index,
+				edgePoints[newSubdivisionEdge(index, next)],
+				facePoint,
+				edgePoints[newSubdivisionEdge(previous, index)],
+			})
+		}
+	}
+
+	return result, newPolygons
+}
+
+// ComputeNormals replaces the vertex normals with the area weighted average of the normals of the adjacent polygons.
+func (m *MeshData) ComputeNormals() {
+	m.Normals = make([]mathutils.Vector, len(m.Vertices))
+	for _, polygon := range m.Polygons {
+		// The cross products of the fan triangles are twice their areas along their normals.
+		for i := 2; i < len(polygon); i++ {
+			a, b, c := m.Vertices[polygon[0]], m.Vertices[polygon[i-1]], m.Vertices[polygon[i]]
+			normal := mathutils.CrossProduct(mathutils.VectorSubstraction(b, a), mathutils.VectorSubstraction(c, a))
+			for _, index := range polygon {
+				m.Normals[index].Add(normal)
+			}
+		}
+	}
+
+	for i := range m.Normals {
+		if m.Normals[i].LengthSqr() > 0 {
+			m.Normals[i].Normalize()
+		}
+	}
+}
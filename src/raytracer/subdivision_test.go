@@ -0,0 +1,43 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"testing"
+)
+
+func TestSubdivideCatmullClark(t *testing.T) {
+	cube := MeshData{
+		Vertices: []mathutils.Vector{
+			mathutils.NewVector(-1, -1, -1), mathutils.NewVector(1, -1, -1), mathutils.NewVector(1, 1, -1), mathutils.NewVector(-1, 1, -1),
+			mathutils.NewVector(-1, -1, 1), mathutils.NewVector(1, -1, 1), mathutils.NewVector(1, 1, 1), mathutils.NewVector(-1, 1, 1),
+		},
+		Polygons: [][]int{{0, 3, 2, 1}, {4, 5, 6, 7}, {0, 1, 5, 4}, {2, 3, 7, 6}, {0, 4, 7, 3}, {1, 2, 6, 5}},
+	}
+
+	data, err := SubdivideCatmullClark(cube, 1)
+	if err != nil || len(data.Vertices) != 26 || len(data.Polygons) != 24 || len(data.Normals) != 26 {
+		t.Fatalf("SubdivideCatmullClark() failed!")
+	}
+
+	// Corners of a cube move to 5/9 of their distance, face points stay at the face centers.
+	if !compareVectors(data.Vertices[6], mathutils.NewVector(5.0/9, 5.0/9, 5.0/9)) || !compareVectors(data.Vertices[9], mathutils.NewVector(0, 0, 1)) {
+		t.Errorf("SubdivideCatmullClark() failed!")
+	}
+
+	data, err = SubdivideCatmullClark(cube, 3)
+	if err != nil || len(data.Polygons) != 6*64 {
+		t.Fatalf("SubdivideCatmullClark() failed!")
+	}
+	mesh := NewMesh(data)
+	var info IntersectionInfo
+	ray := NewRay(mathutils.NewVector(0, 0, -10), mathutils.NewVector(0, 0, 1))
+	if !mesh.Intersect(&ray, &info) || info.Distance < 9 || info.Distance > 9.5 || mathutils.DotProduct(info.Normal, mathutils.NewVector(0, 0, -1)) < 0.999 {
+		t.Errorf("SubdivideCatmullClark() failed!")
+	}
+
+	open := MeshData{Vertices: cube.Vertices[:4], Polygons: [][]int{{0, 1, 2, 3}}}
+	data, err = SubdivideCatmullClark(open, 1)
+	if err != nil || !compareVectors(data.Vertices[0], cube.Vertices[0]) || !compareVectors(data.Vertices[5], mathutils.NewVector(0, -1, -1)) {
+		t.Errorf("SubdivideCatmullClark() failed!")
+	}
+}
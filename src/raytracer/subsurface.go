@@ -0,0 +1,189 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"math"
+)
+
+// subsurfaceMaxSteps limits the number of scattering events of a walk, longer walks are dropped.
+const subsurfaceMaxSteps = 256
+
+// Subsurface defines a shader for translucent materials like skin, wax or marble, where the light enters the surface,
+// scatters inside the closed geometry and leaves it somewhere else. The light is followed by random walks through
+// a homogeneous medium filling the geometry, lit where the walks leave it.
+type Subsurface struct {
+	color   utils.Color // The color of a thick block of the material, the share of the light leaving it again.
+	texture *Texture
+	radius  utils.Color // The mean distance the light travels inside before scattering, per channel.
+	samples int         // The number of walks per hit.
+	surfaceDetail
+}
+
+// NewSubsurface creates and returns a new subsurface scattering shader with the color, the scattering radius
+// per channel in scene units and the number of random walks per hit.
+func NewSubsurface(color utils.Color, texture Texture, radius utils.Color, samples int) Subsurface {
+	subsurface := Subsurface{color: color, radius: radius, samples: int(math.Max(1, float64(samples)))}
+	if texture != nil {
+		subsurface.SetTexture(texture)
+	}
+
+	return subsurface
+}
+
+// SetTexture sets the texture for the current subsurface shader.
+func (s *Subsurface) SetTexture(texture Texture) {
+	s.texture = &texture
+}
+
+// Shade implements a subsurface scattering shader. Every walk enters the surface in a diffuse direction
+// and scatters isotropically.
+func (s *Subsurface) Shade(ray *Ray, info *IntersectionInfo, scene *Scene) utils.Color {
+	shading := s.shadingInfo(info)
+	color := s.color
+	if s.texture != nil {
+		color = sampleTexture(*s.texture, shading)
+	}
+
+	var extinction, albedo utils.Color
+	for channel := range extinction {
+		extinction[channel] = 1 / math.Max(s.radius[channel], 1e-6)
+		albedo[channel] = singleScatteringAlbedo(color[channel])
+	}
+
+	inward := mathutils.Faceforward(ray.Direction, shading.Normal)
+	inward.UnaryMinus()
+	var result utils.Color
+	for i := 0; i < s.samples; i++ {
+		random := newRandomSequence(info.Position, ray.Direction, i)
+		direction := cosineDirection(inward, random.next(), random.next())
+		result = utils.ColorAddition(result, s.walk(info.Position, direction, extinction, albedo, &random, scene))
+	}
+
+	return utils.ColorMultiplication(utils.MultiplyColorFloat(result, 1/float64(s.samples)), scene.ambientLight)
+}
+
+// walk follows the light from the surface point into the geometry and returns the light it brings out,
+// black if the walk gets lost in an open geometry or lasts too long. Every distance is sampled for a channel
+// chosen by its share of the weight of the walk, and weighted by the density averaged over the channels
+// with the same shares, which keeps the weights from growing when the channels scatter at very different radii.
+func (s *Subsurface) walk(point, direction mathutils.Vector, extinction, albedo utils.Color,
+	random *randomSequence, scene *Scene) utils.Color {
+	weight := utils.Color{1, 1, 1}
+	for step := 0; step < subsurfaceMaxSteps; step++ {
+		ray := NewRay(mathutils.VectorAddition(point, mathutils.VectorMultiply(direction, 1e-5)), direction)
+		var hit IntersectionInfo
+		if !scene.intersect(&ray, &hit) {
+			return utils.Color{}
+		}
+
+		shares := utils.MultiplyColorFloat(weight, 1/(weight[0]+weight[1]+weight[2]))
+		channel := 2
+		switch u := random.next(); {
+		case u < shares[0]:
+			channel = 0
+		case u < shares[0]+shares[1]:
+			channel = 1
+		}
+
+		t := -math.Log(1-random.next()) / extinction[channel]
+		if t >= hit.Distance {
+			transmittance := channelTransmittance(extinction, hit.Distance)
+			probability := weightedAverage(shares, transmittance)
+			weight = utils.MultiplyColorFloat(utils.ColorMultiplication(weight, transmittance), 1/probability)
+
+			return utils.ColorMultiplication(weight, exitLighting(direction, &hit, scene))
+		}
+
+		transmittance := channelTransmittance(extinction, t)
+		density := utils.ColorMultiplication(extinction, transmittance)
+		pdf := weightedAverage(shares, density)
+		weight = utils.MultiplyColorFloat(utils.ColorMultiplication(weight, utils.ColorMultiplication(albedo, density)), 1/pdf)
+		if math.Max(weight[0], math.Max(weight[1], weight[2])) < 1e-4 {
+			return utils.Color{}
+		}
+
+		point = mathutils.VectorAddition(ray.Start, mathutils.VectorMultiply(direction, t))
+		direction = sphereDirection(random.next(), random.next())
+	}
+
+	return utils.Color{}
+}
+
+// exitLighting returns the light of the lights and the environment entering the surface at the hit where a walk
+// leaves along the direction. The surface lets the light through diffusely.
+func exitLighting(direction mathutils.Vector, hit *IntersectionInfo, scene *Scene) utils.Color {
+	outward := hit.Normal
+	if mathutils.DotProduct(outward, direction) < 0 {
+		outward.UnaryMinus()
+	}
+
+	var result utils.Color
+	start := mathutils.VectorAddition(hit.Position, mathutils.VectorMultiply(outward, 1e-5))
+	for _, sample := range sceneLightSamples(scene, hit.Position) {
+		cosTheta := mathutils.DotProduct(outward, sample.toLight)
+		if cosTheta <= 0 {
+			continue
+		}
+		light := utils.ColorMultiplication(sample.intensity, visibility(start, sample.toLight, sample.distance, scene))
+		result = utils.ColorAddition(result, utils.MultiplyColorFloat(light, cosTheta))
+	}
+
+	// The environment is sampled as seen from outside, by a ray arriving against the outward normal.
+	exit := *hit
+	exit.Normal = outward
+	inward := NewRay(start, mathutils.VectorMultiply(outward, -1))
+	result = utils.ColorAddition(result, environmentLighting(&inward, &exit, scene, func(toLight mathutils.Vector) utils.Color {
+		share := math.Max(0, mathutils.DotProduct(outward, toLight)) / math.Pi
+		return utils.Color{share, share, share}
+	}))
+
+	return result
+}
+
+// singleScatteringAlbedo returns the share of the extinction which is scattering for a medium whose multiple
+// scattering reflects the share given by the color, by the fit of Chiang et al., "Practical and Controllable
+// Subsurface Scattering for Production Path Tracing".
+func singleScatteringAlbedo(color float64) float64 {
+	color = math.Max(0, math.Min(color, 1))
+	s := 4.09712 + 4.20863*color - math.Sqrt(9.59217+41.6808*color+17.7126*color*color)
+
+	return math.Max(0, math.Min(1-s*s, 1))
+}
+
+// channelTransmittance returns the share of the light passing the distance for the extinction of every channel.
+func channelTransmittance(extinction utils.Color, distance float64) utils.Color {
+	var result utils.Color
+	for channel := range result {
+		result[channel] = math.Exp(-extinction[channel] * distance)
+	}
+
+	return result
+}
+
+// weightedAverage returns the average of the channels of the values weighted by the shares, which add up to one.
+func weightedAverage(shares, values utils.Color) float64 {
+	return shares[0]*values[0] + shares[1]*values[1] + shares[2]*values[2]
+}
+
+// cosineDirection returns a direction around the normalized axis distributed proportionally to the cosine.
+func cosineDirection(axis mathutils.Vector, u1, u2 float64) mathutils.Vector {
+	tangent, bitangent := mathutils.OrthonormalBasis(axis)
+	radius, phi := math.Sqrt(u1), 2*math.Pi*u2
+
+	direction := mathutils.VectorMultiply(axis, math.Sqrt(math.Max(0, 1-u1)))
+	direction.Add(mathutils.VectorMultiply(tangent, radius*math.Cos(phi)))
+	direction.Add(mathutils.VectorMultiply(bitangent, radius*math.Sin(phi)))
+
+	return direction
+}
+
+// sphereDirection returns a direction distributed uniformly over the sphere.
+func sphereDirection(u1, u2 float64) mathutils.Vector {
+	cosTheta := 1 - 2*u1
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := 2 * math.Pi * u2
+
+	return mathutils.NewVector(sinTheta*math.Cos(phi), sinTheta*math.Sin(phi), cosTheta)
+}
@@ -0,0 +1,52 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"strings"
+	"testing"
+)
+
+func TestSubsurface(t *testing.T) {
+	// The single scattering albedo of a black material is zero and of a white one is one.
+	if singleScatteringAlbedo(0) > 1e-3 || singleScatteringAlbedo(1) < 0.999 ||
+		singleScatteringAlbedo(0.5) <= singleScatteringAlbedo(0.25) || singleScatteringAlbedo(0.5) >= 1 {
+		t.Errorf("singleScatteringAlbedo() failed!")
+	}
+
+	// A sphere lit from above lets the light through to its bottom, more of the channels scattering farther.
+	scene := NewScene()
+	scene.SetAmbientLight(utils.Color{1, 1, 1})
+	scene.AddLight(NewDirectionalLight(mathutils.NewVector(0, 1, 0), utils.Color{1, 1, 1}, 1, 0, 1))
+	subsurface := NewSubsurface(utils.Color{1, 1, 1}, nil, utils.Color{40, 10, 1}, 64)
+	var sphere Geometry = &Sphere{mathutils.NewVector(0, 0, 0), 10}
+	var shader Shader = &subsurface
+	scene.SceneNodes = append(scene.SceneNodes, NewNode(&sphere, &shader))
+	scene.Build()
+
+	ray := NewRay(mathutils.NewVector(0, -50, 0), mathutils.NewVector(0, 1, 0))
+	bottom := scene.trace(&ray)
+	if bottom[0] < 0.2 || bottom[0] > 1 || bottom[2] >= bottom[1] || bottom[1] >= bottom[0] {
+		t.Errorf("Subsurface.Shade() failed!")
+	}
+	ray = NewRay(mathutils.NewVector(0, 50, 0), mathutils.NewVector(0, -1, 0))
+	if top := scene.trace(&ray); top[2] < 0.4 || top[2] > 1.05 {
+		t.Errorf("Subsurface.Shade() failed!")
+	}
+
+	// A black material absorbs everything which scatters.
+	subsurface = NewSubsurface(utils.Color{}, nil, utils.Color{0.01, 0.01, 0.01}, 16)
+	if top := scene.trace(&ray); top[0] > 1e-3 {
+		t.Errorf("Subsurface.Shade() failed!")
+	}
+
+	reader := SceneReader{fileContent: strings.Fields(`shader Subsurface { color 255 128 0 texture nil radius 3 2 1
+		samples 4 bumpScale 2 } end`)}
+	parsed, err := reader.readShader()
+	if parsed, ok := parsed.(*Subsurface); err != nil || !ok || parsed.radius != (utils.Color{3, 2, 1}) ||
+		parsed.samples != 4 || parsed.texture != nil || parsed.bumpScale != 2 {
+		t.Errorf("SceneReader.readSubsurface() failed!")
+	} else if reader.fileContent[reader.position] != "end" {
+		t.Errorf("SceneReader.readSubsurface() failed!")
+	}
+}
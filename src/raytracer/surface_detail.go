@@ -0,0 +1,115 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"math"
+)
+
+// surfaceDetail holds the optional normal map and bump map of a shader, which perturb the shading normal
+// to add detail the geometry does not have. It is embedded in the shaders.
+type surfaceDetail struct {
+	normalMap Texture // Tangent space normals with red along DPdu, green along DPdv and blue along the normal.
+	bumpMap   Texture // Heights given by the brightness of the texture.
+	bumpScale float64 // The height of a white texel of the bump map.
+}
+
+// SetNormalMap sets the tangent space normal map, which should be read with a linear color space.
+func (d *surfaceDetail) SetNormalMap(texture Texture) {
+	d.normalMap = texture
+}
+
+// SetBumpMap sets the bump map and the height of its white texels.
+func (d *surfaceDetail) SetBumpMap(texture Texture, scale float64) {
+	d.bumpMap = texture
+	d.bumpScale = scale
+}
+
+// shadingInfo returns the intersection with the normal perturbed by the normal map and then by the bump map.
+// The given intersection is returned unchanged if the shader has neither.
+// With triplanar mapping the normals are blended from the projections.
+func (d *surfaceDetail) shadingInfo(info *IntersectionInfo) *IntersectionInfo {
+	if d.normalMap == nil && d.bumpMap == nil {
+		return info
+	}
+
+	shading := *info
+	if d.normalMap != nil {
+		shading.Normal = blendNormals(&shading, d.applyNormalMap)
+	}
+	if d.bumpMap != nil {
+		shading.Normal = blendNormals(&shading, d.applyBumpMap)
+	}
+
+	return &shading
+}
+
+// applyNormalMap returns the normal from the normal map expressed in the tangent frame of the intersection.
+func (d *surfaceDetail) applyNormalMap(info *IntersectionInfo) mathutils.Vector {
+	normal := info.Normal
+	tangent := mathutils.VectorSubstraction(info.DPdu, mathutils.VectorMultiply(normal, mathutils.DotProduct(normal, info.DPdu)))
+	var bitangent mathutils.Vector
+	if tangent.LengthSqr() > 0 {
+		tangent.Normalize()
+		bitangent = mathutils.CrossProduct(normal, tangent)
+		if mathutils.DotProduct(bitangent, info.DPdv) < 0 {
+			bitangent.UnaryMinus()
+		}
+	} else {
+		tangent, bitangent = mathutils.OrthonormalBasis(normal)
+	}
+
+	color := d.normalMap.Sample(info)
+	result := mathutils.VectorMultiply(tangent, 2*color[0]-1)
+	result.Add(mathutils.VectorMultiply(bitangent, 2*color[1]-1))
+	result.Add(mathutils.VectorMultiply(normal, 2*color[2]-1))
+	if result.LengthSqr() == 0 {
+		return normal
+	}
+	result.Normalize()
+
+	return result
+}
+
+// applyBumpMap returns the normal of the surface displaced along its normal by the bump map.
+// The slope of the heights along U and V comes from finite differences over the footprint of the pixel.
+func (d *surfaceDetail) applyBumpMap(info *IntersectionInfo) mathutils.Vector {
+	normal := info.Normal
+	if info.DPdu.LengthSqr() == 0 || info.DPdv.LengthSqr() == 0 {
+		return normal
+	}
+
+	du := 0.5 * (math.Abs(info.DUdx) + math.Abs(info.DUdy))
+	if du == 0 {
+		du = 5e-4
+	}
+	dv := 0.5 * (math.Abs(info.DVdx) + math.Abs(info.DVdy))
+	if dv == 0 {
+		dv = 5e-4
+	}
+
+	height := func(shifted IntersectionInfo) float64 {
+		return luminance(d.bumpMap.Sample(&shifted)) * d.bumpScale
+	}
+	shiftedU, shiftedV := *info, *info
+	shiftedU.Position = mathutils.VectorAddition(info.Position, mathutils.VectorMultiply(info.DPdu, du))
+	shiftedU.U += du
+	shiftedV.Position = mathutils.VectorAddition(info.Position, mathutils.VectorMultiply(info.DPdv, dv))
+	shiftedV.V += dv
+	displacement := height(*info)
+
+	dPdu := mathutils.VectorAddition(info.DPdu, mathutils.VectorMultiply(normal, (height(shiftedU)-displacement)/du))
+	dPdv := mathutils.VectorAddition(info.DPdv, mathutils.VectorMultiply(normal, (height(shiftedV)-displacement)/dv))
+	result := mathutils.CrossProduct(dPdu, dPdv)
+	if result.LengthSqr() == 0 {
+		return normal
+	}
+	result.Normalize()
+
+	// The derivatives may be ordered either way around the normal, keep the side of the surface.
+	if mathutils.DotProduct(result, normal) < 0 {
+		result.UnaryMinus()
+	}
+
+	return result
+}
@@ -0,0 +1,65 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/mathutils"
+	"GoRaytracer/src/utils"
+	"strings"
+	"testing"
+)
+
+// uGradient is a test texture whose brightness is the U coordinate.
+type uGradient struct{}
+
+func (uGradient) Sample(info *IntersectionInfo) utils.Color {
+	return utils.Color{info.U, info.U, info.U}
+}
+
+func TestSurfaceDetail(t *testing.T) {
+	info := IntersectionInfo{Normal: mathutils.NewVector(0, 1, 0), U: 0.3, V: 0.6,
+		DPdu: mathutils.NewVector(2, 0, 0), DPdv: mathutils.NewVector(0, 0, -1)}
+
+	var detail surfaceDetail
+	if detail.shadingInfo(&info) != &info {
+		t.Errorf("surfaceDetail.shadingInfo() failed!")
+	}
+
+	flat := NewSimpleColor(utils.Color{0.5, 0.5, 1})
+	detail.SetNormalMap(&flat)
+	if !compareVectors(detail.shadingInfo(&info).Normal, info.Normal) {
+		t.Errorf("surfaceDetail.shadingInfo() failed!")
+	}
+
+	// Red and green point along DPdu and DPdv.
+	tilted := NewSimpleColor(utils.Color{1, 0.5, 0.5})
+	detail.SetNormalMap(&tilted)
+	if !compareVectors(detail.shadingInfo(&info).Normal, mathutils.NewVector(1, 0, 0)) {
+		t.Errorf("surfaceDetail.shadingInfo() failed!")
+	}
+
+	tilted = NewSimpleColor(utils.Color{0.5, 1, 0.5})
+	if !compareVectors(detail.shadingInfo(&info).Normal, mathutils.NewVector(0, 0, -1)) {
+		t.Errorf("surfaceDetail.shadingInfo() failed!")
+	}
+
+	// Heights rising by 0.25 per unit along X tilt the normal back.
+	detail = surfaceDetail{}
+	detail.SetBumpMap(uGradient{}, 0.5)
+	expected := mathutils.NewVector(-0.25, 1, 0)
+	expected.Normalize()
+	if shading := detail.shadingInfo(&info); !compareVectors(shading.Normal, expected) || info.Normal.Y != 1 {
+		t.Errorf("surfaceDetail.shadingInfo() failed!")
+	}
+
+	reader := SceneReader{fileContent: strings.Fields(`Lambert {
+		color 255 255 255
+		texture nil
+		bumpMap Checker { color1 0 0 0 color2 255 255 255 scale 5 }
+		bumpScale 0.1
+		normalMap SimpleColor { color 128 128 255 }
+	} End`)}
+	lambert, err := reader.readLambert()
+	if err != nil || lambert.bumpMap == nil || lambert.normalMap == nil || lambert.bumpScale != 0.1 ||
+		reader.fileContent[reader.position] != "End" {
+		t.Errorf("SceneReader.readLambert() failed!")
+	}
+}
@@ -0,0 +1,159 @@
+// Package raytracer provides the raytracer logic.
+package raytracer
+
+import (
+	"GoRaytracer/src/utils"
+	"fmt"
+	"math"
+)
+
+// luminance returns the brightness of the color, which is how textures are used as scalar masks.
+func luminance(c utils.Color) float64 {
+	return 0.2126*c[0] + 0.7152*c[1] + 0.0722*c[2]
+}
+
+// mixColors interpolates linearly between the colors.
+func mixColors(color1, color2 utils.Color, t float64) utils.Color {
+	return utils.ColorAddition(utils.MultiplyColorFloat(color1, 1-t), utils.MultiplyColorFloat(color2, t))
+}
+
+// Mix defines a texture blending two textures by the brightness of a mask texture.
+type Mix struct {
+	texture1 Texture // The texture where the mask is black.
+	texture2 Texture // The texture where the mask is white.
+	mask     Texture
+}
+
+// NewMix creates and returns a new mix texture.
+func NewMix(texture1, texture2, mask Texture) Mix {
+	return Mix{texture1, texture2, mask}
+}
+
+// Sample implements sampling for Mix.
+func (m *Mix) Sample(info *IntersectionInfo) utils.Color {
+	t := math.Max(0, math.Min(luminance(m.mask.Sample(info)), 1))
+
+	return mixColors(m.texture1.Sample(info), m.texture2.Sample(info), t)
+}
+
+// Multiply defines a texture multiplying two textures.
+type Multiply struct {
+	texture1 Texture
+	texture2 Texture
+}
+
+// NewMultiply creates and returns a new multiply texture.
+func NewMultiply(texture1, texture2 Texture) Multiply {
+	return Multiply{texture1, texture2}
+}
+
+// Sample implements sampling for Multiply.
+func (m *Multiply) Sample(info *IntersectionInfo) utils.Color {
+	return utils.ColorMultiplication(m.texture1.Sample(info), m.texture2.Sample(info))
+}
+
+// Add defines a texture adding two textures.
+type Add struct {
+	texture1 Texture
+	texture2 Texture
+}
+
+// NewAdd creates and returns a new add texture.
+func NewAdd(texture1, texture2 Texture) Add {
+	return Add{texture1, texture2}
+}
+
+// Sample implements sampling for Add.
+func (a *Add) Sample(info *IntersectionInfo) utils.Color {
+	return utils.ColorAddition(a.texture1.Sample(info), a.texture2.Sample(info))
+}
+
+// ColorRamp defines a texture mapping the brightness of an input texture to a gradient of colors.
+type ColorRamp struct {
+	input     Texture
+	positions []float64     // The positions of the stops in increasing order.
+	colors    []utils.Color // The colors of the stops.
+}
+
+// NewColorRamp creates and returns a new color ramp through the given stops.
+// The positions must be in increasing order and have one color each.
+func NewColorRamp(input Texture, positions []float64, colors []utils.Color) (ColorRamp, error) {
+	if len(positions) == 0 || len(positions) != len(colors) {
+		return ColorRamp{}, fmt.Errorf("A color ramp needs one color for each of at least one stop")
+	}
+
+	for i := 1; i < len(positions); i++ {
+		if positions[i] < positions[i-1] {
+			return ColorRamp{}, fmt.Errorf("The color ramp stops are not in increasing order")
+		}
+	}
+
+	return ColorRamp{input, positions, colors}, nil
+}
+
+// Sample implements sampling for ColorRamp. The colors are interpolated linearly between the stops
+// and extended beyond the first and the last stop.
+func (c *ColorRamp) Sample(info *IntersectionInfo) utils.Color {
+	t := luminance(c.input.Sample(info))
+	if t <= c.positions[0] {
+		return c.colors[0]
+	}
+
+	for i := 1; i < len(c.positions); i++ {
+		if t < c.positions[i] {
+			share := (t - c.positions[i-1]) / (c.positions[i] - c.positions[i-1])
+			return mixColors(c.colors[i-1], c.colors[i], share)
+		}
+	}
+
+	return c.colors[len(c.colors)-1]
+}
+
+// Invert defines a texture returning the inverted colors of a texture.
+type Invert struct {
+	texture Texture
+}
+
+// NewInvert creates and returns a new invert texture.
+func NewInvert(texture Texture) Invert {
+	return Invert{texture}
+}
+
+// Sample implements sampling for Invert.
+func (i *Invert) Sample(info *IntersectionInfo) utils.Color {
+	color := i.texture.Sample(info)
+	for channel := range color {
+		color[channel] = 1 - color[channel]
+	}
+
+	return color
+}
+
+// Remap defines a texture mapping each channel of a texture linearly from one range to another.
+type Remap struct {
+	texture          Texture
+	fromMin, fromMax float64 // The source range.
+	toMin, toMax     float64 // The target range.
+}
+
+// NewRemap creates and returns a new remap texture mapping [fromMin, fromMax] to [toMin, toMax].
+func NewRemap(texture Texture, fromMin, fromMax, toMin, toMax float64) Remap {
+	return Remap{texture, fromMin, fromMax, toMin, toMax}
+}
+
+// Sample implements sampling for Remap. Values outside of the source range are clamped to it,
+// so a narrow source range raises the contrast of a mask.
+func (r *Remap) Sample(info *IntersectionInfo) utils.Color {
+	color := r.texture.Sample(info)
+	for channel := range color {
+		t := 0.0
+		if r.fromMax != r.fromMin {
+			t = math.Max(0, math.Min((color[channel]-r.fromMin)/(r.fromMax-r.fromMin), 1))
+		} else if color[channel] >= r.fromMax {
+			t = 1
+		}
+		color[channel] = r.toMin + t*(r.toMax-r.toMin)
+	}
+
+	return color
+}
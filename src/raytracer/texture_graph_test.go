@@ -0,0 +1,71 @@
+package raytracer
+
+import (
+	"GoRaytracer/src/utils"
+	"strings"
+	"testing"
+)
+
+func TestTextureGraph(t *testing.T) {
+	black, white := utils.Color{0, 0, 0}, utils.Color{1, 1, 1}
+	red, blue := utils.Color{1, 0, 0}, utils.Color{0, 0, 1}
+	gray := NewSimpleColor(utils.Color{0.5, 0.5, 0.5})
+	redTexture, blueTexture := NewSimpleColor(red), NewSimpleColor(blue)
+	info := IntersectionInfo{}
+
+	mix := NewMix(&redTexture, &blueTexture, &gray)
+	if mix.Sample(&info) != (utils.Color{0.5, 0, 0.5}) {
+		t.Errorf("Mix.Sample() failed!")
+	}
+
+	multiply := NewMultiply(&redTexture, &gray)
+	add := NewAdd(&redTexture, &blueTexture)
+	if multiply.Sample(&info) != (utils.Color{0.5, 0, 0}) || add.Sample(&info) != (utils.Color{1, 0, 1}) {
+		t.Errorf("Multiply.Sample() or Add.Sample() failed!")
+	}
+
+	invert := NewInvert(&redTexture)
+	if invert.Sample(&info) != (utils.Color{0, 1, 1}) {
+		t.Errorf("Invert.Sample() failed!")
+	}
+
+	remap := NewRemap(&gray, 0.25, 0.75, 0, 10)
+	if remap.Sample(&info) != (utils.Color{5, 5, 5}) {
+		t.Errorf("Remap.Sample() failed!")
+	}
+
+	ramp, err := NewColorRamp(&gray, []float64{0, 1}, []utils.Color{black, white})
+	if err != nil || ramp.Sample(&info) != (utils.Color{0.5, 0.5, 0.5}) {
+		t.Errorf("ColorRamp.Sample() failed!")
+	}
+
+	if _, err := NewColorRamp(&gray, []float64{1, 0}, []utils.Color{black, white}); err == nil {
+		t.Errorf("NewColorRamp() failed!")
+	}
+
+	// Textures nest in the scene file, and the phong shader reads its settings after the texture.
+	reader := SceneReader{fileContent: strings.Fields(`Phong {
+		color 255 255 255
+		texture Mix {
+			texture1 SimpleColor { color 255 0 0 }
+			texture2 Invert { texture SimpleColor { color 255 255 0 } }
+			mask ColorRamp { input Checker { color1 0 0 0 color2 255 255 255 scale 5 } stop 0 0 0 0 stop 0.5 255 255 255 }
+		}
+		specularMultiplier 2
+		specularExponent 10
+	} End`)}
+	phong, err := reader.readPhong()
+	if err != nil || phong.specularMultiplier != 2 || phong.specularExponent != 10 || reader.fileContent[reader.position] != "End" {
+		t.Fatalf("SceneReader.readPhong() failed!")
+	}
+
+	info = IntersectionInfo{U: 0.5, V: 0.5}
+	if (*phong.texture).Sample(&info) != red {
+		t.Errorf("SceneReader.readTexture() failed!")
+	}
+
+	info = IntersectionInfo{U: 1.5, V: 0.5}
+	if (*phong.texture).Sample(&info) != blue {
+		t.Errorf("SceneReader.readTexture() failed!")
+	}
+}